@@ -0,0 +1,151 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ ValueMarshaler   = Accept{}
+	_ ValueUnmarshaler = (*Accept)(nil)
+)
+
+var errInvalidAccept = errors.New("invalid accept value")
+
+// AcceptValue is a single element of an Accept style header, e.g.
+// "text/html;level=1;q=0.5".
+type AcceptValue struct {
+	// Value is the media range, coding, charset or language, in lower case.
+	Value string
+
+	// Q is the quality between 0 and 1. Unmarshal sets it to 1 when the
+	// element has no q parameter, and Marshal omits the q parameter when it
+	// is 0 or 1, so that an element without Q is fully acceptable.
+	Q float64
+
+	// Reject marks the element as not acceptable, which is marshaled as q=0
+	// regardless of Q. Unmarshal sets it along with a zero Q for q=0.
+	Reject bool
+
+	// Params are the other parameters of the element, keyed in lower case.
+	Params map[string]string
+}
+
+// Accept is the value of an Accept, Accept-Charset, Accept-Encoding or
+// Accept-Language header as defined by RFC 9110 section 12.5. The elements
+// are sorted by their quality on unmarshal, with the elements of the same
+// quality kept in their original order.
+type Accept []AcceptValue
+
+func (a Accept) MarshalValue() ([]string, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+
+	elems := make([]string, len(a))
+
+	for i, av := range a {
+		if av.Q < 0 || av.Q > 1 || math.IsNaN(av.Q) {
+			return nil, fmt.Errorf("%w %q: quality %v is out of range", errInvalidAccept, av.Value, av.Q)
+		}
+
+		elem := mime.FormatMediaType(av.Value, av.Params)
+		if elem == "" {
+			return nil, fmt.Errorf("%w %q", errInvalidAccept, av.Value)
+		}
+
+		switch {
+		case av.Reject:
+			elem += "; q=0"
+
+		case av.Q != 0 && av.Q != 1:
+			// RFC 9110 section 12.4.2 allows up to three digits after the
+			// decimal point, where a positive quality must not round to 0.
+			q := max(math.Round(av.Q*1000)/1000, 0.001)
+			elem += "; q=" + strconv.FormatFloat(q, 'f', -1, 64)
+		}
+
+		elems[i] = elem
+	}
+
+	return []string{strings.Join(elems, ", ")}, nil
+}
+
+func (a *Accept) UnmarshalValue(v []string) error {
+	var out Accept
+
+	for _, elem := range splitList(v) {
+		val, params, err := mime.ParseMediaType(elem)
+		if err != nil {
+			return fmt.Errorf("%w %q: %w", errInvalidAccept, elem, err)
+		}
+
+		av := AcceptValue{Value: val, Q: 1}
+
+		if q, ok := params["q"]; ok {
+			var ok bool
+			if av.Q, ok = parseQValue(q); !ok {
+				return fmt.Errorf("%w %q: invalid quality %q", errInvalidAccept, elem, q)
+			}
+
+			av.Reject = av.Q == 0
+
+			delete(params, "q")
+		}
+
+		if len(params) > 0 {
+			av.Params = params
+		}
+
+		out = append(out, av)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Q > out[j].Q
+	})
+
+	*a = out
+
+	return nil
+}
+
+// parseQValue parses the qvalue grammar of RFC 9110 section 12.4.2, which is
+// either "0" or "1" followed by up to three decimal digits, e.g. "0.5" or
+// "1.000". The other float forms such as "1e0" or "NaN" are rejected.
+func parseQValue(s string) (float64, bool) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if (whole != "0" && whole != "1") || len(frac) > 3 {
+		return 0, false
+	}
+
+	for i := 0; i < len(frac); i++ {
+		if frac[i] < '0' || frac[i] > '9' || (whole == "1" && frac[i] != '0') {
+			return 0, false
+		}
+	}
+
+	q, err := strconv.ParseFloat(s, 64)
+
+	return q, err == nil
+}
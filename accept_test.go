@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccept(t *testing.T) {
+	type testHeader struct {
+		Accept         structmap.Accept `map:"accept"`
+		AcceptEncoding structmap.Accept `map:"accept-encoding"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Accept":          {"text/html;q=0.5, application/json, Text/Plain;Level=1;q=0.5", "*/*;q=0.1"},
+			"Accept-Encoding": {"gzip;q=0, br"},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testHeader{
+			Accept: structmap.Accept{
+				{Value: "application/json", Q: 1},
+				{Value: "text/html", Q: 0.5},
+				{Value: "text/plain", Q: 0.5, Params: map[string]string{"level": "1"}},
+				{Value: "*/*", Q: 0.1},
+			},
+			AcceptEncoding: structmap.Accept{
+				{Value: "br", Q: 1},
+				{Value: "gzip", Q: 0, Reject: true},
+			},
+		}, actual)
+
+		err = structmap.UnmarshalHeader(http.Header{"Accept": {"text/html;q=1.000, text/plain;q=0."}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, structmap.Accept{{Value: "text/html", Q: 1}, {Value: "text/plain", Q: 0, Reject: true}}, actual.Accept)
+
+		for _, val := range []string{
+			"text/html;q=2", "text/html;q=x", "text/html;;=",
+			"text/html;q=NaN", "text/html;q=1e0", "text/html;q=0x1p-1",
+			"text/html;q=0.1234", "text/html;q=1.5", "text/html;q=.5",
+		} {
+			err = structmap.UnmarshalHeader(http.Header{"Accept": {val}}, &actual)
+			assert.ErrorContains(t, err, "key Accept: invalid accept value", val)
+		}
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{
+			Accept: structmap.Accept{
+				{Value: "text/plain", Q: 1, Params: map[string]string{"format": "a b"}},
+				{Value: "text/html", Q: 0.12345},
+				{Value: "text/*", Q: 0.0001},
+				{Value: "*/*"},
+			},
+			AcceptEncoding: structmap.Accept{{Value: "identity", Reject: true}},
+		}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Accept":          {`text/plain; format="a b", text/html; q=0.123, text/*; q=0.001, */*`},
+			"Accept-Encoding": {"identity; q=0"},
+		}, actual)
+
+		err = structmap.MarshalHeader(testHeader{Accept: structmap.Accept{{Value: "text/html", Q: 1.5}}}, actual)
+		assert.ErrorContains(t, err, "quality 1.5 is out of range")
+
+		err = structmap.MarshalHeader(testHeader{Accept: structmap.Accept{{Value: "text html", Q: 1}}}, actual)
+		assert.ErrorContains(t, err, `invalid accept value "text html"`)
+	})
+}
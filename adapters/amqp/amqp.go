@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package amqp converts between structs and the AMQP message header tables
+// using the structmap tags. It does not depend on any AMQP client: Table has
+// the same underlying type as the amqp091-go Table, so they can be converted
+// directly.
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/adzil/structmap"
+)
+
+var errNestedArray = errors.New("cannot unmarshal from nested array")
+
+// Table is the AMQP field table of the message headers.
+type Table map[string]any
+
+// Headers marshals and unmarshals the AMQP message headers.
+//
+// The values are marshaled as strings, and a key with multiple values as an
+// array of strings. On unmarshal, the values are coerced into strings: the
+// byte slices as-is, the booleans and numbers in their shortest form, the
+// times in RFC 3339, and the arrays element by element.
+type Headers struct {
+	// Marshaler is used to marshal the headers. Defaults to the
+	// structmap.DefaultMarshaler.
+	Marshaler *structmap.Marshaler
+	// Unmarshaler is used to unmarshal the headers. Defaults to the
+	// structmap.DefaultUnmarshaler.
+	Unmarshaler *structmap.Unmarshaler
+}
+
+// Marshal marshals src into the table.
+func (h *Headers) Marshal(src any) (Table, error) {
+	m := h.Marshaler
+	if m == nil {
+		m = &structmap.DefaultMarshaler
+	}
+
+	v := make(map[string][]string)
+	if err := m.Marshal(src, v); err != nil {
+		return nil, err
+	}
+
+	out := make(Table, len(v))
+
+	for key, val := range v {
+		if len(val) == 1 {
+			out[key] = val[0]
+
+			continue
+		}
+
+		arr := make([]any, len(val))
+		for i, s := range val {
+			arr[i] = s
+		}
+
+		out[key] = arr
+	}
+
+	return out, nil
+}
+
+// Unmarshal unmarshals the table into dst.
+func (h *Headers) Unmarshal(table Table, dst any) error {
+	u := h.Unmarshaler
+	if u == nil {
+		u = &structmap.DefaultUnmarshaler
+	}
+
+	v := make(map[string][]string, len(table))
+
+	for key, val := range table {
+		val, err := coerce(nil, val)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+
+		v[key] = val
+	}
+
+	return u.Unmarshal(v, dst)
+}
+
+func coerce(out []string, val any) ([]string, error) {
+	switch val := val.(type) {
+	case nil:
+		return out, nil
+	case string:
+		return append(out, val), nil
+	case []byte:
+		return append(out, string(val)), nil
+	case bool:
+		return append(out, strconv.FormatBool(val)), nil
+	case int8:
+		return append(out, strconv.FormatInt(int64(val), 10)), nil
+	case int16:
+		return append(out, strconv.FormatInt(int64(val), 10)), nil
+	case int32:
+		return append(out, strconv.FormatInt(int64(val), 10)), nil
+	case int64:
+		return append(out, strconv.FormatInt(val, 10)), nil
+	case int:
+		return append(out, strconv.Itoa(val)), nil
+	case uint8:
+		return append(out, strconv.FormatUint(uint64(val), 10)), nil
+	case uint16:
+		return append(out, strconv.FormatUint(uint64(val), 10)), nil
+	case uint32:
+		return append(out, strconv.FormatUint(uint64(val), 10)), nil
+	case uint64:
+		return append(out, strconv.FormatUint(val, 10)), nil
+	case float32:
+		return append(out, strconv.FormatFloat(float64(val), 'g', -1, 32)), nil
+	case float64:
+		return append(out, strconv.FormatFloat(val, 'g', -1, 64)), nil
+	case time.Time:
+		return append(out, val.Format(time.RFC3339)), nil
+	case []any:
+		for _, elem := range val {
+			if _, ok := elem.([]any); ok {
+				return nil, errNestedArray
+			}
+
+			var err error
+			if out, err = coerce(out, elem); err != nil {
+				return nil, err
+			}
+		}
+
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("cannot unmarshal from %T", val)
+}
+
+// Marshal marshals src into the table using the default Headers.
+func Marshal(src any) (Table, error) {
+	var h Headers
+
+	return h.Marshal(src)
+}
+
+// Unmarshal unmarshals the table into dst using the default Headers.
+func Unmarshal(table Table, dst any) error {
+	var h Headers
+
+	return h.Unmarshal(table, dst)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adzil/structmap/adapters/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStruct struct {
+	TraceID string    `map:"trace-id"`
+	Retries int       `map:"retries"`
+	Tags    []string  `map:"tag"`
+	Sent    time.Time `map:"sent"`
+	Urgent  bool      `map:"urgent"`
+}
+
+func TestHeaders(t *testing.T) {
+	t.Run("Marshal", func(t *testing.T) {
+		input := testStruct{
+			TraceID: "abc",
+			Retries: 2,
+			Tags:    []string{"a", "b"},
+			Sent:    time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC),
+		}
+
+		actual, err := amqp.Marshal(input)
+		require.NoError(t, err)
+		assert.Equal(t, amqp.Table{
+			"trace-id": "abc",
+			"retries":  "2",
+			"tag":      []any{"a", "b"},
+			"sent":     "2023-08-17T10:00:00Z",
+			"urgent":   "false",
+		}, actual)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		expected := testStruct{
+			TraceID: "abc",
+			Retries: 2,
+			Tags:    []string{"a", "b"},
+			Sent:    time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC),
+			Urgent:  true,
+		}
+
+		var actual testStruct
+
+		err := amqp.Unmarshal(amqp.Table{
+			"trace-id": []byte("abc"),
+			"retries":  int32(2),
+			"tag":      []any{"a", []byte("b")},
+			"sent":     time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC),
+			"urgent":   true,
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("UnmarshalUnsupported", func(t *testing.T) {
+		var actual testStruct
+
+		err := amqp.Unmarshal(amqp.Table{"trace-id": amqp.Table{}}, &actual)
+		assert.EqualError(t, err, "key trace-id: cannot unmarshal from amqp.Table")
+	})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package decimal provides the structmap codec for
+// github.com/shopspring/decimal.Decimal fields and slices.
+package decimal
+
+import (
+	"fmt"
+
+	"github.com/adzil/structmap"
+	"github.com/shopspring/decimal"
+)
+
+// Rounding is the rounding mode used when formatting with fixed places.
+type Rounding int
+
+const (
+	// RoundHalfUp rounds half away from zero.
+	RoundHalfUp Rounding = iota
+	// RoundHalfEven rounds half to the nearest even digit (banker's rounding).
+	RoundHalfEven
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundDown rounds towards zero.
+	RoundDown
+	// RoundCeil rounds towards positive infinity.
+	RoundCeil
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor
+)
+
+func (r Rounding) round(d decimal.Decimal, places int32) decimal.Decimal {
+	switch r {
+	case RoundHalfEven:
+		return d.RoundBank(places)
+	case RoundUp:
+		return d.RoundUp(places)
+	case RoundDown:
+		return d.RoundDown(places)
+	case RoundCeil:
+		return d.RoundCeil(places)
+	case RoundFloor:
+		return d.RoundFloor(places)
+	}
+
+	return d.Round(places)
+}
+
+// Config configures the decimal codec.
+type Config struct {
+	// Fixed makes Marshal round the value into Places decimal places using
+	// Rounding, and always write exactly that many decimal places. Otherwise,
+	// the value is written as-is with the minimum number of places.
+	Fixed    bool
+	Places   int32
+	Rounding Rounding
+}
+
+// Codecs returns the codecs for the decimal types.
+func Codecs(cfg Config) []structmap.Codec {
+	format := func(val decimal.Decimal) (string, error) {
+		if !cfg.Fixed {
+			return val.String(), nil
+		}
+
+		return cfg.Rounding.round(val, cfg.Places).StringFixed(cfg.Places), nil
+	}
+
+	return []structmap.Codec{
+		structmap.NewCodec(format, parseDecimal),
+	}
+}
+
+func parseDecimal(s string) (decimal.Decimal, error) {
+	val, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	return val, nil
+}
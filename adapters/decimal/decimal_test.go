@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decimal_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	decimaladapter "github.com/adzil/structmap/adapters/decimal"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStruct struct {
+	Amount decimal.Decimal   `map:"amount"`
+	Fees   []decimal.Decimal `map:"fees"`
+}
+
+func TestCodecs(t *testing.T) {
+	input := testStruct{
+		Amount: decimal.RequireFromString("10.125"),
+		Fees:   []decimal.Decimal{decimal.RequireFromString("0.5")},
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		m := structmap.NewMarshaler(structmap.MarshalConfig{
+			Codecs: decimaladapter.Codecs(decimaladapter.Config{}),
+		})
+
+		actual := make(map[string][]string)
+
+		err := m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"amount": {"10.125"},
+			"fees":   {"0.5"},
+		}, actual)
+	})
+
+	t.Run("MarshalFixed", func(t *testing.T) {
+		m := structmap.NewMarshaler(structmap.MarshalConfig{
+			Codecs: decimaladapter.Codecs(decimaladapter.Config{
+				Fixed:    true,
+				Places:   2,
+				Rounding: decimaladapter.RoundHalfEven,
+			}),
+		})
+
+		actual := make(map[string][]string)
+
+		err := m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"amount": {"10.12"},
+			"fees":   {"0.50"},
+		}, actual)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			Codecs: decimaladapter.Codecs(decimaladapter.Config{}),
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{
+			"amount": {"10.125"},
+			"fees":   {"0.5"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.True(t, input.Amount.Equal(actual.Amount))
+		require.Len(t, actual.Fees, 1)
+		assert.True(t, input.Fees[0].Equal(actual.Fees[0]))
+
+		err = u.Unmarshal(map[string][]string{"amount": {"1,5"}}, &actual)
+		assert.ErrorContains(t, err, `key amount: invalid decimal "1,5"`)
+	})
+}
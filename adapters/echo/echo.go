@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package echo provides an echo.Binder backed by structmap.UnmarshalRequest.
+package echo
+
+import (
+	"net/http"
+
+	"github.com/adzil/structmap"
+	"github.com/labstack/echo/v4"
+)
+
+var _ echo.Binder = (*Binder)(nil)
+
+// Binder binds the request using the structmap tags. The route parameters are
+// available to the fields with the "path" option.
+type Binder struct {
+	// Unmarshaler is used to bind the request. Defaults to the
+	// structmap.DefaultUnmarshaler.
+	Unmarshaler *structmap.Unmarshaler
+}
+
+func (b *Binder) Bind(i any, c echo.Context) error {
+	u := b.Unmarshaler
+	if u == nil {
+		u = &structmap.DefaultUnmarshaler
+	}
+
+	r := c.Request()
+
+	names, values := c.ParamNames(), c.ParamValues()
+	for j := 0; j < len(names) && j < len(values); j++ {
+		r.SetPathValue(names[j], values[j])
+	}
+
+	if err := u.UnmarshalRequest(r, i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	return nil
+}
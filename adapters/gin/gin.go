@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gin provides a gin binding.Binding backed by
+// structmap.UnmarshalRequest.
+package gin
+
+import (
+	"net/http"
+
+	"github.com/adzil/structmap"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var _ binding.Binding = Binding{}
+
+// Binding binds the request using the structmap tags. Since binding.Binding
+// has no access to the route parameters, use ShouldBind or MustBind to make
+// them available to the fields with the "path" option.
+type Binding struct {
+	// Unmarshaler is used to bind the request. Defaults to the
+	// structmap.DefaultUnmarshaler.
+	Unmarshaler *structmap.Unmarshaler
+}
+
+func (b Binding) Name() string {
+	return "structmap"
+}
+
+func (b Binding) Bind(r *http.Request, obj any) error {
+	u := b.Unmarshaler
+	if u == nil {
+		u = &structmap.DefaultUnmarshaler
+	}
+
+	return u.UnmarshalRequest(r, obj)
+}
+
+func setPathValues(c *gin.Context) {
+	for _, param := range c.Params {
+		c.Request.SetPathValue(param.Key, param.Value)
+	}
+}
+
+// ShouldBind binds the request of c into obj, including the route parameters.
+func (b Binding) ShouldBind(c *gin.Context, obj any) error {
+	setPathValues(c)
+
+	return c.ShouldBindWith(obj, b)
+}
+
+// MustBind is the same as ShouldBind, but aborts the request with HTTP 400 on
+// error like gin.Context.MustBindWith.
+func (b Binding) MustBind(c *gin.Context, obj any) error {
+	setPathValues(c)
+
+	return c.MustBindWith(obj, b)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ginadapter "github.com/adzil/structmap/adapters/gin"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type getItemRequest struct {
+	ID      int    `map:"id,path"`
+	Fields  string `map:"fields,default:all"`
+	TraceID string `map:"x-trace-id,header,required"`
+}
+
+func TestBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var (
+		actual  getItemRequest
+		binding ginadapter.Binding
+	)
+
+	e := gin.New()
+	e.GET("/items/:id", func(c *gin.Context) {
+		if err := binding.MustBind(c, &actual); err != nil {
+			return
+		}
+
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/items/42?fields=name", nil)
+		r.Header.Set("X-Trace-Id", "abc")
+		w := httptest.NewRecorder()
+
+		e.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, getItemRequest{ID: 42, Fields: "name", TraceID: "abc"}, actual)
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+		w := httptest.NewRecorder()
+
+		e.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kafka converts between structs and the Kafka record headers using
+// the structmap tags. It does not depend on any Kafka client: Header has the
+// same layout as the kafka-go Header, so they can be converted directly, while
+// the sarama RecordHeader needs its key to be converted into bytes.
+package kafka
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/adzil/structmap"
+)
+
+// Header is a single Kafka record header. A key with multiple values is
+// written as repeated headers of the same key.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Headers marshals and unmarshals the Kafka record headers.
+type Headers struct {
+	// Marshaler is used to marshal the headers. Defaults to the
+	// structmap.DefaultMarshaler.
+	Marshaler *structmap.Marshaler
+	// Unmarshaler is used to unmarshal the headers. Defaults to the
+	// structmap.DefaultUnmarshaler.
+	Unmarshaler *structmap.Unmarshaler
+}
+
+// Marshal marshals src into the headers, sorted by their keys.
+func (h *Headers) Marshal(src any) ([]Header, error) {
+	m := h.Marshaler
+	if m == nil {
+		m = &structmap.DefaultMarshaler
+	}
+
+	v := make(map[string][]string)
+	if err := m.Marshal(src, v); err != nil {
+		return nil, err
+	}
+
+	var out []Header
+
+	for _, key := range slices.Sorted(maps.Keys(v)) {
+		for _, val := range v[key] {
+			out = append(out, Header{Key: key, Value: []byte(val)})
+		}
+	}
+
+	return out, nil
+}
+
+// Unmarshal unmarshals the headers into dst. The values of the repeated keys
+// are kept in their order.
+func (h *Headers) Unmarshal(headers []Header, dst any) error {
+	u := h.Unmarshaler
+	if u == nil {
+		u = &structmap.DefaultUnmarshaler
+	}
+
+	v := make(map[string][]string)
+	for _, hdr := range headers {
+		v[hdr.Key] = append(v[hdr.Key], string(hdr.Value))
+	}
+
+	return u.Unmarshal(v, dst)
+}
+
+// Marshal marshals src into the headers using the default Headers.
+func Marshal(src any) ([]Header, error) {
+	var h Headers
+
+	return h.Marshal(src)
+}
+
+// Unmarshal unmarshals the headers into dst using the default Headers.
+func Unmarshal(headers []Header, dst any) error {
+	var h Headers
+
+	return h.Unmarshal(headers, dst)
+}
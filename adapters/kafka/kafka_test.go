@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap/adapters/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStruct struct {
+	TraceID string   `map:"trace-id"`
+	Retries int      `map:"retries"`
+	Tags    []string `map:"tag"`
+}
+
+func TestHeaders(t *testing.T) {
+	input := testStruct{TraceID: "abc", Retries: 2, Tags: []string{"a", "b"}}
+	expected := []kafka.Header{
+		{Key: "retries", Value: []byte("2")},
+		{Key: "tag", Value: []byte("a")},
+		{Key: "tag", Value: []byte("b")},
+		{Key: "trace-id", Value: []byte("abc")},
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		actual, err := kafka.Marshal(input)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		var actual testStruct
+
+		err := kafka.Unmarshal(expected, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, input, actual)
+	})
+}
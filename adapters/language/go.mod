@@ -0,0 +1,17 @@
+module github.com/adzil/structmap/adapters/language
+
+go 1.23.0
+
+require (
+	github.com/adzil/structmap v0.1.0
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/text v0.26.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/adzil/structmap => ../..
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package language provides the structmap codec for golang.org/x/text
+// language.Tag fields and slices, e.g. "?lang=pt-BR" or "?lang=en,fr" with
+// the comma option.
+package language
+
+import (
+	"fmt"
+
+	"github.com/adzil/structmap"
+	"golang.org/x/text/language"
+)
+
+// Codecs returns the codecs for the language tag types.
+func Codecs() []structmap.Codec {
+	return []structmap.Codec{
+		structmap.NewCodec(formatTag, parseTag),
+	}
+}
+
+func formatTag(val language.Tag) (string, error) {
+	return val.String(), nil
+}
+
+func parseTag(s string) (language.Tag, error) {
+	val, err := language.Parse(s)
+	if err != nil {
+		return language.Und, fmt.Errorf("invalid language tag %q: %w", s, err)
+	}
+
+	return val, nil
+}
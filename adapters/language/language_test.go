@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package language_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	languageadapter "github.com/adzil/structmap/adapters/language"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+type testStruct struct {
+	Lang      language.Tag   `map:"lang"`
+	Fallbacks []language.Tag `map:"fallback,comma"`
+}
+
+func TestCodecs(t *testing.T) {
+	input := testStruct{
+		Lang:      language.BrazilianPortuguese,
+		Fallbacks: []language.Tag{language.English, language.French},
+	}
+
+	encoded := map[string][]string{
+		"lang":     {"pt-BR"},
+		"fallback": {"en,fr"},
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		m := structmap.NewMarshaler(structmap.MarshalConfig{
+			Codecs: languageadapter.Codecs(),
+		})
+
+		actual := make(map[string][]string)
+
+		err := m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, encoded, actual)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			Codecs: languageadapter.Codecs(),
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(encoded, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, input, actual)
+
+		err = u.Unmarshal(map[string][]string{"lang": {"not a tag!"}}, &actual)
+		assert.ErrorContains(t, err, `key lang: invalid language tag "not a tag!"`)
+	})
+}
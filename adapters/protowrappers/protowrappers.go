@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package protowrappers provides the structmap codecs for the protobuf
+// well-known wrapper types.
+//
+// The wrappers keep their presence semantics: a nil wrapper is never written
+// by Marshal and an absent key leaves it nil on Unmarshal, while a non-nil
+// wrapper holding a zero value is still written.
+package protowrappers
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/adzil/structmap"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Codecs returns the codecs for all of the wrapper types.
+func Codecs() []structmap.Codec {
+	return []structmap.Codec{
+		structmap.NewCodec(formatString, parseString),
+		structmap.NewCodec(formatBytes, parseBytes),
+		structmap.NewCodec(formatBool, parseBool),
+		structmap.NewCodec(formatInt32, parseInt32),
+		structmap.NewCodec(formatInt64, parseInt64),
+		structmap.NewCodec(formatUInt32, parseUInt32),
+		structmap.NewCodec(formatUInt64, parseUInt64),
+		structmap.NewCodec(formatFloat, parseFloat),
+		structmap.NewCodec(formatDouble, parseDouble),
+	}
+}
+
+func formatString(val *wrapperspb.StringValue) (string, error) {
+	return val.GetValue(), nil
+}
+
+func parseString(s string) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String(s), nil
+}
+
+func formatBytes(val *wrapperspb.BytesValue) (string, error) {
+	return base64.StdEncoding.EncodeToString(val.GetValue()), nil
+}
+
+func parseBytes(s string) (*wrapperspb.BytesValue, error) {
+	val, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.Bytes(val), nil
+}
+
+func formatBool(val *wrapperspb.BoolValue) (string, error) {
+	return strconv.FormatBool(val.GetValue()), nil
+}
+
+func parseBool(s string) (*wrapperspb.BoolValue, error) {
+	val, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.Bool(val), nil
+}
+
+func formatInt32(val *wrapperspb.Int32Value) (string, error) {
+	return strconv.FormatInt(int64(val.GetValue()), 10), nil
+}
+
+func parseInt32(s string) (*wrapperspb.Int32Value, error) {
+	val, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.Int32(int32(val)), nil
+}
+
+func formatInt64(val *wrapperspb.Int64Value) (string, error) {
+	return strconv.FormatInt(val.GetValue(), 10), nil
+}
+
+func parseInt64(s string) (*wrapperspb.Int64Value, error) {
+	val, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.Int64(val), nil
+}
+
+func formatUInt32(val *wrapperspb.UInt32Value) (string, error) {
+	return strconv.FormatUint(uint64(val.GetValue()), 10), nil
+}
+
+func parseUInt32(s string) (*wrapperspb.UInt32Value, error) {
+	val, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.UInt32(uint32(val)), nil
+}
+
+func formatUInt64(val *wrapperspb.UInt64Value) (string, error) {
+	return strconv.FormatUint(val.GetValue(), 10), nil
+}
+
+func parseUInt64(s string) (*wrapperspb.UInt64Value, error) {
+	val, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.UInt64(val), nil
+}
+
+func formatFloat(val *wrapperspb.FloatValue) (string, error) {
+	return strconv.FormatFloat(float64(val.GetValue()), 'g', -1, 32), nil
+}
+
+func parseFloat(s string) (*wrapperspb.FloatValue, error) {
+	val, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.Float(float32(val)), nil
+}
+
+func formatDouble(val *wrapperspb.DoubleValue) (string, error) {
+	return strconv.FormatFloat(val.GetValue(), 'g', -1, 64), nil
+}
+
+func parseDouble(s string) (*wrapperspb.DoubleValue, error) {
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapperspb.Double(val), nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protowrappers_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/adzil/structmap/adapters/protowrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type listRequest struct {
+	Query    *wrapperspb.StringValue  `map:"query"`
+	PageSize *wrapperspb.Int32Value   `map:"page_size,omitempty"`
+	Archived *wrapperspb.BoolValue    `map:"archived"`
+	Ids      []*wrapperspb.Int64Value `map:"ids"`
+}
+
+func TestCodecs(t *testing.T) {
+	m := structmap.NewMarshaler(structmap.MarshalConfig{Codecs: protowrappers.Codecs()})
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{Codecs: protowrappers.Codecs()})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := map[string][]string{
+			"query":    {""},
+			"archived": {"true"},
+			"ids":      {"1", "2"},
+		}
+
+		var actual listRequest
+
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(wrapperspb.String(""), actual.Query))
+		assert.Nil(t, actual.PageSize)
+		assert.True(t, proto.Equal(wrapperspb.Bool(true), actual.Archived))
+		require.Len(t, actual.Ids, 2)
+		assert.True(t, proto.Equal(wrapperspb.Int64(2), actual.Ids[1]))
+
+		err = u.Unmarshal(map[string][]string{"page_size": {"abc"}}, &actual)
+		assert.ErrorContains(t, err, "key page_size")
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		expected := map[string][]string{
+			"page_size": {"0"},
+			"ids":       {"3"},
+		}
+
+		input := listRequest{
+			PageSize: wrapperspb.Int32(0),
+			Ids:      []*wrapperspb.Int64Value{wrapperspb.Int64(3)},
+		}
+
+		actual := make(map[string][]string)
+
+		err := m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
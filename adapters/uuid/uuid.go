@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package uuid provides the structmap codec for github.com/google/uuid.UUID
+// fields and slices.
+package uuid
+
+import (
+	"fmt"
+
+	"github.com/adzil/structmap"
+	"github.com/google/uuid"
+)
+
+// Codecs returns the codecs for the UUID types.
+func Codecs() []structmap.Codec {
+	return []structmap.Codec{
+		structmap.NewCodec(formatUUID, parseUUID),
+	}
+}
+
+func formatUUID(val uuid.UUID) (string, error) {
+	return val.String(), nil
+}
+
+func parseUUID(s string) (uuid.UUID, error) {
+	val, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uuid_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	uuidadapter "github.com/adzil/structmap/adapters/uuid"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStruct struct {
+	ID      uuid.UUID   `map:"id,required"`
+	Related []uuid.UUID `map:"related,omitempty"`
+}
+
+func TestCodecs(t *testing.T) {
+	expected := testStruct{
+		ID: uuid.MustParse("7d444840-9dc0-11d1-b245-5ffdce74fad2"),
+		Related: []uuid.UUID{
+			uuid.MustParse("a8098c1a-f86e-11da-bd1a-00112444be1e"),
+		},
+	}
+
+	data := map[string][]string{
+		"id":      {"7d444840-9dc0-11d1-b245-5ffdce74fad2"},
+		"related": {"a8098c1a-f86e-11da-bd1a-00112444be1e"},
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		m := structmap.NewMarshaler(structmap.MarshalConfig{Codecs: uuidadapter.Codecs()})
+
+		actual := make(map[string][]string)
+
+		err := m.Marshal(expected, actual)
+		require.NoError(t, err)
+		assert.Equal(t, data, actual)
+
+		err = m.Marshal(testStruct{}, actual)
+		assert.ErrorContains(t, err, "key id")
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{Codecs: uuidadapter.Codecs()})
+
+		var actual testStruct
+
+		err := u.Unmarshal(data, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		err = u.Unmarshal(map[string][]string{
+			"id":      {"7d444840-9dc0-11d1-b245-5ffdce74fad2"},
+			"related": {"not-a-uuid"},
+		}, &actual)
+		assert.ErrorContains(t, err, `key related: slice index #0: invalid UUID "not-a-uuid"`)
+	})
+}
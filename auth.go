@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// authParams formats a struct into an Authorization or WWW-Authenticate header
+// value. The field with the value option holds the scheme, the field with the
+// token68 option holds the token68 credentials (e.g. "Basic dXNlcjpwYXNz"), and
+// the other fields are the parameters.
+var authParams = &paramsFormat{
+	name:    "auth",
+	value:   true,
+	token68: true,
+	invalid: errors.New("auth option is only valid for struct"),
+	format:  formatAuthParams,
+	parse:   parseAuthParams,
+}
+
+var (
+	errInvalidToken68      = errors.New("token68 option is only valid inside an auth struct")
+	errMultipleAuthSchemes = errors.New("multiple auth challenges are not supported")
+	errUnexpectedAuthToken = errors.New("auth token68 is not expected without a token68 field")
+)
+
+// authToken68Key is the key of the field with the token68 option inside an auth
+// struct. It can never collide with the parameters as they must be a token.
+const authToken68Key = "="
+
+// isToken68 reports whether s is an RFC 7235 token68, which is the base64-like
+// credentials such as "dXNlcjpwYXNz" or "abc.def==".
+func isToken68(s string) bool {
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return false
+	}
+
+	return strings.IndexFunc(s, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("-._~+/", r))
+	}) < 0
+}
+
+// formatAuthParams formats the scheme and the parameters of an RFC 7235
+// challenge or credentials, e.g. `Bearer realm="api", error="invalid_token"`.
+// The parameters are sorted by their name and always quoted, and they cannot
+// be combined with the token68. Nothing is written without a scheme.
+func formatAuthParams(params map[string]string, _ map[string]bool) (string, error) {
+	scheme := params[mimeValueKey]
+	if scheme == "" {
+		return "", nil
+	}
+
+	token68 := params[authToken68Key]
+
+	delete(params, mimeValueKey)
+	delete(params, authToken68Key)
+
+	if strings.IndexFunc(scheme, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+		return "", fmt.Errorf("invalid auth scheme %q", scheme)
+	}
+
+	if token68 != "" {
+		if !isToken68(token68) {
+			return "", fmt.Errorf("invalid auth token68 %q", token68)
+		}
+
+		if len(params) > 0 {
+			return "", errors.New("auth token68 cannot be combined with the parameters")
+		}
+
+		return scheme + " " + token68, nil
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(scheme)
+
+	for i, key := range slices.Sorted(maps.Keys(params)) {
+		if strings.IndexFunc(key, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+			return "", fmt.Errorf("invalid auth parameter %q", key)
+		}
+
+		if i == 0 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(quoteString(params[key]))
+	}
+
+	return sb.String(), nil
+}
+
+// parseAuthParams parses a single RFC 7235 challenge or credentials into its
+// parameters, keyed by their lowercase name, and its scheme, keyed by
+// mimeValueKey. The token68 is keyed by authToken68Key, which is only accepted
+// when the flags has it. Multiple challenges are rejected, either in separate
+// values or in a comma-separated list.
+func parseAuthParams(values []string, flags map[string]bool) ([]map[string]string, error) {
+	if len(values) > 1 {
+		return nil, errMultipleAuthSchemes
+	}
+
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(values[0]), " ")
+	if scheme == "" {
+		return nil, errors.New("missing auth scheme")
+	}
+
+	params := map[string]string{mimeValueKey: scheme}
+
+	if rest = strings.TrimSpace(rest); isToken68(rest) {
+		if !flags[authToken68Key] {
+			return nil, errUnexpectedAuthToken
+		}
+
+		params[authToken68Key] = rest
+
+		return []map[string]string{params}, nil
+	}
+
+	for _, param := range splitList([]string{rest}) {
+		key, val, ok := strings.Cut(param, "=")
+
+		// A bare token or a key with a space starts the next challenge, e.g.
+		// `Basic realm="a", Bearer realm="b"`.
+		key = strings.TrimSpace(key)
+		if !ok || strings.ContainsAny(key, " \t") {
+			return nil, errMultipleAuthSchemes
+		}
+
+		key = strings.ToLower(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid auth parameter %q", param)
+		}
+
+		params[key] = unquoteString(strings.TrimSpace(val))
+	}
+
+	return []map[string]string{params}, nil
+}
+
+// quoteString always quotes s as an RFC 7230 quoted string.
+func quoteString(s string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteByte(s[i])
+	}
+
+	sb.WriteByte('"')
+
+	return sb.String()
+}
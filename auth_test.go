@@ -0,0 +1,173 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthOption(t *testing.T) {
+	type Challenge struct {
+		Scheme      string `map:",value"`
+		Realm       string `map:"realm"`
+		Error       string `map:"error"`
+		Description string `map:"error_description"`
+	}
+
+	type Credentials struct {
+		Scheme   string `map:",value"`
+		Username string `map:"username"`
+		Nonce    string `map:"nonce"`
+	}
+
+	type testHeader struct {
+		Challenge     *Challenge  `map:"www-authenticate,auth"`
+		Authorization Credentials `map:"authorization,auth"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Www-Authenticate": {`Bearer realm="api, v2", Error=invalid_token, error_description="The \"token\" expired"`},
+			"Authorization":    {`Digest username="alice", nonce="abc"`},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testHeader{
+			Challenge: &Challenge{
+				Scheme:      "Bearer",
+				Realm:       "api, v2",
+				Error:       "invalid_token",
+				Description: `The "token" expired`,
+			},
+			Authorization: Credentials{Scheme: "Digest", Username: "alice", Nonce: "abc"},
+		}, actual)
+
+		input.Set("Authorization", "Digest username")
+
+		err = structmap.UnmarshalHeader(input, &actual)
+		assert.ErrorContains(t, err, "key Authorization")
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{
+			Challenge:     &Challenge{Scheme: "Bearer", Realm: "api", Error: "invalid_token"},
+			Authorization: Credentials{Scheme: "Digest", Username: `a"b`},
+		}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Www-Authenticate": {`Bearer error="invalid_token", realm="api"`},
+			"Authorization":    {`Digest username="a\"b"`},
+		}, actual)
+
+		err = structmap.MarshalHeader(testHeader{Authorization: Credentials{Scheme: "Bad Scheme"}}, actual)
+		assert.ErrorContains(t, err, `invalid auth scheme "Bad Scheme"`)
+	})
+
+	t.Run("WithToken68", func(t *testing.T) {
+		type Token struct {
+			Scheme  string `map:",value"`
+			Token68 string `map:",token68"`
+			Realm   string `map:"realm"`
+		}
+
+		var actual struct {
+			Authorization Token `map:"authorization,auth"`
+		}
+
+		for _, val := range []string{"Basic dXNlcjpwYXNz", "Bearer abc.def=="} {
+			err := structmap.UnmarshalHeader(http.Header{"Authorization": {val}}, &actual)
+			require.NoError(t, err)
+
+			scheme, token68, _ := strings.Cut(val, " ")
+			assert.Equal(t, Token{Scheme: scheme, Token68: token68}, actual.Authorization)
+
+			output := make(http.Header)
+
+			err = structmap.MarshalHeader(actual, output)
+			require.NoError(t, err)
+			assert.Equal(t, http.Header{"Authorization": {val}}, output)
+		}
+
+		actual.Authorization = Token{Scheme: "Basic", Token68: "a b"}
+
+		err := structmap.MarshalHeader(actual, http.Header{})
+		assert.ErrorContains(t, err, `invalid auth token68 "a b"`)
+
+		actual.Authorization = Token{Scheme: "Basic", Token68: "abc", Realm: "api"}
+
+		err = structmap.MarshalHeader(actual, http.Header{})
+		assert.ErrorContains(t, err, "cannot be combined with the parameters")
+
+		// The token68 is not dropped when there is no field to hold it.
+		err = structmap.UnmarshalHeader(http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}}, &testHeader{})
+		assert.ErrorContains(t, err, "auth token68 is not expected")
+
+		var invalid struct {
+			Token string `map:"token,token68"`
+		}
+
+		err = structmap.UnmarshalHeader(nil, &invalid)
+		assert.ErrorContains(t, err, "token68 option is only valid inside an auth struct")
+	})
+
+	t.Run("WithMultipleChallenges", func(t *testing.T) {
+		for _, input := range []http.Header{
+			{"Www-Authenticate": {`Basic realm="a", Bearer realm="b"`}},
+			{"Www-Authenticate": {`Bearer realm="a", Basic`}},
+			{"Www-Authenticate": {`Basic realm="a"`, `Bearer realm="b"`}},
+		} {
+			var actual testHeader
+
+			err := structmap.UnmarshalHeader(input, &actual)
+			assert.ErrorContains(t, err, "multiple auth challenges are not supported")
+		}
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Authorization string `map:"authorization,auth"`
+		}
+
+		err := structmap.UnmarshalHeader(nil, &actual)
+		assert.ErrorContains(t, err, "auth option is only valid for struct")
+	})
+
+	t.Run("WithOtherParamsOption", func(t *testing.T) {
+		var actual struct {
+			Authorization Credentials `map:"authorization,auth,mime"`
+		}
+
+		err := structmap.UnmarshalHeader(nil, &actual)
+		assert.ErrorContains(t, err, "cannot combine auth and mime options")
+
+		err = structmap.MarshalHeader(actual, map[string][]string{})
+		assert.ErrorContains(t, err, "cannot combine auth and mime options")
+	})
+}
@@ -55,3 +55,22 @@ func (c *cache[K, V]) Get(key K, getter func(K) (V, error)) (val V, err error) {
 
 	return val, err
 }
+
+// Clear removes all the cached values.
+func (c *cache[K, V]) Clear() {
+	c.mu.Lock()
+	c.stor = nil
+	c.mu.Unlock()
+}
+
+// Reset clears the cache of all the package-level marshalers and
+// unmarshalers.
+func Reset() {
+	for _, m := range []*Marshaler{&DefaultMarshaler, &HeaderMarshaler, &MetadataMarshaler, &LabelsMarshaler, &QueryStringMarshaler, &FormMarshaler} {
+		m.ClearCache()
+	}
+
+	for _, u := range []*Unmarshaler{&DefaultUnmarshaler, &HeaderUnmarshaler, &MetadataUnmarshaler, &SchemaUnmarshaler, &FormUnmarshaler} {
+		u.ClearCache()
+	}
+}
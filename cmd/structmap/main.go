@@ -0,0 +1,159 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command structmap prints the resolved key schemas of the struct types with
+// the structmap tags in the given packages, e.g. to review or to diff the
+// parameter surface of an API between releases.
+//
+// Usage:
+//
+//	structmap [-tag map] [-format table|json|ts] [-encoders] [packages]
+//
+// The ts format emits a TypeScript interface for each type, keyed by the
+// resolved keys, and with -encoders a function that encodes it into the
+// URLSearchParams.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "structmap:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("structmap", flag.ContinueOnError)
+
+	var (
+		tagName   = fs.String("tag", "map", "struct tag to read")
+		delimiter = fs.String("delimiter", ".", "delimiter of the nested keys")
+		format    = fs.String("format", "table", "output format: table, json or ts")
+		encoders  = fs.Bool("encoders", false, "emit the TypeScript encoder functions")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	schemas, err := loadSchemas(patterns, *tagName, *delimiter)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "table":
+		return writeTable(w, schemas)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(schemas)
+	case "ts":
+		return writeTypeScript(w, schemas, *encoders)
+	}
+
+	return fmt.Errorf("unknown format %s", *format)
+}
+
+func loadSchemas(patterns []string, tagName, delimiter string) ([]Schema, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedTypes}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, err)
+		}
+	})
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var schemas []Schema
+
+	for _, pkg := range pkgs {
+		b := &schemaBuilder{
+			tagName:   tagName,
+			delimiter: delimiter,
+			qualifier: types.RelativeTo(pkg.Types),
+		}
+
+		scope := pkg.Types.Scope()
+
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+
+			st, ok := obj.Type().Underlying().(*types.Struct)
+			if !ok || !b.hasTags(st) {
+				continue
+			}
+
+			schemas = append(schemas, b.build(pkg.PkgPath+"."+name, st))
+		}
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Type < schemas[j].Type
+	})
+
+	return schemas, nil
+}
+
+func writeTable(w io.Writer, schemas []Schema) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for i, schema := range schemas {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+
+		fmt.Fprintln(tw, schema.Type)
+		fmt.Fprintln(tw, "KEY\tFIELD\tTYPE\tOPTIONS")
+
+		for _, fld := range schema.Fields {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", fld.Key, fld.Field, fld.Type, strings.Join(fld.Options, ","))
+		}
+	}
+
+	return tw.Flush()
+}
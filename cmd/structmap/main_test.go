@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("Table", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := run([]string{"./testdata/example"}, &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "KEY               FIELD               TYPE               OPTIONS\n")
+		assert.Contains(t, buf.String(), "limit             Page.Limit          int                default=10\n")
+		assert.Contains(t, buf.String(), "items.{i}.name    Items[{i}].Name     string")
+		assert.Contains(t, buf.String(), "owner.{key}.name  Owners[{key}].Name  string")
+		assert.Contains(t, buf.String(), "label.{key}       Labels              map[string]string  prefix\n")
+		assert.Contains(t, buf.String(), "X-Token           Token               string             header\n")
+		assert.Contains(t, buf.String(), "Authorization     Auth                Credentials        header,auth\n")
+		assert.Contains(t, buf.String(), "Forwarded         Proxy               []Forward          header,forwarded\n")
+		assert.NotContains(t, buf.String(), "Hidden")
+		assert.NotContains(t, buf.String(), "Scheme")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := run([]string{"-format", "json", "./testdata/example"}, &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), `"key": "label.{key}"`)
+		assert.Contains(t, buf.String(), `"key": "X-Token"`)
+		assert.NotContains(t, buf.String(), "Untagged")
+	})
+}
+
+func TestRunTypeScript(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := run([]string{"-format", "ts", "-encoders", "./testdata/example"}, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `export interface Query {
+  limit?: number;
+  cursor?: string;
+  q: string;
+  since?: string;
+  match: string;
+  [key: `+"`items.${number}.name`"+`]: string | undefined;
+  [key: `+"`owner.${string}.name`"+`]: string | undefined;
+  [key: `+"`label.${string}`"+`]: string | undefined;
+  "X-Token"?: string;
+  Authorization?: string;
+  Forwarded?: string;
+}
+
+export function encodeQuery(v: Query): URLSearchParams {
+  return encodeParams(v, ["X-Token", "Authorization", "Forwarded"]);
+}
+`)
+}
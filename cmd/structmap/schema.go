@@ -0,0 +1,246 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"go/types"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/adzil/structmap"
+)
+
+// Schema is the resolved keys of a struct type.
+type Schema struct {
+	Type   string  `json:"type"`
+	Fields []Field `json:"fields"`
+}
+
+// Field is a single resolved key. The placeholders "{i}" and "{key}" stand for
+// the slice indexes and the map keys respectively.
+type Field struct {
+	Key     string   `json:"key"`
+	Field   string   `json:"field"`
+	Type    string   `json:"type"`
+	Options []string `json:"options,omitempty"`
+
+	typ types.Type
+	// single reports whether the field is marshaled into a single value by
+	// one of its options.
+	single bool
+}
+
+// schemaBuilder resolves the keys of the struct types the same way as the
+// structmap runtime with its default configuration.
+type schemaBuilder struct {
+	tagName   string
+	delimiter string
+	qualifier types.Qualifier
+}
+
+// hasTags reports whether any field of the struct has the tag.
+func (b *schemaBuilder) hasTags(st *types.Struct) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if _, ok := reflect.StructTag(st.Tag(i)).Lookup(b.tagName); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *schemaBuilder) build(name string, st *types.Struct) Schema {
+	return Schema{
+		Type:   name,
+		Fields: b.fields(st, nil, "", nil),
+	}
+}
+
+func (b *schemaBuilder) fields(st *types.Struct, prefix []string, path string, seen []*types.Struct) []Field {
+	// Stop at the recursive types, which are only resolved up to the first
+	// repetition.
+	for _, s := range seen {
+		if s == st {
+			return nil
+		}
+	}
+
+	seen = append(seen, st)
+
+	var out []Field
+
+	for i := 0; i < st.NumFields(); i++ {
+		fld := st.Field(i)
+		if !fld.Exported() {
+			continue
+		}
+
+		name, opts := structmap.ParseTag(reflect.StructTag(st.Tag(i)).Get(b.tagName))
+		if name == "-" && len(opts) == 0 {
+			continue
+		}
+
+		// The value and token68 options are only valid inside the structs with
+		// one of the single value options, which are not walked.
+		if slices.ContainsFunc(opts, func(opt structmap.TagOption) bool { return opt.Name == "value" || opt.Name == "token68" }) {
+			continue
+		}
+
+		keyPrefix := prefix
+		if name != "" {
+			keyPrefix = append(keyPrefix[:len(keyPrefix):len(keyPrefix)], name)
+		} else if !fld.Embedded() {
+			keyPrefix = append(keyPrefix[:len(keyPrefix):len(keyPrefix)], fld.Name())
+		}
+
+		fieldPath := fld.Name()
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		out = append(out, b.field(fld.Type(), keyPrefix, fieldPath, opts, seen)...)
+	}
+
+	return out
+}
+
+func (b *schemaBuilder) field(
+	typ types.Type,
+	prefix []string,
+	path string,
+	opts []structmap.TagOption,
+	seen []*types.Struct,
+) []Field {
+	var (
+		options []string
+		source  string
+		isMap   bool
+		single  bool
+	)
+
+	for _, opt := range opts {
+		switch opt.Name {
+		case "":
+			continue
+		case "query", "header", "path", "form", "cookie", "fragment":
+			source = opt.Name
+		case "prefix":
+			isMap = true
+		case "structured", "auth", "mime", "link", "forwarded", "directives":
+			// The whole field is marshaled into a single value, e.g. a
+			// struct with the auth option, so it is not walked.
+			single = true
+		}
+
+		if opt.Value != "" {
+			options = append(options, opt.Name+"="+opt.Value)
+		} else {
+			options = append(options, opt.Name)
+		}
+	}
+
+	elem := derefType(typ)
+
+	if single {
+		field := b.leaf(typ, prefix, path, source, options, false)
+		field.single = true
+
+		return []Field{field}
+	}
+
+	if st, ok := elem.Underlying().(*types.Struct); ok && !isValueType(elem) {
+		return b.fields(st, prefix, path, seen)
+	}
+
+	if slice, ok := elem.Underlying().(*types.Slice); ok && !isValueType(elem) {
+		if st, ok := derefType(slice.Elem()).Underlying().(*types.Struct); ok && !isValueType(derefType(slice.Elem())) {
+			return b.fields(st, append(prefix[:len(prefix):len(prefix)], "{i}"), path+"[{i}]", seen)
+		}
+	}
+
+	if m, ok := elem.Underlying().(*types.Map); ok && !isMap && !isValueType(elem) {
+		if st, ok := derefType(m.Elem()).Underlying().(*types.Struct); ok && !isValueType(derefType(m.Elem())) {
+			return b.fields(st, append(prefix[:len(prefix):len(prefix)], "{key}"), path+"[{key}]", seen)
+		}
+	}
+
+	return []Field{b.leaf(typ, prefix, path, source, options, isMap)}
+}
+
+func (b *schemaBuilder) leaf(typ types.Type, prefix []string, path, source string, options []string, isMap bool) Field {
+	key := strings.Join(prefix, b.delimiter)
+	if isMap {
+		key += "{key}"
+	}
+
+	if source == "header" {
+		key = http.CanonicalHeaderKey(key)
+	}
+
+	return Field{
+		Key:     key,
+		Field:   path,
+		Type:    types.TypeString(typ, b.qualifier),
+		Options: options,
+		typ:     typ,
+	}
+}
+
+func derefType(typ types.Type) types.Type {
+	for {
+		ptr, ok := typ.Underlying().(*types.Pointer)
+		if !ok {
+			return typ
+		}
+
+		typ = ptr.Elem()
+	}
+}
+
+// builtinTypes are the named types with a built-in support in the structmap
+// runtime, keyed by their package path and name.
+var builtinTypes = map[string]bool{
+	"time.Time":        true,
+	"net/mail.Address": true,
+	"regexp.Regexp":    true,
+}
+
+// isValueType reports whether the type is marshaled as a single value instead
+// of being walked, i.e. the built-in types and the types with the value
+// methods.
+func isValueType(typ types.Type) bool {
+	if named, ok := typ.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && builtinTypes[obj.Pkg().Path()+"."+obj.Name()] {
+			return true
+		}
+	}
+
+	for _, name := range []string{"MarshalValue", "UnmarshalValue", "MarshalMap", "UnmarshalMap"} {
+		for _, t := range []types.Type{typ, types.NewPointer(typ)} {
+			if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name); obj != nil {
+				if _, ok := obj.(*types.Func); ok {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,43 @@
+package example
+
+import (
+	"regexp"
+	"time"
+)
+
+type Page struct {
+	Limit  int    `map:"limit,default=10"`
+	Cursor string `map:"cursor"`
+}
+
+type Item struct {
+	Name string `map:"name"`
+}
+
+type Credentials struct {
+	Scheme string `map:",value"`
+	Realm  string `map:"realm"`
+}
+
+type Forward struct {
+	For   string `map:"for"`
+	Proto string `map:"proto"`
+}
+
+type Query struct {
+	Page
+	Search string            `map:"q,required"`
+	Since  *time.Time        `map:"since"`
+	Match  *regexp.Regexp    `map:"match,required"`
+	Items  []Item            `map:"items"`
+	Owners map[string]Item   `map:"owner"`
+	Labels map[string]string `map:"label.,prefix"`
+	Token  string            `map:"x-token,header"`
+	Auth   Credentials       `map:"authorization,header,auth"`
+	Proxy  []Forward         `map:"forwarded,header,forwarded"`
+	Hidden string            `map:"-"`
+}
+
+type Untagged struct {
+	Name string
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/types"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var tsIdentRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+const tsEncodeParams = `function encodeParams(v: object, skip: string[]): URLSearchParams {
+  const params = new URLSearchParams();
+  for (const [key, val] of Object.entries(v)) {
+    if (val === undefined || val === null || skip.includes(key)) {
+      continue;
+    }
+    for (const elem of Array.isArray(val) ? val : [val]) {
+      params.append(key, String(elem));
+    }
+  }
+  return params;
+}
+`
+
+// writeTypeScript writes the schemas as the TypeScript interfaces, where the
+// keys with placeholders become template literal index signatures.
+func writeTypeScript(w io.Writer, schemas []Schema, encoders bool) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "// Code generated by structmap. DO NOT EDIT.")
+
+	if encoders {
+		fmt.Fprintln(bw)
+		fmt.Fprint(bw, tsEncodeParams)
+	}
+
+	for _, schema := range schemas {
+		name := schema.Type[strings.LastIndex(schema.Type, ".")+1:]
+
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "export interface %s {\n", name)
+
+		for _, fld := range schema.Fields {
+			fmt.Fprintf(bw, "  %s: %s;\n", tsProperty(fld), tsFieldType(fld))
+		}
+
+		fmt.Fprintln(bw, "}")
+
+		if encoders {
+			fmt.Fprintln(bw)
+			fmt.Fprintf(bw, "export function encode%s(v: %s): URLSearchParams {\n", name, name)
+			fmt.Fprintf(bw, "  return encodeParams(v, [%s]);\n", strings.Join(tsSkippedKeys(schema), ", "))
+			fmt.Fprintln(bw, "}")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// tsSkippedKeys lists the keys that are not encoded into the URLSearchParams,
+// i.e. the ones bound to the header, path or cookie.
+func tsSkippedKeys(schema Schema) []string {
+	var keys []string
+
+	for _, fld := range schema.Fields {
+		for _, opt := range fld.Options {
+			if opt == "header" || opt == "path" || opt == "cookie" {
+				keys = append(keys, strconv.Quote(fld.Key))
+
+				break
+			}
+		}
+	}
+
+	return keys
+}
+
+func tsProperty(fld Field) string {
+	if strings.Contains(fld.Key, "{i}") || strings.Contains(fld.Key, "{key}") {
+		key := strings.ReplaceAll(fld.Key, "`", "\\`")
+		key = strings.ReplaceAll(key, "{i}", "${number}")
+		key = strings.ReplaceAll(key, "{key}", "${string}")
+
+		return "[key: `" + key + "`]"
+	}
+
+	prop := fld.Key
+	if !tsIdentRegexp.MatchString(prop) {
+		prop = strconv.Quote(prop)
+	}
+
+	for _, opt := range fld.Options {
+		if opt == "required" {
+			return prop
+		}
+	}
+
+	return prop + "?"
+}
+
+func tsFieldType(fld Field) string {
+	// The fields with a single value option are written as a header value.
+	if fld.single {
+		return "string"
+	}
+
+	typ := derefType(fld.typ)
+
+	// The prefix map is flattened into its entries.
+	if m, ok := typ.Underlying().(*types.Map); ok && strings.Contains(fld.Key, "{key}") {
+		typ = m.Elem()
+	}
+
+	ts := tsType(typ)
+
+	// The index signatures cannot be optional, so they allow undefined instead.
+	if strings.Contains(fld.Key, "{i}") || strings.Contains(fld.Key, "{key}") {
+		ts += " | undefined"
+	}
+
+	return ts
+}
+
+func tsType(typ types.Type) string {
+	typ = derefType(typ)
+
+	if isValueType(typ) {
+		return "string"
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			return "boolean"
+		case t.Info()&types.IsNumeric != 0 && t.Info()&types.IsComplex == 0:
+			return "number"
+		}
+
+	case *types.Slice:
+		if b, ok := t.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "string"
+		}
+
+		return tsElemType(t.Elem()) + "[]"
+
+	case *types.Array:
+		return tsElemType(t.Elem()) + "[]"
+	}
+
+	return "string"
+}
+
+func tsElemType(typ types.Type) string {
+	ts := tsType(typ)
+	if strings.Contains(ts, " ") {
+		return "(" + ts + ")"
+	}
+
+	return ts
+}
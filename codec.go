@@ -0,0 +1,212 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	_ marshaler   = (*codecMarshaler)(nil)
+	_ unmarshaler = (*codecUnmarshaler)(nil)
+)
+
+// Codec converts a value of a specific type from and into a single string. It
+// allows types that are not supported natively (e.g. from other packages) to
+// be used as fields or slice elements, and takes precedence over any other
+// handling of the same type.
+type Codec struct {
+	typ    reflect.Type
+	format func(src reflect.Value) (string, error)
+	parse  func(s string, dst reflect.Value) error
+}
+
+// NewCodec creates a Codec for the type T. A nil format or parse function
+// makes the type unsupported for Marshal or Unmarshal respectively. When T is
+// a slice, the results of parsing each value of the key are appended.
+func NewCodec[T any](format func(val T) (string, error), parse func(s string) (T, error)) Codec {
+	codec := Codec{
+		typ: reflect.TypeOf((*T)(nil)).Elem(),
+	}
+
+	if format != nil {
+		codec.format = func(src reflect.Value) (string, error) {
+			return format(src.Interface().(T))
+		}
+	}
+
+	if parse != nil {
+		codec.parse = func(s string, dst reflect.Value) error {
+			val, err := parse(s)
+			if err != nil {
+				return err
+			}
+
+			dst.Set(reflect.ValueOf(&val).Elem())
+
+			return nil
+		}
+	}
+
+	return codec
+}
+
+// Type returns the type handled by the codec.
+func (c Codec) Type() reflect.Type {
+	return c.typ
+}
+
+// builtinCodecs handles the types from the standard library that are
+// supported out of the box. The codecs from the config take precedence.
+var builtinCodecs = []Codec{
+	NewCodec(formatRegexp, regexp.Compile),
+	NewCodec(formatMailAddress, parseMailAddress),
+	NewCodec(formatMailAddressPtr, mail.ParseAddress),
+	NewCodec(formatMailAddressList, mail.ParseAddressList),
+}
+
+func findCodec(codecs []Codec, typ reflect.Type) (Codec, bool) {
+	for i := len(codecs) - 1; i >= 0; i-- {
+		if codecs[i].typ == typ {
+			return codecs[i], true
+		}
+	}
+
+	for _, codec := range builtinCodecs {
+		if codec.typ == typ {
+			return codec, true
+		}
+	}
+
+	return Codec{}, false
+}
+
+func formatRegexp(re *regexp.Regexp) (string, error) {
+	if re == nil {
+		return "", nil
+	}
+
+	return re.String(), nil
+}
+
+func isNilValue(src reflect.Value) bool {
+	switch src.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return src.IsNil()
+	}
+
+	return false
+}
+
+type codecMarshaler struct {
+	keyMarshaler
+	format func(src reflect.Value) (string, error)
+}
+
+func (m *codecMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if src.IsZero() {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		// There is nothing to format from a nil value, so it is always omitted.
+		if m.omitEmpty || isNilValue(src) {
+			return nil
+		}
+	}
+
+	val, err := m.format(src)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", ctx.key(m.key), err)
+	}
+
+	ctx.set(v, m.key, val)
+
+	return nil
+}
+
+type codecUnmarshaler struct {
+	typ   reflect.Type
+	parse func(s string, dst reflect.Value) error
+}
+
+func newCodecUnmarshaler(codec Codec) *codecUnmarshaler {
+	return &codecUnmarshaler{typ: codec.typ, parse: codec.parse}
+}
+
+func (u *codecUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if u.typ.Kind() != reflect.Slice || len(ctx.value) == 1 {
+		return u.parse(ctx.value[0], dst)
+	}
+
+	// Each value of a slice type is parsed on its own and appended, e.g. the
+	// address lists of a repeated header.
+	out := reflect.MakeSlice(u.typ, 0, len(ctx.value))
+
+	for i, s := range ctx.value {
+		val := reflect.New(u.typ).Elem()
+
+		if err := u.parse(s, val); err != nil {
+			return &FieldError{Index: i, Value: s, Err: err}
+		}
+
+		out = reflect.AppendSlice(out, val)
+	}
+
+	dst.Set(out)
+
+	return nil
+}
+
+func formatMailAddress(addr mail.Address) (string, error) {
+	return addr.String(), nil
+}
+
+func parseMailAddress(s string) (mail.Address, error) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return mail.Address{}, err
+	}
+
+	return *addr, nil
+}
+
+func formatMailAddressPtr(addr *mail.Address) (string, error) {
+	if addr == nil {
+		return "", nil
+	}
+
+	return addr.String(), nil
+}
+
+// formatMailAddressList formats the addresses into a single RFC 5322 address
+// list, the same as parsed by mail.ParseAddressList.
+func formatMailAddressList(list []*mail.Address) (string, error) {
+	out := make([]string, 0, len(list))
+
+	for _, addr := range list {
+		if addr != nil {
+			out = append(out, addr.String())
+		}
+	}
+
+	return strings.Join(out, ", "), nil
+}
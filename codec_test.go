@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/mail"
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	addrCodec := structmap.NewCodec(
+		func(val netip.Addr) (string, error) { return val.String(), nil },
+		netip.ParseAddr,
+	)
+
+	type testStruct struct {
+		Addr  netip.Addr   `map:"addr"`
+		Peers []netip.Addr `map:"peers"`
+	}
+
+	expected := testStruct{
+		Addr:  netip.MustParseAddr("10.0.0.1"),
+		Peers: []netip.Addr{netip.MustParseAddr("::1"), netip.MustParseAddr("10.0.0.2")},
+	}
+
+	data := map[string][]string{
+		"addr":  {"10.0.0.1"},
+		"peers": {"::1", "10.0.0.2"},
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		m := structmap.NewMarshaler(structmap.MarshalConfig{
+			Codecs: []structmap.Codec{addrCodec},
+		})
+
+		actual := make(map[string][]string)
+
+		err := m.Marshal(expected, actual)
+		require.NoError(t, err)
+		assert.Equal(t, data, actual)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			Codecs: []structmap.Codec{addrCodec},
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(data, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		err = u.Unmarshal(map[string][]string{"peers": {"invalid"}}, &actual)
+		assert.ErrorContains(t, err, "key peers")
+	})
+}
+
+func TestRaw(t *testing.T) {
+	type testStruct struct {
+		Filter structmap.Raw `map:"filter"`
+		Sort   structmap.Raw `map:"sort,omitempty"`
+	}
+
+	input := map[string][]string{
+		"filter": {" a,b ", "", "%20"},
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, structmap.Raw{" a,b ", "", "%20"}, actual.Filter)
+
+	input["filter"][0] = "changed"
+	assert.Equal(t, " a,b ", actual.Filter[0])
+
+	output := make(map[string][]string)
+
+	err = structmap.Marshal(actual, output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"filter": {" a,b ", "", "%20"}}, output)
+}
+
+func TestRegexp(t *testing.T) {
+	type testStruct struct {
+		Pattern  *regexp.Regexp   `map:"pattern"`
+		Patterns []*regexp.Regexp `map:"patterns"`
+		Optional *regexp.Regexp   `map:"optional"`
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(map[string][]string{
+		"pattern":  {"^a+$"},
+		"patterns": {"b", "c.*"},
+	}, &actual)
+	require.NoError(t, err)
+	assert.True(t, actual.Pattern.MatchString("aaa"))
+	require.Len(t, actual.Patterns, 2)
+	assert.Equal(t, "c.*", actual.Patterns[1].String())
+	assert.Nil(t, actual.Optional)
+
+	output := make(map[string][]string)
+
+	err = structmap.Marshal(actual, output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"pattern": {"^a+$"}, "patterns": {"b", "c.*"}}, output)
+
+	err = structmap.Unmarshal(map[string][]string{"pattern": {"(a"}}, &actual)
+
+	var fe *structmap.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "pattern", fe.Key)
+	assert.ErrorContains(t, err, "missing closing )")
+}
+
+func TestMailAddress(t *testing.T) {
+	type testStruct struct {
+		From    mail.Address    `map:"From"`
+		ReplyTo *mail.Address   `map:"Reply-To"`
+		To      []*mail.Address `map:"To"`
+	}
+
+	input := map[string][]string{
+		"From":     {"Alice <alice@example.com>"},
+		"Reply-To": {"noreply@example.com"},
+		"To":       {`"Bob, Jr." <bob@example.com>, carol@example.com`},
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, mail.Address{Name: "Alice", Address: "alice@example.com"}, actual.From)
+	assert.Equal(t, &mail.Address{Address: "noreply@example.com"}, actual.ReplyTo)
+	assert.Equal(t, []*mail.Address{
+		{Name: "Bob, Jr.", Address: "bob@example.com"},
+		{Address: "carol@example.com"},
+	}, actual.To)
+
+	output := make(map[string][]string)
+
+	err = structmap.Marshal(actual, output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"From":     {`"Alice" <alice@example.com>`},
+		"Reply-To": {"<noreply@example.com>"},
+		"To":       {`"Bob, Jr." <bob@example.com>, <carol@example.com>`},
+	}, output)
+
+	err = structmap.Unmarshal(map[string][]string{
+		"To": {"bob@example.com, carol@example.com", "dave@example.com"},
+	}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, []*mail.Address{
+		{Address: "bob@example.com"},
+		{Address: "carol@example.com"},
+		{Address: "dave@example.com"},
+	}, actual.To)
+
+	err = structmap.Unmarshal(map[string][]string{"To": {"not an address"}}, &actual)
+
+	var fe *structmap.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "To", fe.Key)
+
+	err = structmap.Unmarshal(map[string][]string{"To": {"bob@example.com", "not an address"}}, &actual)
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "To", fe.Key)
+	assert.Equal(t, 1, fe.Index)
+}
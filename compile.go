@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Config combines the configuration of both directions for Compile.
+type Config struct {
+	Marshal   MarshalConfig
+	Unmarshal UnmarshalConfig
+}
+
+// TypedMarshaler is a Marshaler compiled for the type T, which skips the type
+// lookup on every call.
+type TypedMarshaler[T any] struct {
+	marshaler marshaler
+}
+
+// NewTypedMarshaler compiles the type T under cfg.
+func NewTypedMarshaler[T any](cfg MarshalConfig) (*TypedMarshaler[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	m, err := newMarshaler(newMarshalConfig(cfg), typ)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s: %w", typ.String(), err)
+	}
+
+	return &TypedMarshaler[T]{marshaler: m}, nil
+}
+
+func (m *TypedMarshaler[T]) Marshal(src T, v map[string][]string) error {
+	if v == nil {
+		return errors.New("cannot marshal into a nil map")
+	}
+
+	return m.marshaler.marshal(marshalContext{}, reflect.ValueOf(&src).Elem(), v)
+}
+
+// TypedUnmarshaler is an Unmarshaler compiled for the type T, which skips the
+// type lookup on every call.
+type TypedUnmarshaler[T any] struct {
+	unmarshaler unmarshaler
+	config      UnmarshalConfig
+}
+
+// NewTypedUnmarshaler compiles the type T under cfg.
+func NewTypedUnmarshaler[T any](cfg UnmarshalConfig) (*TypedUnmarshaler[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	u, err := newUnmarshaler(newUnmarshalConfig(cfg), typ)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", typ.String(), err)
+	}
+
+	return &TypedUnmarshaler[T]{unmarshaler: u, config: cfg}, nil
+}
+
+func (u *TypedUnmarshaler[T]) Unmarshal(v map[string][]string, dst *T) error {
+	if dst == nil {
+		return errors.New("can only unmarshal into a non-nil pointer")
+	}
+
+	return u.unmarshaler.unmarshal(u.config.newContext(), u.config.inputKeys(v), reflect.ValueOf(dst).Elem())
+}
+
+// Schema is the compiled marshaler and unmarshaler of the type T.
+type Schema[T any] struct {
+	*TypedMarshaler[T]
+	*TypedUnmarshaler[T]
+}
+
+// Normalize unmarshals v into T and marshals it back, returning the canonical
+// map of v with the defaults applied and the unknown keys dropped.
+func (s *Schema[T]) Normalize(v map[string][]string) (map[string][]string, error) {
+	var dst T
+	if err := s.Unmarshal(v, &dst); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]string)
+	if err := s.Marshal(dst, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Normalize is like Schema.Normalize using the default marshaler and
+// unmarshaler, e.g. for an API gateway to forward a cleaned-up parameter set.
+func Normalize[T any](v map[string][]string) (map[string][]string, error) {
+	dst, err := UnmarshalAs[T](v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]string)
+	if err := DefaultMarshaler.Marshal(dst, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Compile compiles the type T under cfg, returning any error in its tags
+// upfront instead of on the first Marshal or Unmarshal.
+func Compile[T any](cfg Config) (*Schema[T], error) {
+	m, err := NewTypedMarshaler[T](cfg.Marshal)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := NewTypedUnmarshaler[T](cfg.Unmarshal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema[T]{
+		TypedMarshaler:   m,
+		TypedUnmarshaler: u,
+	}, nil
+}
+
+// MustCompile is like Compile but panics on error. It is intended for package
+// level variables, so the schema mistakes surface at startup.
+func MustCompile[T any](cfg Config) *Schema[T] {
+	s, err := Compile[T](cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Check reports whether the type T can be compiled under cfg in both
+// directions, without needing any value or data.
+func Check[T any](cfg Config) error {
+	_, err := Compile[T](cfg)
+
+	return err
+}
+
+// CheckType is like Check for a type that is only known at runtime, e.g. one
+// that is built using reflect.StructOf.
+func CheckType(typ reflect.Type, cfg Config) error {
+	if _, err := newMarshaler(newMarshalConfig(cfg.Marshal), typ); err != nil {
+		return fmt.Errorf("marshal %s: %w", typ.String(), err)
+	}
+
+	if _, err := newUnmarshaler(newUnmarshalConfig(cfg.Unmarshal), typ); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", typ.String(), err)
+	}
+
+	return nil
+}
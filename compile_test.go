@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userQuery struct {
+	Name  string `map:"name,required"`
+	Limit int    `map:"limit,default:10"`
+}
+
+var userQuerySchema = structmap.MustCompile[userQuery](structmap.Config{})
+
+func TestCompile(t *testing.T) {
+	t.Run("WithValidType", func(t *testing.T) {
+		require.NoError(t, structmap.Check[userQuery](structmap.Config{}))
+
+		var actual userQuery
+
+		err := userQuerySchema.Unmarshal(map[string][]string{"name": {"alice"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, userQuery{Name: "alice", Limit: 10}, actual)
+
+		output := make(map[string][]string)
+
+		err = userQuerySchema.Marshal(actual, output)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"name": {"alice"}, "limit": {"10"}}, output)
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		type invalidQuery struct {
+			Limit int `map:"limit,default:ten"`
+		}
+
+		_, err := structmap.Compile[invalidQuery](structmap.Config{})
+		assert.ErrorContains(t, err, "invalid default value")
+
+		err = structmap.Check[invalidQuery](structmap.Config{})
+		assert.ErrorContains(t, err, "invalid default value")
+
+		assert.Panics(t, func() {
+			structmap.MustCompile[invalidQuery](structmap.Config{})
+		})
+	})
+
+	t.Run("WithRuntimeType", func(t *testing.T) {
+		typ := reflect.StructOf([]reflect.StructField{
+			{Name: "Limit", Type: reflect.TypeOf(0), Tag: `map:"limit,unknown"`},
+		})
+
+		err := structmap.CheckType(typ, structmap.Config{})
+		assert.ErrorContains(t, err, "unknown option unknown")
+
+		err = structmap.CheckType(reflect.TypeOf(userQuery{}), structmap.Config{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestTypedMarshaler(t *testing.T) {
+	m, err := structmap.NewTypedMarshaler[userQuery](structmap.MarshalConfig{})
+	require.NoError(t, err)
+
+	actual := make(map[string][]string)
+
+	err = m.Marshal(userQuery{Name: "alice", Limit: 5}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"alice"}, "limit": {"5"}}, actual)
+
+	_, err = structmap.NewTypedMarshaler[chan int](structmap.MarshalConfig{})
+	assert.ErrorContains(t, err, "cannot marshal from chan")
+}
+
+func TestTypedUnmarshaler(t *testing.T) {
+	u, err := structmap.NewTypedUnmarshaler[userQuery](structmap.UnmarshalConfig{})
+	require.NoError(t, err)
+
+	var actual userQuery
+
+	err = u.Unmarshal(map[string][]string{"name": {"alice"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, userQuery{Name: "alice", Limit: 10}, actual)
+
+	err = u.Unmarshal(nil, nil)
+	assert.ErrorContains(t, err, "non-nil pointer")
+
+	_, err = structmap.NewTypedUnmarshaler[chan int](structmap.UnmarshalConfig{})
+	assert.ErrorContains(t, err, "cannot unmarshal into chan")
+}
+
+func TestNormalize(t *testing.T) {
+	type testStruct struct {
+		Query string   `map:"q,required"`
+		Page  int      `map:"page,default:1"`
+		Tags  []string `map:"tags,omitempty"`
+	}
+
+	input := map[string][]string{
+		"q":       {"go"},
+		"unknown": {"x"},
+	}
+
+	expected := map[string][]string{
+		"q":    {"go"},
+		"page": {"1"},
+	}
+
+	actual, err := structmap.Normalize[testStruct](input)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	schema := structmap.MustCompile[testStruct](structmap.Config{
+		Marshal:   structmap.MarshalConfig{KeyLookupFunc: strings.ToUpper},
+		Unmarshal: structmap.UnmarshalConfig{KeyFold: structmap.KeyFoldCase},
+	})
+
+	actual, err = schema.Normalize(map[string][]string{"Q": {"go"}, "tags": {"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"Q": {"go"}, "PAGE": {"1"}, "TAGS": {"a"}}, actual)
+
+	_, err = structmap.Normalize[testStruct](nil)
+	assert.ErrorContains(t, err, "q")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// directivesParams formats a struct into the comma-separated directives of a
+// header such as Cache-Control. The bool fields are written as a directive
+// without any value when they are true.
+var directivesParams = &paramsFormat{
+	name:    "directives",
+	invalid: errors.New("directives option is only valid for struct"),
+	format:  formatDirectives,
+	parse:   parseDirectives,
+}
+
+// parseDirectives parses the comma-separated directives of a header such as
+// Cache-Control, e.g. `max-age=60, no-cache, private="Set-Cookie"`.
+func parseDirectives(values []string, flags map[string]bool) ([]map[string]string, error) {
+	params := make(map[string]string)
+
+	for _, directive := range splitList(values) {
+		key, val, ok := strings.Cut(directive, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		switch {
+		case flags[key]:
+			val = "true"
+		case ok:
+			val = unquoteString(strings.TrimSpace(val))
+		}
+
+		params[key] = val
+	}
+
+	return []map[string]string{params}, nil
+}
+
+// formatDirectives formats the directives sorted by their name. The values are
+// only quoted when they are not a token.
+func formatDirectives(params map[string]string, flags map[string]bool) (string, error) {
+	var directives []string
+
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		val := params[key]
+
+		if flags[key] {
+			if val == "true" {
+				directives = append(directives, key)
+			}
+
+			continue
+		}
+
+		if strings.IndexFunc(val, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+			val = quoteString(val)
+		}
+
+		directives = append(directives, key+"="+val)
+	}
+
+	return strings.Join(directives, ", "), nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectivesOption(t *testing.T) {
+	type CacheControl struct {
+		MaxAge  *int   `map:"max-age"`
+		SMaxAge int    `map:"s-maxage,omitempty"`
+		NoStore bool   `map:"no-store"`
+		NoCache bool   `map:"no-cache"`
+		Public  bool   `map:"public"`
+		Private string `map:"private,omitempty"`
+	}
+
+	type testHeader struct {
+		CacheControl CacheControl `map:"cache-control,directives"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Cache-Control": {`Max-Age=60, no-store, private="Set-Cookie, Authorization"`, "public, unknown=1"},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+
+		maxAge := 60
+		assert.Equal(t, CacheControl{
+			MaxAge:  &maxAge,
+			NoStore: true,
+			Public:  true,
+			Private: "Set-Cookie, Authorization",
+		}, actual.CacheControl)
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			KeyFold:    structmap.KeyFoldStripSeparators,
+			NullValues: []string{"60"},
+		})
+
+		// The parameter keys and values are not folded or nulled.
+		actual = testHeader{}
+
+		err = u.Unmarshal(map[string][]string{"cache-control": {"max-age=60, no-store"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, CacheControl{MaxAge: &maxAge, NoStore: true}, actual.CacheControl)
+
+		input.Set("Cache-Control", "max-age=soon")
+
+		err = structmap.UnmarshalHeader(input, &actual)
+		assert.ErrorContains(t, err, "key Cache-Control")
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		maxAge := 0
+
+		input := testHeader{CacheControl: CacheControl{
+			MaxAge:  &maxAge,
+			NoCache: true,
+			Private: "Set-Cookie",
+		}}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Cache-Control": {"max-age=0, no-cache, private=Set-Cookie"},
+		}, actual)
+
+		var output testHeader
+
+		err = structmap.UnmarshalHeader(actual, &output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+
+		actual = make(http.Header)
+
+		err = structmap.MarshalHeader(testHeader{}, actual)
+		require.NoError(t, err)
+		assert.Empty(t, actual)
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			CacheControl string `map:"cache-control,directives"`
+		}
+
+		err := structmap.UnmarshalHeader(nil, &actual)
+		assert.ErrorContains(t, err, "directives option is only valid for struct")
+	})
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"slices"
+	"strings"
+)
+
+// QueryEncoder builds a query string with precise control over its encoding,
+// so it can be byte-identical to what a signature verifier expects (e.g. AWS
+// SigV4 or OAuth 1.0).
+type QueryEncoder struct {
+	// SpaceAsPlus encodes spaces as "+" instead of "%20".
+	SpaceAsPlus bool
+
+	// LowerHex uses lowercase hexadecimal digits for percent-encoding.
+	LowerHex bool
+
+	// Strict only leaves the RFC 3986 unreserved characters unescaped.
+	// Otherwise, the "!'()*" characters are also left as they are, the same
+	// as the JavaScript encodeURIComponent.
+	Strict bool
+
+	// SortValues sorts the values of each key by their encoded form.
+	SortValues bool
+}
+
+func (e QueryEncoder) shouldEscape(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return false
+	}
+
+	switch c {
+	case '-', '_', '.', '~':
+		return false
+	case '!', '\'', '(', ')', '*':
+		return e.Strict
+	}
+
+	return true
+}
+
+// Escape percent-encodes s.
+func (e QueryEncoder) Escape(s string) string {
+	hex := "0123456789ABCDEF"
+	if e.LowerHex {
+		hex = "0123456789abcdef"
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == ' ' && e.SpaceAsPlus:
+			sb.WriteByte('+')
+		case e.shouldEscape(c):
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&15])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String()
+}
+
+// Encode encodes kvs into a query string. The keys are sorted by their encoded
+// form, so the output of MarshalSorted or any other ordering can be used.
+func (e QueryEncoder) Encode(kvs []KeyValues) string {
+	type pair struct {
+		key    string
+		values []string
+	}
+
+	pairs := make([]pair, len(kvs))
+
+	for i, kv := range kvs {
+		values := make([]string, len(kv.Values))
+		for j, val := range kv.Values {
+			values[j] = e.Escape(val)
+		}
+
+		if e.SortValues {
+			slices.Sort(values)
+		}
+
+		pairs[i] = pair{key: e.Escape(kv.Key), values: values}
+	}
+
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	var sb strings.Builder
+
+	for _, p := range pairs {
+		for _, val := range p.values {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+
+			sb.WriteString(p.key)
+			sb.WriteByte('=')
+			sb.WriteString(val)
+		}
+	}
+
+	return sb.String()
+}
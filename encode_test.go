@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryEncoder(t *testing.T) {
+	type listRequest struct {
+		Prefix string   `map:"prefix"`
+		Marker string   `map:"marker"`
+		Tags   []string `map:"tag"`
+	}
+
+	kvs, err := structmap.MarshalSorted(listRequest{
+		Prefix: "photos/2023 (old)*",
+		Marker: "ä~",
+		Tags:   []string{"b", "a"},
+	})
+	require.NoError(t, err)
+
+	t.Run("Default", func(t *testing.T) {
+		var enc structmap.QueryEncoder
+
+		assert.Equal(t, "marker=%C3%A4~&prefix=photos%2F2023%20(old)*&tag=b&tag=a", enc.Encode(kvs))
+	})
+
+	t.Run("WithStrict", func(t *testing.T) {
+		enc := structmap.QueryEncoder{Strict: true, SortValues: true}
+
+		assert.Equal(t, "marker=%C3%A4~&prefix=photos%2F2023%20%28old%29%2A&tag=a&tag=b", enc.Encode(kvs))
+	})
+
+	t.Run("WithFormStyle", func(t *testing.T) {
+		enc := structmap.QueryEncoder{SpaceAsPlus: true, Strict: true}
+
+		expected := url.Values{
+			"prefix": {"photos/2023 (old)*"},
+			"marker": {"ä~"},
+			"tag":    {"b", "a"},
+		}
+
+		assert.Equal(t, expected.Encode(), enc.Encode(kvs))
+	})
+
+	t.Run("WithLowerHex", func(t *testing.T) {
+		enc := structmap.QueryEncoder{LowerHex: true}
+
+		assert.Equal(t, "%c3%a4", enc.Escape("ä"))
+	})
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	_ keyMatcher = (*structUnmarshaler)(nil)
+	_ keyMatcher = (*pointerUnmarshaler)(nil)
+	_ keyMatcher = (*indexedSliceUnmarshaler)(nil)
+	_ keyMatcher = (*prefixMapUnmarshaler)(nil)
+	_ keyMatcher = (*structMapUnmarshaler)(nil)
+	_ keyMatcher = (*interfaceUnmarshaler)(nil)
+)
+
+// keyMatcher is implemented by the nested unmarshalers to report whether a key
+// of the input map is read by them, without parsing any value.
+type keyMatcher interface {
+	matchKey(key string) bool
+}
+
+func matchKey(u unmarshaler, key string) bool {
+	if m, ok := u.(keyMatcher); ok {
+		return m.matchKey(key)
+	}
+
+	return false
+}
+
+func (c *fieldUnmarshaler) matchKey(key string) bool {
+	if c.nested {
+		return matchKey(c.unmarshaler, key)
+	}
+
+	if key == c.name {
+		return true
+	}
+
+	for _, alias := range c.aliases {
+		if key == alias.name {
+			return true
+		}
+	}
+
+	if c.indexKey != nil {
+		// The index is the last run of digits in the key, e.g. "key[2]".
+		end := strings.LastIndexFunc(key, isDigit) + 1
+		start := strings.LastIndexFunc(key[:end], func(r rune) bool { return !isDigit(r) }) + 1
+
+		if i, err := strconv.Atoi(key[start:end]); err == nil && c.indexKey(i) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func (u *structUnmarshaler) matchKey(key string) bool {
+	for i := range u.fields {
+		if u.fields[i].matchKey(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *pointerUnmarshaler) matchKey(key string) bool {
+	return matchKey(u.elem, key)
+}
+
+func (u *indexedSliceUnmarshaler) matchKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, u.prefix)
+	if !ok {
+		return false
+	}
+
+	index, sub, ok := strings.Cut(rest, u.suffix)
+	if !ok {
+		return false
+	}
+
+	if _, err := strconv.Atoi(index); err != nil {
+		return false
+	}
+
+	if u.lookup != nil {
+		sub = u.lookup(sub)
+	}
+
+	return matchKey(u.elem, sub)
+}
+
+func (u *prefixMapUnmarshaler) matchKey(key string) bool {
+	return len(key) > len(u.prefix) && strings.HasPrefix(key, u.prefix)
+}
+
+func (u *structMapUnmarshaler) matchKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, u.prefix)
+	if !ok {
+		return false
+	}
+
+	name, sub, ok := strings.Cut(rest, u.suffix)
+	if !ok || name == "" {
+		return false
+	}
+
+	if u.lookup != nil {
+		sub = u.lookup(sub)
+	}
+
+	return matchKey(u.elem, sub)
+}
+
+func (u *interfaceUnmarshaler) matchKey(key string) bool {
+	if u.key != "" && key == u.key {
+		return true
+	}
+
+	for _, cand := range u.candidates {
+		if matchKey(cand.elem, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func filterKnown(vu unmarshaler, cfg UnmarshalConfig, v map[string][]string) map[string][]string {
+	out := make(map[string][]string)
+
+	for key, val := range cfg.inputKeys(v) {
+		if matchKey(vu, key) {
+			out[key] = val
+		}
+	}
+
+	return out
+}
+
+// FilterKnown returns a copy of v without the keys that are not read by u into
+// the type pointed by dst, which is not modified. The keys are rewritten and
+// folded as in Unmarshal, while the values are kept as-is without being
+// parsed, e.g. for a cheap sanitization before proxying.
+func (u *Unmarshaler) FilterKnown(v map[string][]string, dst any) (map[string][]string, error) {
+	vu, _, err := u.getUnmarshaler(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKnown(vu, u.config, v), nil
+}
+
+// FilterKnown is like Unmarshaler.FilterKnown for the type T.
+func (u *TypedUnmarshaler[T]) FilterKnown(v map[string][]string) map[string][]string {
+	return filterKnown(u.unmarshaler, u.config, v)
+}
+
+// FilterKnown is like Unmarshaler.FilterKnown using the default unmarshaler.
+func FilterKnown[T any](v map[string][]string) (map[string][]string, error) {
+	return DefaultUnmarshaler.FilterKnown(v, new(T))
+}
+
+// fieldMask holds the keys of MarshalMask and UnmarshalMask.
+type fieldMask struct {
+	keys  []string
+	delim string
+}
+
+// covers reports whether key is one of the mask keys or nested under one of
+// them, e.g. "filter" covers both "filter.name" and "filter[0]".
+func (m *fieldMask) covers(key string) bool {
+	for _, k := range m.keys {
+		rest, ok := strings.CutPrefix(key, k)
+		if !ok {
+			continue
+		}
+
+		if rest == "" || strings.HasPrefix(rest, m.delim) || rest[0] == '[' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// touches reports whether any key under the prefix is covered by the mask.
+func (m *fieldMask) touches(prefix string) bool {
+	for _, k := range m.keys {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+
+	return m.covers(prefix)
+}
+
+// MarshalMask is like Marshal, but only sets the keys covered by the mask,
+// where a key also covers all the keys nested under it. The fields are still
+// marshaled as a whole, so the required fields outside the mask must be set.
+func (m *Marshaler) MarshalMask(src any, v map[string][]string, mask []string) error {
+	out := make(map[string][]string)
+	if err := m.Marshal(src, out); err != nil {
+		return err
+	}
+
+	fm := &fieldMask{keys: mask, delim: m.config.delimiter()}
+
+	for key, val := range out {
+		if fm.covers(key) {
+			v[key] = val
+		}
+	}
+
+	return nil
+}
+
+func MarshalMask(src any, v map[string][]string, mask []string) error {
+	return DefaultMarshaler.MarshalMask(src, v, mask)
+}
+
+// UnmarshalMask is like Unmarshal, but only applies the fields whose keys are
+// covered by the mask, where a key also covers all the keys nested under it.
+// The other fields of dst are left untouched and their required and default
+// options are not checked, which gives the PATCH semantics to a struct.
+func (u *Unmarshaler) UnmarshalMask(v map[string][]string, dst any, mask []string) error {
+	vu, elem, err := u.getUnmarshaler(dst)
+	if err != nil {
+		return err
+	}
+
+	ctx := u.config.newContext()
+	ctx.mask = &fieldMask{keys: mask, delim: u.config.delimiter()}
+
+	return vu.unmarshal(ctx, u.config.inputKeys(v), elem)
+}
+
+func UnmarshalMask(v map[string][]string, dst any, mask []string) error {
+	return DefaultUnmarshaler.UnmarshalMask(v, dst, mask)
+}
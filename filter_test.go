@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterKnown(t *testing.T) {
+	type Item struct {
+		Name string `map:"name"`
+	}
+
+	type Paging struct {
+		Page int `map:"page"`
+	}
+
+	type testStruct struct {
+		Query  string            `map:"q,alias=search"`
+		Items  []Item            `map:"items"`
+		Labels map[string]string `map:"label.,prefix"`
+		IDs    []int             `map:"ids"`
+		Paging *Paging           `map:"paging"`
+	}
+
+	input := map[string][]string{
+		"q":             {"go"},
+		"search":        {"old"},
+		"items.0.name":  {"a"},
+		"items.0.price": {"1"},
+		"items.x.name":  {"b"},
+		"label.env":     {"prod"},
+		"label.":        {"empty"},
+		"ids":           {"not-a-number"},
+		"paging.page":   {"2"},
+		"paging.size":   {"10"},
+		"debug":         {"true"},
+	}
+
+	expected := map[string][]string{
+		"q":            {"go"},
+		"search":       {"old"},
+		"items.0.name": {"a"},
+		"label.env":    {"prod"},
+		"ids":          {"not-a-number"},
+		"paging.page":  {"2"},
+	}
+
+	actual, err := structmap.FilterKnown[testStruct](input)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Len(t, input, 11)
+
+	t.Run("WithInputKeys", func(t *testing.T) {
+		cfg := structmap.UnmarshalConfig{
+			KeyFold:     structmap.KeyFoldCase,
+			KeyRewrites: map[string]string{"query": "q"},
+		}
+
+		input := map[string][]string{
+			"query":       {"go"},
+			"Paging.Page": {"2"},
+			"debug":       {"true"},
+		}
+
+		// The keys are returned as they are matched, i.e. folded.
+		expected := map[string][]string{
+			"Q":           {"go"},
+			"PAGING.PAGE": {"2"},
+		}
+
+		actual, err := structmap.NewUnmarshaler(cfg).FilterKnown(input, new(testStruct))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		s := structmap.MustCompile[testStruct](structmap.Config{Unmarshal: cfg})
+		assert.Equal(t, expected, s.FilterKnown(input))
+	})
+}
+
+func TestFieldMask(t *testing.T) {
+	type Address struct {
+		City string `map:"city"`
+		Zip  string `map:"zip,required"`
+	}
+
+	type testStruct struct {
+		Name    string            `map:"name,required"`
+		Age     int               `map:"age"`
+		Address Address           `map:"address"`
+		Labels  map[string]string `map:"label.,prefix"`
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testStruct{
+			Name:    "john",
+			Age:     30,
+			Address: Address{City: "x", Zip: "1"},
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.MarshalMask(input, actual, []string{"age", "address"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"age":          {"30"},
+			"address.city": {"x"},
+			"address.zip":  {"1"},
+		}, actual)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		actual := testStruct{
+			Name:    "john",
+			Age:     30,
+			Address: Address{City: "x", Zip: "1"},
+			Labels:  map[string]string{"env": "prod"},
+		}
+
+		input := map[string][]string{
+			"name":         {"ignored"},
+			"address.city": {"y"},
+		}
+
+		err := structmap.UnmarshalMask(input, &actual, []string{"age", "address.city"})
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{
+			Name:    "john",
+			Address: Address{City: "y", Zip: "1"},
+			Labels:  map[string]string{"env": "prod"},
+		}, actual)
+
+		err = structmap.UnmarshalMask(map[string][]string{"label.a": {"b"}}, &actual, []string{"label.a"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "b"}, actual.Labels)
+	})
+}
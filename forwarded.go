@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// forwardedParams formats a slice of structs into a Forwarded header value,
+// where each struct is an element and its fields are the parameters. To append
+// an element, unmarshal the header first and marshal it back with the new
+// element at the end of the slice.
+var forwardedParams = &paramsFormat{
+	name:    "forwarded",
+	list:    true,
+	invalid: errors.New("forwarded option is only valid for slice of struct"),
+	format:  formatForwarded,
+	parse:   parseForwarded,
+}
+
+// parseForwarded parses the elements of the RFC 7239 Forwarded header values,
+// e.g. `for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"`. The parameter
+// names are lowercased.
+func parseForwarded(values []string, _ map[string]bool) ([]map[string]string, error) {
+	var elems []map[string]string
+
+	for _, s := range values {
+		elem := make(map[string]string)
+
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if s == "" || s[0] == ',' {
+				if len(elem) > 0 {
+					elems = append(elems, elem)
+					elem = make(map[string]string)
+				}
+
+				if s == "" {
+					break
+				}
+
+				s = s[1:]
+
+				continue
+			}
+
+			end := strings.IndexAny(s, "=;,")
+			if end < 0 || s[end] != '=' {
+				return nil, fmt.Errorf("invalid forwarded pair %q", s)
+			}
+
+			key := strings.ToLower(strings.TrimSpace(s[:end]))
+			if key == "" {
+				return nil, fmt.Errorf("invalid forwarded pair %q", s)
+			}
+
+			// A parameter must not occur more than once per element, as in
+			// RFC 7239 section 4.
+			if _, ok := elem[key]; ok {
+				return nil, fmt.Errorf("duplicate forwarded parameter %q", key)
+			}
+
+			elem[key], s = cutParamValue(strings.TrimLeft(s[end+1:], " \t"))
+
+			s = strings.TrimLeft(s, " \t")
+			if s != "" && s[0] == ';' {
+				s = s[1:]
+			} else if s != "" && s[0] != ',' {
+				return nil, fmt.Errorf("invalid forwarded pair %q", s)
+			}
+		}
+	}
+
+	return elems, nil
+}
+
+// formatForwarded formats a single element with its parameters sorted by their
+// name. The values are only quoted when they are not a token, e.g. the IPv6
+// addresses and the ports. An element without any parameter cannot be
+// represented, so it is skipped.
+func formatForwarded(params map[string]string, _ map[string]bool) (string, error) {
+	pairs := make([]string, 0, len(params))
+
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		if strings.IndexFunc(key, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+			return "", fmt.Errorf("invalid forwarded parameter %q", key)
+		}
+
+		val := params[key]
+		if val == "" || strings.IndexFunc(val, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+			val = quoteString(val)
+		}
+
+		pairs = append(pairs, key+"="+val)
+	}
+
+	return strings.Join(pairs, ";"), nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardedOption(t *testing.T) {
+	type Forwarded struct {
+		For   string `map:"for"`
+		By    string `map:"by"`
+		Proto string `map:"proto"`
+		Host  string `map:"host"`
+	}
+
+	type testHeader struct {
+		Forwarded []Forwarded `map:"forwarded,forwarded"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Forwarded": {
+				`for=192.0.2.60;Proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"`,
+				`for=unknown; host="example.com"`,
+			},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, []Forwarded{
+			{For: "192.0.2.60", By: "203.0.113.43", Proto: "http"},
+			{For: "[2001:db8:cafe::17]:4711"},
+			{For: "unknown", Host: "example.com"},
+		}, actual.Forwarded)
+
+		for _, val := range []string{"for", "for=a;for=b", `for="a" by=b`, "=a"} {
+			err = structmap.UnmarshalHeader(http.Header{"Forwarded": {val}}, &actual)
+			assert.ErrorContains(t, err, "key Forwarded: ", val)
+		}
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{Forwarded: []Forwarded{
+			{For: "192.0.2.60", Proto: "https", Host: "example.com"},
+			{For: "[2001:db8:cafe::17]:4711", By: "_hidden"},
+		}}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Forwarded": {`for=192.0.2.60;host=example.com;proto=https, by=_hidden;for="[2001:db8:cafe::17]:4711"`},
+		}, actual)
+
+		var output testHeader
+
+		err = structmap.UnmarshalHeader(actual, &output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+	})
+
+	t.Run("WithAppend", func(t *testing.T) {
+		header := http.Header{"Forwarded": {"for=192.0.2.60"}}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(header, &actual)
+		require.NoError(t, err)
+
+		actual.Forwarded = append(actual.Forwarded, Forwarded{For: "198.51.100.17", Proto: "http"})
+
+		err = structmap.MarshalHeader(actual, header)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{"Forwarded": {"for=192.0.2.60, for=198.51.100.17;proto=http"}}, header)
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Forwarded []string `map:"forwarded,forwarded"`
+		}
+
+		err := structmap.UnmarshalHeader(nil, &actual)
+		assert.ErrorContains(t, err, "forwarded option is only valid for slice of struct")
+	})
+}
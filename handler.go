@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// StatusCoder can be implemented by the errors returned from a Handler
+// function to select the response status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Handler creates an http.Handler that binds the request into Req using
+// UnmarshalRequest, calls fn and writes Resp as JSON.
+//
+// A request that cannot be bound is answered with 400 Bad Request. An error
+// from fn is answered with 500 Internal Server Error, unless it implements
+// StatusCoder.
+func Handler[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+
+		if err := UnmarshalRequest(r, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			writeError(w, err)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		http.Error(w, err.Error(), sc.StatusCode())
+
+		return
+	}
+
+	// Hide the details of unexpected errors from the client.
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
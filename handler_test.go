@@ -0,0 +1,89 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+)
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string   { return "item not found" }
+func (notFoundError) StatusCode() int { return http.StatusNotFound }
+
+func TestHandler(t *testing.T) {
+	type getRequest struct {
+		ID   int    `map:"id,path"`
+		Lang string `map:"lang,required"`
+	}
+
+	type getResponse struct {
+		ID   int    `json:"id"`
+		Lang string `json:"lang"`
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /items/{id}", structmap.Handler(func(_ context.Context, req getRequest) (getResponse, error) {
+		switch req.ID {
+		case 0:
+			return getResponse{}, notFoundError{}
+		case 1:
+			return getResponse{}, errors.New("database is down")
+		}
+
+		return getResponse{ID: req.ID, Lang: req.Lang}, nil
+	}))
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := serve("/items/42?lang=en")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"id":42,"lang":"en"}`, w.Body.String())
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		w := serve("/items/42")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "lang")
+	})
+
+	t.Run("WithStatusCoder", func(t *testing.T) {
+		w := serve("/items/0?lang=en")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "item not found")
+	})
+
+	t.Run("WithInternalError", func(t *testing.T) {
+		w := serve("/items/1?lang=en")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "database")
+	})
+}
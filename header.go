@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+)
+
+var (
+	errInvalidList  = errors.New("list option is only valid for slice")
+	errInvalidValue = errors.New("value option is only valid inside a mime, auth or link struct")
+)
+
+// mimeValueKey is the key of the field with the value option inside a mime
+// struct. It can never collide with the parameters as they cannot be empty.
+const mimeValueKey = ""
+
+// splitList splits the comma-separated list values defined by RFC 7230
+// section 7. Commas inside quoted strings are not treated as separators, and
+// empty elements are skipped.
+func splitList(values []string) []string {
+	var out []string
+
+	for _, s := range values {
+		start, quoted := 0, false
+
+		for i := 0; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if quoted {
+					i++
+				}
+			case '"':
+				quoted = !quoted
+			case ',':
+				if !quoted {
+					out = appendListElem(out, s[start:i])
+					start = i + 1
+				}
+			}
+		}
+
+		out = appendListElem(out, s[start:])
+	}
+
+	return out
+}
+
+func appendListElem(out []string, elem string) []string {
+	elem = strings.Trim(elem, " \t")
+	if elem == "" {
+		return out
+	}
+
+	return append(out, unquoteString(elem))
+}
+
+// unquoteString removes the quotes and escapes of an RFC 7230 quoted string.
+// Any other string is returned as it is.
+func unquoteString(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+// quoteListElem quotes s when it cannot be used as a list element as it is.
+func quoteListElem(s string) string {
+	if s != "" && !strings.ContainsAny(s, `,"\`) && strings.Trim(s, " \t") == s {
+		return s
+	}
+
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteByte(s[i])
+	}
+
+	sb.WriteByte('"')
+
+	return sb.String()
+}
+
+func quoteListFormat(format func(src reflect.Value) (string, error)) func(src reflect.Value) (string, error) {
+	return func(src reflect.Value) (string, error) {
+		val, err := format(src)
+		if err != nil {
+			return "", err
+		}
+
+		return quoteListElem(val), nil
+	}
+}
+
+// mimeParams formats a struct into a parameterized header value (e.g.
+// "text/html; charset=utf-8") using mime.FormatMediaType. The field with the
+// value option holds the media type, and the other fields are the parameters.
+var mimeParams = &paramsFormat{
+	name:    "mime",
+	value:   true,
+	invalid: errors.New("mime option is only valid for struct"),
+	format:  formatMediaType,
+	parse:   parseMediaType,
+}
+
+// formatMediaType formats the media type keyed by mimeValueKey with its
+// parameters. Nothing is written without a media type.
+func formatMediaType(params map[string]string, _ map[string]bool) (string, error) {
+	typ := params[mimeValueKey]
+	if typ == "" {
+		return "", nil
+	}
+
+	delete(params, mimeValueKey)
+
+	val := mime.FormatMediaType(typ, params)
+	if val == "" {
+		return "", fmt.Errorf("invalid media type %s", typ)
+	}
+
+	return val, nil
+}
+
+// parseMediaType parses a parameterized header value using
+// mime.ParseMediaType, keying the media type by mimeValueKey.
+func parseMediaType(values []string, _ map[string]bool) ([]map[string]string, error) {
+	typ, params, err := mime.ParseMediaType(values[0])
+	if err != nil {
+		return nil, err
+	}
+
+	params[mimeValueKey] = typ
+
+	return []map[string]string{params}, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOption(t *testing.T) {
+	type testHeader struct {
+		AcceptEncoding []string `map:"accept-encoding,list"`
+		IfMatch        []string `map:"if-match,list"`
+		Vary           []string `map:"vary,omitempty"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Accept-Encoding": {"gzip, br", ",deflate ,"},
+			"If-Match":        {`"a, b", "c \"d\""`},
+			"Vary":            {"Accept, Origin"},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testHeader{
+			AcceptEncoding: []string{"gzip", "br", "deflate"},
+			IfMatch:        []string{"a, b", `c "d"`},
+			Vary:           []string{"Accept, Origin"},
+		}, actual)
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{
+			AcceptEncoding: []string{"gzip", "br"},
+			IfMatch:        []string{"a, b", `c "d"`, " e"},
+		}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Accept-Encoding": {"gzip, br"},
+			"If-Match":        {`"a, b", "c \"d\"", " e"`},
+		}, actual)
+
+		var roundTrip testHeader
+
+		err = structmap.UnmarshalHeader(actual, &roundTrip)
+		require.NoError(t, err)
+		assert.Equal(t, input, roundTrip)
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Value string `map:"value,list"`
+		}
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.ErrorContains(t, err, "list option")
+
+		err = structmap.Marshal(actual, map[string][]string{})
+		assert.ErrorContains(t, err, "list option")
+	})
+}
+
+func TestMimeOption(t *testing.T) {
+	type ContentType struct {
+		MediaType string `map:",value"`
+		Charset   string `map:"charset"`
+		Boundary  string `map:"boundary"`
+	}
+
+	type ContentDisposition struct {
+		Type     string `map:",value"`
+		Filename string `map:"filename"`
+	}
+
+	type testHeader struct {
+		ContentType        ContentType         `map:"content-type,mime,required"`
+		ContentDisposition *ContentDisposition `map:"content-disposition,mime"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Content-Type":        {"text/HTML; Charset=UTF-8"},
+			"Content-Disposition": {`attachment; filename="report 2023.pdf"`},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testHeader{
+			ContentType:        ContentType{MediaType: "text/html", Charset: "UTF-8"},
+			ContentDisposition: &ContentDisposition{Type: "attachment", Filename: "report 2023.pdf"},
+		}, actual)
+
+		input.Set("Content-Type", "text/html; charset")
+
+		err = structmap.UnmarshalHeader(input, &actual)
+		assert.ErrorContains(t, err, "key Content-Type")
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{
+			ContentType: ContentType{MediaType: "multipart/form-data", Boundary: "xyz"},
+		}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Content-Type": {"multipart/form-data; boundary=xyz"},
+		}, actual)
+
+		err = structmap.MarshalHeader(testHeader{}, actual)
+		assert.ErrorContains(t, err, "missing required value")
+	})
+
+	t.Run("WithInvalidOption", func(t *testing.T) {
+		var actual struct {
+			Value string `map:"value,mime"`
+			Type  string `map:"type,value"`
+		}
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.ErrorContains(t, err, "mime option")
+
+		err = structmap.Marshal(actual, map[string][]string{})
+		assert.ErrorContains(t, err, "mime option")
+	})
+}
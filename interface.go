@@ -0,0 +1,248 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	_ marshaler   = (*interfaceMarshaler)(nil)
+	_ unmarshaler = (*interfaceUnmarshaler)(nil)
+)
+
+// InterfaceCandidate is a concrete type that can be stored in an
+// interface-typed field. Value is only used for its type, so it is usually the
+// zero value of the concrete type (or a nil pointer to it).
+type InterfaceCandidate struct {
+	Name  string
+	Value any
+}
+
+// Interface registers the candidate concrete types for fields declared as the
+// interface Type.
+//
+// When Key is set, its value (relative to the field prefix) selects the
+// candidate by name during Unmarshal and is written back during Marshal.
+// Otherwise, the candidates are probed in order and the first one that can be
+// unmarshaled without error is used.
+type Interface struct {
+	Type       reflect.Type
+	Key        string
+	Candidates []InterfaceCandidate
+}
+
+// InterfaceOf returns the reflect.Type of the interface I, for use in
+// Interface.Type.
+func InterfaceOf[I any]() reflect.Type {
+	return reflect.TypeOf((*I)(nil)).Elem()
+}
+
+func findInterface(ifaces []Interface, typ reflect.Type) (Interface, error) {
+	for _, iface := range ifaces {
+		if iface.Type != typ {
+			continue
+		}
+
+		for _, cand := range iface.Candidates {
+			if cand.Value == nil || !reflect.TypeOf(cand.Value).Implements(typ) {
+				return Interface{}, fmt.Errorf("candidate %s does not implement %s", cand.Name, typ.String())
+			}
+		}
+
+		return iface, nil
+	}
+
+	return Interface{}, fmt.Errorf("no candidates registered for %s", typ.String())
+}
+
+type interfaceMarshaler struct {
+	cfg   marshalConfig
+	iface Interface
+	cache cache[reflect.Type, marshaler]
+}
+
+func (m *interfaceMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if src.IsNil() {
+		if m.cfg.Required {
+			return fmt.Errorf("key %s: %w", m.cfg.name(), errMissingValue)
+		}
+
+		return nil
+	}
+
+	elem := src.Elem()
+
+	vm, err := m.cache.Get(elem.Type(), func(key reflect.Type) (marshaler, error) {
+		cfg := m.cfg
+
+		base := key
+		if base.Kind() == reflect.Pointer {
+			base = base.Elem()
+		}
+
+		// The nil check is already done above, so the struct options can be
+		// safely dropped for the dynamic type.
+		if base.Kind() == reflect.Struct && base != timeReflectType {
+			cfg.Required = false
+			cfg.OmitEmpty = false
+		}
+
+		return newValueMarshaler(cfg, key)
+	})
+	if err != nil {
+		var ute *UnsupportedTypeError
+		if errors.As(err, &ute) {
+			return &UnsupportedValueError{Value: src, Field: joinFieldPath(ctx.fieldPath, m.cfg.path), msg: err.Error()}
+		}
+
+		return err
+	}
+
+	if m.iface.Key != "" && !m.cfg.skipSource() {
+		for _, cand := range m.iface.Candidates {
+			if reflect.TypeOf(cand.Value) == elem.Type() {
+				ctx.set(v, m.cfg.childName(m.iface.Key), cand.Name)
+
+				break
+			}
+		}
+	}
+
+	// Value candidates are bound to the source of the interface field itself.
+	if !isNestedMarshaler(vm) && m.cfg.skipSource() {
+		return nil
+	}
+
+	return vm.marshal(ctx, elem, v)
+}
+
+func newInterfaceMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	iface, err := findInterface(cfg.Interfaces, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the name so it will not be overwritten by the sibling fields.
+	cfg.Name = append([]string(nil), cfg.Name...)
+
+	return &interfaceMarshaler{
+		cfg:   cfg,
+		iface: iface,
+	}, nil
+}
+
+type interfaceCandidate struct {
+	name string
+	typ  reflect.Type
+	elem unmarshaler
+}
+
+type interfaceUnmarshaler struct {
+	key        string
+	candidates []interfaceCandidate
+}
+
+func (u *interfaceUnmarshaler) unmarshalCandidate(
+	ctx unmarshalContext,
+	cand interfaceCandidate,
+	v map[string][]string,
+	dst reflect.Value,
+) error {
+	val := reflect.New(cand.typ).Elem()
+
+	if err := cand.elem.unmarshal(ctx, v, val); err != nil {
+		return err
+	}
+
+	dst.Set(val)
+
+	return nil
+}
+
+func (u *interfaceUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	if u.key == "" {
+		var errs []error
+
+		for _, cand := range u.candidates {
+			err := u.unmarshalCandidate(ctx, cand, v, dst)
+			if err == nil {
+				return nil
+			}
+
+			errs = append(errs, fmt.Errorf("candidate %s: %w", cand.name, err))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	name, ok := getValue(v, u.key)
+	if !ok {
+		dst.SetZero()
+
+		return nil
+	}
+
+	for _, cand := range u.candidates {
+		if cand.name == name[0] {
+			return u.unmarshalCandidate(ctx, cand, v, dst)
+		}
+	}
+
+	return fmt.Errorf("key %s: unknown type %s", u.key, name[0])
+}
+
+func newInterfaceUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unm unmarshaler, nested bool, err error) {
+	iface, err := findInterface(cfg.Interfaces, typ)
+	if err != nil {
+		return nil, false, err
+	}
+
+	candidates := make([]interfaceCandidate, len(iface.Candidates))
+
+	for i, cand := range iface.Candidates {
+		candTyp := reflect.TypeOf(cand.Value)
+
+		elem, elemNested, err := newValueUnmarshaler(cfg, candTyp)
+		if err != nil {
+			return nil, false, fmt.Errorf("candidate %s: %w", cand.Name, err)
+		}
+
+		if i > 0 && elemNested != nested {
+			return nil, false, errors.New("interface candidates must be either all structs or all values")
+		}
+
+		nested = elemNested
+		candidates[i] = interfaceCandidate{
+			name: cand.Name,
+			typ:  candTyp,
+			elem: elem,
+		}
+	}
+
+	var key string
+	if iface.Key != "" {
+		key = cfg.lookupKey(cfg.joinKey(append(cfg.Prefix[:len(cfg.Prefix):len(cfg.Prefix)], iface.Key)))
+	}
+
+	return &interfaceUnmarshaler{
+		key:        key,
+		candidates: candidates,
+	}, nested, nil
+}
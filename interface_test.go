@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type filter interface {
+	isFilter()
+}
+
+type rangeFilter struct {
+	Min int `map:"min,required"`
+	Max int `map:"max"`
+}
+
+func (rangeFilter) isFilter() {}
+
+type termFilter struct {
+	Term string `map:"term,required"`
+}
+
+func (*termFilter) isFilter() {}
+
+func TestInterface(t *testing.T) {
+	candidates := []structmap.InterfaceCandidate{
+		{Name: "range", Value: rangeFilter{}},
+		{Name: "term", Value: (*termFilter)(nil)},
+	}
+
+	type testStruct struct {
+		Filter filter `map:"filter"`
+	}
+
+	t.Run("WithKey", func(t *testing.T) {
+		ifaces := []structmap.Interface{{
+			Type:       structmap.InterfaceOf[filter](),
+			Key:        "type",
+			Candidates: candidates,
+		}}
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{Interfaces: ifaces})
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{Interfaces: ifaces})
+
+		input := map[string][]string{
+			"filter.type": {"term"},
+			"filter.term": {"hello"},
+		}
+
+		var actual testStruct
+
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Filter: &termFilter{Term: "hello"}}, actual)
+
+		output := make(map[string][]string)
+
+		err = m.Marshal(actual, output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+
+		err = u.Unmarshal(map[string][]string{"filter.type": {"unknown"}}, &actual)
+		assert.ErrorContains(t, err, "unknown type")
+	})
+
+	t.Run("WithProbeOrder", func(t *testing.T) {
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			Interfaces: []structmap.Interface{{
+				Type:       structmap.InterfaceOf[filter](),
+				Candidates: candidates,
+			}},
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{"filter.term": {"hello"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Filter: &termFilter{Term: "hello"}}, actual)
+
+		err = u.Unmarshal(map[string][]string{"filter.min": {"1"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Filter: rangeFilter{Min: 1}}, actual)
+
+		err = u.Unmarshal(map[string][]string{}, &actual)
+		assert.ErrorContains(t, err, "candidate term")
+	})
+
+	t.Run("WithoutRegistration", func(t *testing.T) {
+		var actual testStruct
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.ErrorContains(t, err, "no candidates registered")
+	})
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	labelNameMaxLength   = 63
+	labelPrefixMaxLength = 253
+	annotationsMaxSize   = 256 * 1024
+)
+
+var (
+	labelNameRegexp   = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	labelPrefixRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// LabelsMarshaler writes the Kubernetes labels and annotations. Nested keys are
+// joined with "." and multiple values of a key are rejected, since both only
+// accept a single value per key.
+var LabelsMarshaler = Marshaler{
+	config: MarshalConfig{
+		MultiValue: MultiValueError,
+	},
+}
+
+// MarshalLabels marshals src into the Kubernetes labels. Every key must be a
+// valid label key, i.e. an optional DNS subdomain prefix and a "/" followed by
+// a name of at most 63 alphanumeric, "-", "_" or "." characters, and every
+// value must be empty or a valid name. The invalid ones are returned as a
+// FieldError, checked in the order of the keys.
+func (m *Marshaler) MarshalLabels(src any) (map[string]string, error) {
+	out, fields, err := m.marshalSingleFields(src)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(out)) {
+		if err := validateLabelKey(key); err != nil {
+			return nil, &FieldError{Key: key, Field: fields[key], Index: -1, Err: err}
+		}
+
+		val := out[key]
+		if val == "" {
+			continue
+		}
+
+		if err := validateLabelName(val); err != nil {
+			return nil, &FieldError{
+				Key:   key,
+				Field: fields[key],
+				Index: -1,
+				Value: val,
+				Err:   fmt.Errorf("invalid label value: %w", err),
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func MarshalLabels(src any) (map[string]string, error) {
+	return LabelsMarshaler.MarshalLabels(src)
+}
+
+// MarshalAnnotations marshals src into the Kubernetes annotations. Every key
+// must be valid as in MarshalLabels, while the values are unrestricted as long
+// as the total size of the keys and values is at most 256 KiB.
+func (m *Marshaler) MarshalAnnotations(src any) (map[string]string, error) {
+	out, fields, err := m.marshalSingleFields(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int
+
+	for _, key := range slices.Sorted(maps.Keys(out)) {
+		if err := validateLabelKey(key); err != nil {
+			return nil, &FieldError{Key: key, Field: fields[key], Index: -1, Err: err}
+		}
+
+		size += len(key) + len(out[key])
+	}
+
+	if size > annotationsMaxSize {
+		return nil, fmt.Errorf("annotations size of %d bytes exceeds the limit of %d bytes", size, annotationsMaxSize)
+	}
+
+	return out, nil
+}
+
+func MarshalAnnotations(src any) (map[string]string, error) {
+	return LabelsMarshaler.MarshalAnnotations(src)
+}
+
+func validateLabelKey(key string) error {
+	prefix, name, ok := strings.Cut(key, "/")
+	if !ok {
+		prefix, name = "", key
+	} else if prefix == "" {
+		return errors.New("invalid label key: empty prefix")
+	}
+
+	if prefix != "" {
+		if len(prefix) > labelPrefixMaxLength {
+			return fmt.Errorf("invalid label key: prefix must be at most %d characters", labelPrefixMaxLength)
+		}
+
+		if !labelPrefixRegexp.MatchString(prefix) {
+			return errors.New("invalid label key: prefix must be a lowercase DNS subdomain")
+		}
+	}
+
+	if err := validateLabelName(name); err != nil {
+		return fmt.Errorf("invalid label key: %w", err)
+	}
+
+	return nil
+}
+
+func validateLabelName(name string) error {
+	if len(name) > labelNameMaxLength {
+		return fmt.Errorf("name must be at most %d characters", labelNameMaxLength)
+	}
+
+	if !labelNameRegexp.MatchString(name) {
+		return errors.New("name must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character")
+	}
+
+	return nil
+}
+
+// marshalSingleFields is like MarshalSingle, but also returns the Go path of
+// the field that wrote every key.
+func (m *Marshaler) marshalSingleFields(src any) (map[string]string, map[string]string, error) {
+	val := reflect.ValueOf(src)
+
+	vm, err := m.compile(val.Type())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(map[string]string)
+	fields := make(map[string]string)
+	v := make(map[string][]string)
+
+	walkMarshaler(vm, val, "", v, func(path string, fieldErr error) bool {
+		if err = fieldErr; err != nil {
+			return false
+		}
+
+		for key, vals := range v {
+			out[key], err = m.singleValue(key, vals)
+			if err != nil {
+				return false
+			}
+
+			fields[key] = path
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, fields, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalLabels(t *testing.T) {
+	type testLabels struct {
+		Name      string `map:"app.kubernetes.io/name"`
+		Component string `map:"app.kubernetes.io/component,omitempty"`
+		Tier      string `map:"tier"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		actual, err := structmap.MarshalLabels(testLabels{Name: "my-app_1.0", Tier: ""})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"app.kubernetes.io/name": "my-app_1.0", "tier": ""}, actual)
+	})
+
+	t.Run("InvalidValue", func(t *testing.T) {
+		_, err := structmap.MarshalLabels(testLabels{Name: "-app"})
+
+		var fe *structmap.FieldError
+		require.ErrorAs(t, err, &fe)
+		assert.Equal(t, "app.kubernetes.io/name", fe.Key)
+		assert.Equal(t, "Name", fe.Field)
+		assert.Equal(t, "-app", fe.Value)
+	})
+
+	t.Run("InvalidNestedValues", func(t *testing.T) {
+		type Team struct {
+			Owner string `map:"owner"`
+			Lead  string `map:"lead"`
+		}
+
+		type testStruct struct {
+			Team Team `map:"team"`
+		}
+
+		// The keys are checked in order, so "team.lead" is always reported.
+		for i := 0; i < 10; i++ {
+			_, err := structmap.MarshalLabels(testStruct{Team: Team{Owner: "-a", Lead: "-b"}})
+
+			var fe *structmap.FieldError
+			require.ErrorAs(t, err, &fe)
+			assert.Equal(t, "team.lead", fe.Key)
+			assert.Equal(t, "Team.Lead", fe.Field)
+		}
+	})
+
+	t.Run("ValueTooLong", func(t *testing.T) {
+		_, err := structmap.MarshalLabels(testLabels{Name: strings.Repeat("a", 64)})
+		assert.EqualError(t, err, "key app.kubernetes.io/name: invalid label value: name must be at most 63 characters")
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		type testStruct struct {
+			A string `map:"Example.com/a"`
+		}
+
+		_, err := structmap.MarshalLabels(testStruct{A: "x"})
+		assert.EqualError(t, err, "key Example.com/a: invalid label key: prefix must be a lowercase DNS subdomain")
+	})
+
+	t.Run("MultipleValues", func(t *testing.T) {
+		type testStruct struct {
+			A []string `map:"a"`
+		}
+
+		_, err := structmap.MarshalLabels(testStruct{A: []string{"x", "y"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestMarshalAnnotations(t *testing.T) {
+	type testAnnotations struct {
+		Description string `map:"example.com/description"`
+	}
+
+	actual, err := structmap.MarshalAnnotations(testAnnotations{Description: "any value, even with spaces"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"example.com/description": "any value, even with spaces"}, actual)
+
+	_, err = structmap.MarshalAnnotations(testAnnotations{Description: strings.Repeat("a", 256*1024)})
+	assert.Error(t, err)
+
+	var invalid struct {
+		Owner string `map:"Example.com/owner"`
+	}
+
+	_, err = structmap.MarshalAnnotations(invalid)
+
+	var fe *structmap.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "Owner", fe.Field)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// linkParams formats a slice of structs into a Link header value. The field
+// with the value option holds the target URI, and the other fields are the
+// parameters.
+var linkParams = &paramsFormat{
+	name:    "link",
+	list:    true,
+	value:   true,
+	invalid: errors.New("link option is only valid for slice of struct"),
+	format:  formatLink,
+	parse:   parseLinks,
+}
+
+// parseLinks parses the links of the Link header values, e.g.
+// `<https://example.com/?page=2>; rel="next"`. The parameter names are
+// lowercased, and the target is keyed by mimeValueKey.
+func parseLinks(values []string, _ map[string]bool) ([]map[string]string, error) {
+	var links []map[string]string
+
+	for _, s := range values {
+		for {
+			s = strings.TrimLeft(s, " \t,")
+			if s == "" {
+				break
+			}
+
+			if s[0] != '<' {
+				return nil, fmt.Errorf("invalid link %q", s)
+			}
+
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid link %q", s)
+			}
+
+			link := map[string]string{mimeValueKey: s[1:end]}
+
+			var err error
+			if s, err = parseLinkParams(s[end+1:], link); err != nil {
+				return nil, err
+			}
+
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// parseLinkParams parses the parameters of a link into params, returning the
+// rest of s after the comma that ends the link.
+func parseLinkParams(s string, params map[string]string) (string, error) {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" || s[0] == ',' {
+			return s, nil
+		}
+
+		if s[0] != ';' {
+			return "", fmt.Errorf("invalid link parameter %q", s)
+		}
+
+		s = strings.TrimLeft(s[1:], " \t")
+
+		end := strings.IndexAny(s, "=;,")
+		if end < 0 {
+			end = len(s)
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:end]))
+		if key == "" {
+			return "", fmt.Errorf("invalid link parameter %q", s)
+		}
+
+		s = s[end:]
+
+		var val string
+		if s != "" && s[0] == '=' {
+			val, s = cutParamValue(strings.TrimLeft(s[1:], " \t"))
+		}
+
+		// Only the first occurrence of a parameter is used, as in RFC 8288
+		// section 3.
+		if _, ok := params[key]; !ok {
+			params[key] = val
+		}
+	}
+}
+
+// cutParamValue cuts a token or a quoted string from the start of s.
+func cutParamValue(s string) (val, rest string) {
+	if s == "" || s[0] != '"' {
+		end := strings.IndexAny(s, ";,")
+		if end < 0 {
+			end = len(s)
+		}
+
+		return strings.TrimSpace(s[:end]), s[end:]
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return unquoteString(s[:i+1]), s[i+1:]
+		}
+	}
+
+	return unquoteString(s + `"`), ""
+}
+
+// formatLink formats a single link with its parameters sorted by their name
+// and always quoted.
+func formatLink(params map[string]string, _ map[string]bool) (string, error) {
+	target := params[mimeValueKey]
+	if target == "" {
+		return "", errors.New("missing link target")
+	}
+
+	delete(params, mimeValueKey)
+
+	if strings.ContainsAny(target, "<>") {
+		return "", fmt.Errorf("invalid link target %q", target)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("<" + target + ">")
+
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		if strings.IndexFunc(key, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+			return "", fmt.Errorf("invalid link parameter %q", key)
+		}
+
+		sb.WriteString("; " + key + "=" + quoteString(params[key]))
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkOption(t *testing.T) {
+	type Link struct {
+		URL   string `map:",value"`
+		Rel   string `map:"rel"`
+		Title string `map:"title"`
+	}
+
+	type testHeader struct {
+		Links []Link `map:"link,link"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Link": {
+				`<https://api.example.com/items?page=2&q=a,b>; rel="next"; Title="Next, please", <https://api.example.com/items?page=1>;rel=first`,
+				`</items?page=9>; rel=last; rel=ignored`,
+			},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, []Link{
+			{URL: "https://api.example.com/items?page=2&q=a,b", Rel: "next", Title: "Next, please"},
+			{URL: "https://api.example.com/items?page=1", Rel: "first"},
+			{URL: "/items?page=9", Rel: "last"},
+		}, actual.Links)
+
+		input.Set("Link", "https://example.com; rel=next")
+
+		err = structmap.UnmarshalHeader(input, &actual)
+		assert.ErrorContains(t, err, "key Link")
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{Links: []Link{
+			{URL: "/items?page=2", Rel: "next"},
+			{URL: "/items?page=1", Rel: "prev", Title: `the "first"`},
+		}}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Link": {`</items?page=2>; rel="next", </items?page=1>; rel="prev"; title="the \"first\""`},
+		}, actual)
+
+		var output testHeader
+
+		err = structmap.UnmarshalHeader(actual, &output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+
+		err = structmap.MarshalHeader(testHeader{Links: []Link{{Rel: "next"}}}, actual)
+		assert.ErrorContains(t, err, "missing link target")
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Link []string `map:"link,link"`
+		}
+
+		err := structmap.UnmarshalHeader(nil, &actual)
+		assert.ErrorContains(t, err, "link option is only valid for slice of struct")
+	})
+}
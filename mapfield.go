@@ -0,0 +1,240 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ marshaler   = (*structMapMarshaler)(nil)
+	_ unmarshaler = (*structMapUnmarshaler)(nil)
+)
+
+// structMapMarshaler marshals a map of structs where the keys of each value
+// are prefixed with its map key, e.g. "items.a.name" or "items[a].name". The
+// value marshaler is compiled once with the keys relative to the value.
+type structMapMarshaler struct {
+	key      string
+	required bool
+	prefix   string
+	suffix   string
+	path     string
+	lookup   func(s string) string
+	elemTyp  reflect.Type
+	elem     marshaler
+}
+
+func (m *structMapMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if src.Len() == 0 && m.required {
+		return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+	}
+
+	// Marshal in order so the same input always reports the same error.
+	keys := make([]string, 0, src.Len())
+	for _, key := range src.MapKeys() {
+		keys = append(keys, key.String())
+	}
+
+	slices.Sort(keys)
+
+	// The map values are not addressable, so they are copied for the methods
+	// with a pointer receiver.
+	elem := reflect.New(m.elemTyp).Elem()
+
+	for _, key := range keys {
+		elem.Set(src.MapIndex(reflect.ValueOf(key).Convert(src.Type().Key())))
+
+		ctx := ctx
+		ctx.keyPrefix += m.prefix + key + m.suffix
+		ctx.fieldPath = joinFieldPath(ctx.fieldPath, m.path+"["+strconv.Quote(key)+"]")
+
+		if m.lookup != nil {
+			ctx.lookup = m.lookup
+		}
+
+		if err := m.elem.marshal(ctx, elem, v); err != nil {
+			return fmt.Errorf("map key %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func newStructMapMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	prefix := cfg.joinKey(cfg.Name)
+
+	// The value keys and field paths are relative to its map key, and the
+	// options only apply to the map itself. The KeyLookupFunc is applied to
+	// the full keys instead, as in the nested structs.
+	elemCfg := cfg
+	elemCfg.Name = nil
+	elemCfg.KeyLookupFunc = nil
+	elemCfg.path = ""
+	elemCfg.Required = false
+	elemCfg.OmitEmpty = false
+
+	elem, err := newValueMarshaler(elemCfg, typ.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNestedMarshaler(elem) {
+		return nil, newUnsupportedTypeError(typ.Elem(), "cannot marshal from map of %s", typ.Elem().String())
+	}
+
+	m := &structMapMarshaler{
+		key:      cfg.name(),
+		required: cfg.Required,
+		prefix:   prefix + cfg.delimiter(),
+		suffix:   cfg.delimiter(),
+		path:     cfg.path,
+		lookup:   cfg.KeyLookupFunc,
+		elemTyp:  typ.Elem(),
+		elem:     elem,
+	}
+
+	if cfg.BracketIndex {
+		m.prefix = prefix + "["
+		m.suffix = "]" + cfg.delimiter()
+	}
+
+	return m, nil
+}
+
+// structMapUnmarshaler unmarshals a map of structs by grouping the keys by
+// their map key segment, e.g. "items.a.name" or "items[a].name".
+type structMapUnmarshaler struct {
+	typ    reflect.Type
+	prefix string
+	suffix string
+	lookup func(key string) string
+	elem   unmarshaler
+}
+
+func (u *structMapUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	var groups map[string]map[string][]string
+
+	for key, val := range v {
+		rest, ok := strings.CutPrefix(key, u.prefix)
+		if !ok {
+			continue
+		}
+
+		name, sub, ok := strings.Cut(rest, u.suffix)
+		if !ok || name == "" {
+			continue
+		}
+
+		if groups == nil {
+			groups = make(map[string]map[string][]string)
+		}
+
+		if groups[name] == nil {
+			groups[name] = make(map[string][]string)
+		}
+
+		// The value keys are looked up as a part of the full key, see
+		// indexedSliceUnmarshaler.
+		if u.lookup != nil {
+			sub = u.lookup(sub)
+		}
+
+		groups[name][sub] = val
+	}
+
+	if groups == nil {
+		dst.SetZero()
+
+		return nil
+	}
+
+	out := reflect.MakeMapWithSize(u.typ, len(groups))
+
+	// Unmarshal in order so the same input always reports the same error.
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		ctx := ctx
+		if ctx.trace != nil || ctx.mask != nil {
+			ctx.keyPrefix += u.prefix + name + u.suffix
+			ctx.fieldPath = joinFieldPath(ctx.fieldPath, "["+strconv.Quote(name)+"]")
+		}
+
+		elem := reflect.New(u.typ.Elem()).Elem()
+		if err := u.elem.unmarshal(ctx, groups[name], elem); err != nil {
+			// The keys of the value fields are relative to its map key.
+			if fe, ok := err.(*FieldError); ok {
+				fe.Key = u.prefix + name + u.suffix + fe.Key
+				fe.Field = joinFieldPath("["+strconv.Quote(name)+"]", fe.Field)
+
+				return fe
+			}
+
+			return fmt.Errorf("map key %q: %w", name, err)
+		}
+
+		out.SetMapIndex(reflect.ValueOf(name).Convert(u.typ.Key()), elem)
+	}
+
+	dst.Set(out)
+
+	return nil
+}
+
+func newStructMapUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	prefix := cfg.lookupKey(cfg.joinKey(cfg.Prefix))
+
+	// The value keys are relative to its map key.
+	elemCfg := cfg
+	elemCfg.Prefix = nil
+
+	elemTyp := typ.Elem()
+
+	elem, err := newStructUnmarshaler(elemCfg, indirectType(elemTyp))
+	if err != nil {
+		return nil, err
+	}
+
+	if elemTyp.Kind() == reflect.Pointer {
+		elem = &pointerUnmarshaler{
+			elemTyp: elemTyp.Elem(),
+			elem:    elem,
+		}
+	}
+
+	unm := &structMapUnmarshaler{
+		typ:    typ,
+		prefix: prefix + cfg.delimiter(),
+		suffix: cfg.delimiter(),
+		elem:   elem,
+	}
+
+	if cfg.KeyLookupFunc != nil {
+		unm.lookup = cfg.lookupKey
+	}
+
+	if cfg.BracketIndex {
+		unm.prefix = prefix + "["
+		unm.suffix = "]" + cfg.delimiter()
+	}
+
+	return unm, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructMap(t *testing.T) {
+	type Account struct {
+		Name    string `map:"name,required"`
+		Balance int    `map:"balance,omitempty"`
+	}
+
+	type testStruct struct {
+		Accounts map[string]Account  `map:"accounts"`
+		Owners   map[string]*Account `map:"owner"`
+	}
+
+	input := map[string][]string{
+		"accounts.main.name":    {"Main"},
+		"accounts.main.balance": {"100"},
+		"accounts.savings.name": {"Savings"},
+		"owner.alice.name":      {"Alice"},
+	}
+
+	expected := testStruct{
+		Accounts: map[string]Account{
+			"main":    {Name: "Main", Balance: 100},
+			"savings": {Name: "Savings"},
+		},
+		Owners: map[string]*Account{
+			"alice": {Name: "Alice"},
+		},
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	output := make(map[string][]string)
+
+	err = structmap.Marshal(actual, output)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	err = structmap.Unmarshal(map[string][]string{
+		"accounts.main.name":    {"Main"},
+		"accounts.main.balance": {"x"},
+	}, &actual)
+	assert.ErrorContains(t, err, "key accounts.main.balance")
+
+	var fieldErr *structmap.FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, `Accounts["main"].Balance`, fieldErr.Field)
+
+	err = structmap.Unmarshal(map[string][]string{"accounts.main.balance": {"1"}}, &actual)
+	assert.ErrorContains(t, err, `map key "main"`)
+
+	t.Run("WithBracketIndex", func(t *testing.T) {
+		input := map[string][]string{"accounts[main].name": {"Main"}}
+
+		var actual testStruct
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{BracketIndex: true})
+
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]Account{"main": {Name: "Main"}}, actual.Accounts)
+
+		output := make(map[string][]string)
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{BracketIndex: true})
+
+		err = m.Marshal(actual, output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+	})
+
+	t.Run("WithHeader", func(t *testing.T) {
+		input := testStruct{Accounts: map[string]Account{"k": {Name: "Main", Balance: 100}}}
+
+		output := make(map[string][]string)
+
+		err := structmap.MarshalHeader(input, output)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"Accounts.k.name":    {"Main"},
+			"Accounts.k.balance": {"100"},
+		}, output)
+
+		// The keys are canonicalized again when they are added to the header.
+		header := make(http.Header)
+		for key, vals := range output {
+			for _, val := range vals {
+				header.Add(key, val)
+			}
+		}
+
+		var actual testStruct
+
+		err = structmap.UnmarshalHeader(header, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, input, actual)
+	})
+
+	t.Run("WithMarshalErrors", func(t *testing.T) {
+		input := testStruct{Accounts: map[string]Account{}}
+		for _, key := range []string{"e", "d", "c", "b", "a"} {
+			input.Accounts[key] = Account{}
+		}
+
+		// The value fields are counted once regardless of the map size.
+		m := structmap.NewMarshaler(structmap.MarshalConfig{MaxFields: 6})
+
+		for i := 0; i < 10; i++ {
+			err := m.Marshal(input, map[string][]string{})
+			require.ErrorContains(t, err, `map key "a": key accounts.a.name`)
+		}
+	})
+
+	t.Run("WithFilterKnown", func(t *testing.T) {
+		actual, err := structmap.FilterKnown[testStruct](map[string][]string{
+			"accounts.main.name":     {"Main"},
+			"accounts.main.nickname": {"Main"},
+			"accounts.name":          {"Main"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"accounts.main.name": {"Main"}}, actual)
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Times map[string]time.Time `map:"times"`
+		}
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.ErrorContains(t, err, "cannot unmarshal into map")
+
+		err = structmap.Marshal(actual, map[string][]string{})
+		assert.ErrorContains(t, err, "cannot marshal from map of time.Time")
+	})
+}
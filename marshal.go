@@ -17,12 +17,17 @@ limitations under the License.
 package structmap
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"iter"
 	"net/http"
+	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -30,18 +35,80 @@ var (
 	_ marshaler = (*structMarshaler)(nil)
 	_ marshaler = (*stringMarshaler)(nil)
 	_ marshaler = (*intMarshaler)(nil)
+	_ marshaler = (*uintMarshaler)(nil)
+	_ marshaler = (*boolMarshaler)(nil)
+	_ marshaler = (*floatMarshaler)(nil)
+	_ marshaler = (*complexMarshaler)(nil)
+	_ marshaler = (*charMarshaler)(nil)
 	_ marshaler = (*methodMarshaler)(nil)
 	_ marshaler = (*sliceMarshaler)(nil)
+	_ marshaler = (*indexedSliceMarshaler)(nil)
+	_ marshaler = (*timeMarshaler)(nil)
+	_ marshaler = (*escapeMarshaler)(nil)
+	_ marshaler = (*traceMarshaler)(nil)
 )
 
 var (
-	errMissingValue = errors.New("missing required value")
+	errMissingValue  = errors.New("missing required value")
+	errInvalidChar   = errors.New("char option is only valid for rune or byte")
+	errInvalidUnique = errors.New("unique option is only valid for slice")
+	errInvalidSorted = errors.New("sorted option is only valid for slice")
 )
 
+// valueEscaper escapes the values of fields with the escape option. It is
+// strict so the values can be decoded with url.QueryUnescape.
+var valueEscaper = QueryEncoder{Strict: true}
+
 var (
 	valueMarshalerReflectType = reflect.TypeOf((*ValueMarshaler)(nil)).Elem()
 )
 
+// SliceStyle selects how the values of a slice field are laid out.
+type SliceStyle int
+
+const (
+	// SliceRepeated writes every element as a value of the same key, e.g.
+	// "tags=a&tags=b".
+	SliceRepeated SliceStyle = iota
+
+	// SliceIndexed writes every element into its own indexed key, e.g.
+	// "tags.0=a&tags.1=b".
+	SliceIndexed
+
+	// SliceJoined writes all elements as a single value, e.g. "tags=a,b".
+	SliceJoined
+)
+
+// EmptySlice selects how a slice without any element is marshaled.
+type EmptySlice int
+
+const (
+	// EmptySliceKey writes the key without any value, or a single empty value
+	// for the joined slices.
+	EmptySliceKey EmptySlice = iota
+
+	// EmptySliceOmit does not write the key at all.
+	EmptySliceOmit
+
+	// EmptySliceValue writes the key with a single empty value, which is
+	// encoded as "key=" in a query string.
+	EmptySliceValue
+)
+
+// MultiValue selects how MarshalSingle handles a key with multiple values.
+type MultiValue int
+
+const (
+	// MultiValueFirst only keeps the first value.
+	MultiValueFirst MultiValue = iota
+
+	// MultiValueJoin joins the values using the SliceSeparator.
+	MultiValueJoin
+
+	// MultiValueError returns an error.
+	MultiValueError
+)
+
 var (
 	DefaultMarshaler Marshaler
 
@@ -50,14 +117,93 @@ var (
 			KeyLookupFunc: http.CanonicalHeaderKey,
 		},
 	}
+
+	// MetadataMarshaler lowercases all keys, as required by gRPC metadata, S3
+	// object metadata and similar systems.
+	MetadataMarshaler = Marshaler{
+		config: MarshalConfig{
+			KeyLookupFunc: strings.ToLower,
+		},
+	}
+
+	// QueryStringMarshaler reads the `url` tags and options used by
+	// github.com/google/go-querystring, including its "parent[child]" naming
+	// for nested structs.
+	QueryStringMarshaler = Marshaler{
+		config: MarshalConfig{
+			TagName:     "url",
+			JoinKeyFunc: joinBracketKey,
+		},
+	}
+
+	// FormMarshaler reads the `form` tags used by
+	// github.com/go-playground/form.
+	FormMarshaler = Marshaler{
+		config: MarshalConfig{
+			TagName:      "form",
+			BracketIndex: true,
+		},
+	}
 )
 
+func joinBracketKey(names []string) string {
+	var sb strings.Builder
+
+	for i, name := range names {
+		if i == 0 {
+			sb.WriteString(name)
+
+			continue
+		}
+
+		sb.WriteByte('[')
+		sb.WriteString(name)
+		sb.WriteByte(']')
+	}
+
+	return sb.String()
+}
+
 type ValueMarshaler interface {
 	MarshalValue() ([]string, error)
 }
 
+// ErrOmitKey can be returned by MarshalValue to omit the key entirely, unlike
+// an empty slice which is still written unless the field is omitempty.
+var ErrOmitKey = errors.New("omit key")
+
+// marshalContext is the state of a single marshal call. The elements of the
+// indexed slices and the struct maps are compiled once with the keys and the
+// field paths relative to the element, which are prefixed while marshaling.
+type marshalContext struct {
+	keyPrefix string
+	fieldPath string
+	// lookup is applied to the full keys of the elements that are compiled
+	// with the keys relative to their index, see indexedSliceMarshaler.
+	lookup func(s string) string
+	// sources holds the output of every source while marshaling a request,
+	// see sourceMarshaler.
+	sources map[string]map[string][]string
+}
+
+func (ctx marshalContext) key(key string) string {
+	key = ctx.keyPrefix + key
+
+	if ctx.lookup != nil {
+		key = ctx.lookup(key)
+	}
+
+	return key
+}
+
+// set replaces the values of the key while reusing its slice.
+func (ctx marshalContext) set(v map[string][]string, key string, vals ...string) {
+	key = ctx.key(key)
+	v[key] = append(v[key][:0], vals...)
+}
+
 type marshaler interface {
-	marshal(src reflect.Value, v map[string][]string) error
+	marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error
 }
 
 type pointerMarshaler struct {
@@ -66,17 +212,41 @@ type pointerMarshaler struct {
 	elem     marshaler
 }
 
-func (m *pointerMarshaler) marshal(src reflect.Value, v map[string][]string) error {
+func (m *pointerMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
 	if !src.IsNil() {
-		return m.elem.marshal(src, v)
+		return m.elem.marshal(ctx, src.Elem(), v)
 	}
 
 	if m.required {
-		if m.key == "" {
+		if ctx.key(m.key) == "" {
 			return errMissingValue
 		}
 
-		return fmt.Errorf("key %s: %w", m.key, errMissingValue)
+		return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+	}
+
+	return nil
+}
+
+// escapeMarshaler percent-encodes every value written by its element, so
+// they can safely carry delimiters or embedded URLs.
+type escapeMarshaler struct {
+	elem marshaler
+}
+
+func (m *escapeMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	out := make(map[string][]string)
+
+	if err := m.elem.marshal(ctx, src, out); err != nil {
+		return err
+	}
+
+	for key, vals := range out {
+		for i, val := range vals {
+			vals[i] = valueEscaper.Escape(val)
+		}
+
+		v[key] = vals
 	}
 
 	return nil
@@ -84,6 +254,7 @@ func (m *pointerMarshaler) marshal(src reflect.Value, v map[string][]string) err
 
 type fieldMarshaler struct {
 	index     int
+	path      string
 	marshaler marshaler
 }
 
@@ -91,9 +262,9 @@ type structMarshaler struct {
 	fields []fieldMarshaler
 }
 
-func (m *structMarshaler) marshal(src reflect.Value, v map[string][]string) error {
+func (m *structMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
 	for _, field := range m.fields {
-		if err := field.marshaler.marshal(src.Field(field.index), v); err != nil {
+		if err := field.marshaler.marshal(ctx, src.Field(field.index), v); err != nil {
 			return err
 		}
 	}
@@ -101,6 +272,30 @@ func (m *structMarshaler) marshal(src reflect.Value, v map[string][]string) erro
 	return nil
 }
 
+// traceMarshaler calls the TraceFunc after its field is marshaled.
+type traceMarshaler struct {
+	key    string
+	path   string
+	secret bool
+	trace  func(key, fieldPath string, values []string)
+	elem   marshaler
+}
+
+func (m *traceMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if err := m.elem.marshal(ctx, src, v); err != nil {
+		return err
+	}
+
+	vals := v[ctx.key(m.key)]
+	if m.secret {
+		vals = redactValues(vals)
+	}
+
+	m.trace(ctx.key(m.key), joinFieldPath(ctx.fieldPath, m.path), vals)
+
+	return nil
+}
+
 type keyMarshaler struct {
 	key       string
 	required  bool
@@ -119,12 +314,12 @@ type stringMarshaler struct {
 	keyMarshaler
 }
 
-func (m *stringMarshaler) marshal(src reflect.Value, v map[string][]string) error {
+func (m *stringMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
 	val := src.String()
 
 	if val == "" {
 		if m.required {
-			return fmt.Errorf("key %s: %w", m.key, errMissingValue)
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
 		}
 
 		if m.omitEmpty {
@@ -132,7 +327,7 @@ func (m *stringMarshaler) marshal(src reflect.Value, v map[string][]string) erro
 		}
 	}
 
-	v[m.key] = append(v[m.key][:0], val)
+	ctx.set(v, m.key, val)
 
 	return nil
 }
@@ -141,12 +336,12 @@ type intMarshaler struct {
 	keyMarshaler
 }
 
-func (m *intMarshaler) marshal(src reflect.Value, v map[string][]string) error {
+func (m *intMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
 	val := src.Int()
 
 	if val == 0 {
 		if m.required {
-			return fmt.Errorf("key %s: %w", m.key, errMissingValue)
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
 		}
 
 		if m.omitEmpty {
@@ -154,7 +349,7 @@ func (m *intMarshaler) marshal(src reflect.Value, v map[string][]string) error {
 		}
 	}
 
-	v[m.key] = append(v[m.key][:0], strconv.FormatInt(val, 10))
+	ctx.set(v, m.key, strconv.FormatInt(val, 10))
 
 	return nil
 }
@@ -164,23 +359,31 @@ type methodMarshaler struct {
 	ptrReceiver bool
 }
 
-func (m *methodMarshaler) marshal(src reflect.Value, v map[string][]string) error {
+func (m *methodMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
 	if m.ptrReceiver {
 		if !src.CanAddr() {
-			return errors.New("unable to call MarshalValue to an unadressable value")
+			return &UnsupportedValueError{Value: src, msg: "unable to call MarshalValue to an unadressable value"}
 		}
 
 		src = src.Addr()
 	}
 
 	val, err := src.Interface().(ValueMarshaler).MarshalValue()
+	if errors.Is(err, ErrOmitKey) {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
 
 	if len(val) == 0 {
 		if m.required {
-			return fmt.Errorf("key %s: %w", m.key, errMissingValue)
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
 		}
 
 		if m.omitEmpty {
@@ -188,22 +391,21 @@ func (m *methodMarshaler) marshal(src reflect.Value, v map[string][]string) erro
 		}
 	}
 
-	v[m.key] = append(v[m.key][:0], val...)
+	ctx.set(v, m.key, val...)
 
 	return nil
 }
 
-type sliceMarshaler struct {
+type uintMarshaler struct {
 	keyMarshaler
-	intElem bool
 }
 
-func (m *sliceMarshaler) marshal(src reflect.Value, v map[string][]string) error {
-	n := src.Len()
+func (m *uintMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	val := src.Uint()
 
-	if n == 0 {
+	if val == 0 {
 		if m.required {
-			return fmt.Errorf("key %s: %w", m.key, errMissingValue)
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
 		}
 
 		if m.omitEmpty {
@@ -211,200 +413,1183 @@ func (m *sliceMarshaler) marshal(src reflect.Value, v map[string][]string) error
 		}
 	}
 
-	out := v[m.key][:0]
+	ctx.set(v, m.key, strconv.FormatUint(val, 10))
 
-	for i := 0; i < n; i++ {
-		var val string
+	return nil
+}
+
+type boolMarshaler struct {
+	keyMarshaler
+	format func(src reflect.Value) string
+}
 
-		if m.intElem {
-			val = strconv.FormatInt(src.Index(i).Int(), 10)
-		} else {
-			val = src.Index(i).String()
+func (m *boolMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if !src.Bool() {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
 		}
 
-		out = append(out, val)
+		if m.omitEmpty {
+			return nil
+		}
 	}
 
-	v[m.key] = out
+	ctx.set(v, m.key, m.format(src))
 
 	return nil
 }
 
-type MarshalConfig struct {
-	Delimiter     string
-	KeyLookupFunc func(s string) string
+type floatMarshaler struct {
+	keyMarshaler
 }
 
-func (c MarshalConfig) delimiter() string {
-	if c.Delimiter != "" {
-		return c.Delimiter
+func (m *floatMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	val := src.Float()
+
+	if val == 0 {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		if m.omitEmpty {
+			return nil
+		}
 	}
 
-	return "."
+	ctx.set(v, m.key, formatFloat(src))
+
+	return nil
 }
 
-type marshalConfig struct {
-	MarshalConfig
-	Name         []string
-	NamelessAnon bool
-	Required     bool
-	OmitEmpty    bool
+type complexMarshaler struct {
+	keyMarshaler
 }
 
-func (c *marshalConfig) applyOption(opt string) error {
-	switch opt {
-	case "required":
-		c.Required = true
-	case "omitempty":
-		c.OmitEmpty = true
-	case "":
-		// Allow empty option.
-	default:
-		return fmt.Errorf("unknown option %s", opt)
+func (m *complexMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if src.Complex() == 0 {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		if m.omitEmpty {
+			return nil
+		}
 	}
 
+	ctx.set(v, m.key, formatComplex(src))
+
 	return nil
 }
 
-func (c *marshalConfig) name() string {
-	key := strings.Join(c.Name, c.delimiter())
+type charMarshaler struct {
+	keyMarshaler
+	format func(src reflect.Value) string
+}
 
-	if c.KeyLookupFunc != nil {
-		key = c.KeyLookupFunc(key)
+func (m *charMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if src.IsZero() {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		if m.omitEmpty {
+			return nil
+		}
 	}
 
-	return key
-}
+	ctx.set(v, m.key, m.format(src))
 
-func newSliceMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
-	elem := typ.Elem()
+	return nil
+}
 
-	switch elem.Kind() {
-	case reflect.String:
-		return &sliceMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+type sliceMarshaler struct {
+	keyMarshaler
+	format   func(src reflect.Value) (string, error)
+	sep      string
+	indexKey func(i int) string
+	empty    EmptySlice
+	unique   bool
+	sorted   bool
+	// compare orders the numeric elements for the sorted option, which are
+	// otherwise ordered by their formatted values.
+	compare func(a, b reflect.Value) int
+}
 
-	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
-		return &sliceMarshaler{
-			keyMarshaler: newKeyMarshaler(cfg),
-			intElem:      true,
-		}, nil
-	}
+func (m *sliceMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	n := src.Len()
 
-	return nil, fmt.Errorf("cannot marshal from slice of %s", elem.Kind().String())
-}
+	if n == 0 {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
 
-func newValueMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
-	var valReceiver bool
+		if m.omitEmpty {
+			return nil
+		}
 
-	switch {
-	case typ.Implements(valueMarshalerReflectType):
-		valReceiver = true
+		switch m.empty {
+		case EmptySliceOmit:
+			return nil
+		case EmptySliceValue:
+			ctx.set(v, m.key, "")
 
-		fallthrough
+			return nil
+		}
+	}
 
-	case reflect.PointerTo(typ).Implements(valueMarshalerReflectType):
-		return &methodMarshaler{
-			keyMarshaler: newKeyMarshaler(cfg),
-			ptrReceiver:  !valReceiver,
-		}, nil
+	var out []string
+	if m.indexKey == nil {
+		out = v[ctx.key(m.key)][:0]
 	}
 
-	switch typ.Kind() {
-	case reflect.Pointer:
-		mv, err := newValueMarshaler(cfg, typ.Elem())
+	for i := 0; i < n; i++ {
+		val, err := m.format(src.Index(i))
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("key %s: slice index #%d: %w", ctx.key(m.key), i, err)
 		}
 
-		return &pointerMarshaler{
-			key:      cfg.name(),
-			required: cfg.Required,
-			elem:     mv,
-		}, nil
-
-	case reflect.Struct:
-		if cfg.Required || cfg.OmitEmpty {
-			return nil, errors.New("cannot set any option for struct")
-		}
+		out = append(out, val)
+	}
 
-		if cfg.NamelessAnon {
-			cfg.Name = cfg.Name[:len(cfg.Name)-1]
-		}
+	if m.sorted {
+		m.sortValues(src, out)
+	}
 
-		return newStructMarshaler(cfg, typ)
+	if m.unique {
+		out = uniqueValues(out)
+	}
 
-	case reflect.Slice:
-		return newSliceMarshaler(cfg, typ)
+	if m.indexKey != nil {
+		for i, val := range out {
+			ctx.set(v, m.indexKey(i), val)
+		}
 
-	case reflect.String:
-		return &stringMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+		return nil
+	}
 
-	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
-		return &intMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+	if m.sep != "" {
+		out = append(out[:0], strings.Join(out, m.sep))
 	}
 
-	return nil, fmt.Errorf("cannot marshal from %s", typ.Kind().String())
+	v[ctx.key(m.key)] = out
+
+	return nil
 }
 
-func newFieldMarshaler(cfg marshalConfig, structFld reflect.StructField) (fieldMarshaler, error) {
-	tag := strings.Split(structFld.Tag.Get("map"), ",")
-	name := tag[0]
+func (m *sliceMarshaler) sortValues(src reflect.Value, out []string) {
+	if m.compare == nil {
+		slices.Sort(out)
 
-	// Follow the encoding/json standard where a field can still be named "-"
-	// by using a comma suffix.
-	if name == "-" && len(tag) == 1 {
-		return fieldMarshaler{}, errSkipField
+		return
 	}
 
-	var namelessAnon bool
-	if name == "" {
-		if structFld.Anonymous {
-			namelessAnon = true
-		}
-
-		name = structFld.Name
+	order := make([]int, len(out))
+	for i := range order {
+		order[i] = i
 	}
 
-	fieldCfg := marshalConfig{
-		MarshalConfig: cfg.MarshalConfig,
-		Name:          append(cfg.Name, name),
-		NamelessAnon:  namelessAnon,
-	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return m.compare(src.Index(a), src.Index(b))
+	})
 
-	for i := 1; i < len(tag); i++ {
-		if err := fieldCfg.applyOption(tag[i]); err != nil {
-			return fieldMarshaler{}, err
-		}
+	sorted := make([]string, len(out))
+	for i, j := range order {
+		sorted[i] = out[j]
 	}
 
-	if fieldCfg.Required && fieldCfg.OmitEmpty {
-		return fieldMarshaler{}, errors.New("a field cannot be set as both required and omitempty")
-	}
+	copy(out, sorted)
+}
 
-	vm, err := newValueMarshaler(fieldCfg, structFld.Type)
-	if err != nil {
-		return fieldMarshaler{}, fmt.Errorf("struct field %s: %w", structFld.Name, err)
+// getCompareFunc returns the numeric order of the type, or nil if it is not a
+// number.
+func getCompareFunc(typ reflect.Type) func(a, b reflect.Value) int {
+	switch {
+	case getIntSize(typ.Kind()) > 0:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Int(), b.Int()) }
+	case getUintSize(typ.Kind()) > 0:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Uint(), b.Uint()) }
+	case getFloatSize(typ.Kind()) > 0:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Float(), b.Float()) }
 	}
 
-	return fieldMarshaler{
-		index:     structFld.Index[len(structFld.Index)-1],
-		marshaler: vm,
-	}, nil
+	return nil
 }
 
-func newStructMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
-	var fields []fieldMarshaler
+// indexedSliceMarshaler marshals a slice of structs where the keys of each
+// element are prefixed with its index, e.g. "items.0.name" or "items[0].name".
+// The element marshaler is compiled once with the keys relative to the element.
+type indexedSliceMarshaler struct {
+	key      string
+	required bool
+	prefix   string
+	suffix   string
+	path     string
+	lookup   func(s string) string
+	elem     marshaler
+}
+
+func (m *indexedSliceMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	n := src.Len()
+
+	if n == 0 && m.required {
+		return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+	}
 
-	n := typ.NumField()
 	for i := 0; i < n; i++ {
-		field, err := newFieldMarshaler(cfg, typ.Field(i))
+		ctx := ctx
+		ctx.keyPrefix += m.prefix + strconv.Itoa(i) + m.suffix
+		ctx.fieldPath = joinFieldPath(ctx.fieldPath, m.path+"["+strconv.Itoa(i)+"]")
 
-		if errors.Is(err, errSkipField) {
-			continue
+		if m.lookup != nil {
+			ctx.lookup = m.lookup
 		}
 
-		if err != nil {
-			return nil, err
+		if err := m.elem.marshal(ctx, src.Index(i), v); err != nil {
+			return fmt.Errorf("slice index #%d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func newIndexedSliceMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	prefix := cfg.joinKey(cfg.Name)
+
+	// The element keys and field paths are relative to its index, and the
+	// options only apply to the slice itself. The KeyLookupFunc is applied to
+	// the full keys instead, as in the nested structs.
+	elemCfg := cfg
+	elemCfg.Name = nil
+	elemCfg.KeyLookupFunc = nil
+	elemCfg.path = ""
+	elemCfg.Required = false
+	elemCfg.OmitEmpty = false
+
+	elem, err := newValueMarshaler(elemCfg, typ.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	m := &indexedSliceMarshaler{
+		key:      cfg.name(),
+		required: cfg.Required,
+		prefix:   prefix + cfg.delimiter(),
+		suffix:   cfg.delimiter(),
+		path:     cfg.path,
+		lookup:   cfg.KeyLookupFunc,
+		elem:     elem,
+	}
+
+	if cfg.BracketIndex {
+		m.prefix = prefix + "["
+		m.suffix = "]" + cfg.delimiter()
+	}
+
+	return m, nil
+}
+
+type timeMarshaler struct {
+	keyMarshaler
+	format func(src reflect.Value) string
+}
+
+func (m *timeMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	val := src.Interface().(time.Time)
+
+	if val.IsZero() {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		if m.omitEmpty {
+			return nil
+		}
+	}
+
+	ctx.set(v, m.key, m.format(src))
+
+	return nil
+}
+
+type MarshalConfig struct {
+	Delimiter     string
+	KeyLookupFunc func(s string) string
+
+	// TagName is the struct tag to read the field options from. Defaults to
+	// "map".
+	TagName string
+
+	// JoinKeyFunc builds the key of a nested field from the name of each
+	// level, which overrides the Delimiter.
+	JoinKeyFunc func(names []string) string
+
+	// MaxDepth limits the struct nesting depth of the source type, where the
+	// top-level struct counts as one. Zero means no limit.
+	MaxDepth int
+
+	// MaxFields limits the total number of fields compiled for the source type,
+	// including the fields of nested structs. Zero means no limit.
+	MaxFields int
+
+	// Interfaces registers the concrete types for interface-typed fields. The
+	// dynamic type is always used for marshaling, this is only needed to write
+	// the Interface.Key value.
+	Interfaces []Interface
+
+	// Codecs registers the conversion for types that are not supported
+	// natively. When there are multiple codecs for the same type, the last one
+	// is used.
+	Codecs []Codec
+
+	// Options registers the custom tag options.
+	Options []Option
+
+	// Style and Explode select an OpenAPI 3 parameter serialization style
+	// for all fields, which sets the SliceStyle, SliceSeparator and, for the
+	// deepObject style, the JoinKeyFunc.
+	Style   ParamStyle
+	Explode Explode
+
+	// NameFunc derives the key of the fields without a name in their tag from
+	// their Go name, e.g. SnakeCase or LowerCamel. Defaults to the Go name
+	// as-is.
+	NameFunc func(fieldName string) string
+
+	// KeyFuncs registers the key transforms that can be selected per field
+	// using the "keyfunc" option, in addition to the built-in "canonical",
+	// "lower", "upper" and "none". The selected one replaces KeyLookupFunc for
+	// the field.
+	KeyFuncs map[string]func(s string) string
+
+	// SkipUnsupported skips the fields whose type cannot be marshaled (e.g.
+	// funcs or channels) instead of failing the whole struct.
+	SkipUnsupported bool
+
+	// BracketIndex uses "key[0]" instead of "key.0" for the indexed slice
+	// elements.
+	BracketIndex bool
+
+	// OmitZero applies the omitempty option to all fields that are not
+	// required, unless they have the keepzero option.
+	OmitZero bool
+
+	// SliceStyle is the default layout of slice fields, which can be
+	// overridden using the "repeated", "indexed" or "joined" options.
+	SliceStyle SliceStyle
+
+	// SliceSeparator separates the joined slice values. Defaults to ",".
+	SliceSeparator string
+
+	// EmptySlice selects how a slice without any element is marshaled, when
+	// it is neither required nor omitempty.
+	EmptySlice EmptySlice
+
+	// MultiValue selects how MarshalSingle handles a key with multiple values.
+	MultiValue MultiValue
+
+	// TraceFunc is called with the key, the Go field path and the values of
+	// every marshaled field, which helps to debug a confusing mapping.
+	TraceFunc func(key, fieldPath string, values []string)
+
+	// SanitizeFunc is called with the key and the values of every marshaled
+	// field before they are written. It returns the key and values to write
+	// in their place, or an error to fail the field, e.g. SanitizeHeader.
+	SanitizeFunc func(key string, values []string) (string, []string, error)
+}
+
+func (c MarshalConfig) delimiter() string {
+	if c.Delimiter != "" {
+		return c.Delimiter
+	}
+
+	return "."
+}
+
+func (c MarshalConfig) sliceSeparator() string {
+	if c.SliceSeparator != "" {
+		return c.SliceSeparator
+	}
+
+	return ","
+}
+
+func (c MarshalConfig) fieldName(structFld reflect.StructField) string {
+	if c.NameFunc != nil {
+		return c.NameFunc(structFld.Name)
+	}
+
+	return structFld.Name
+}
+
+func (c MarshalConfig) tagName() string {
+	if c.TagName != "" {
+		return c.TagName
+	}
+
+	return "map"
+}
+
+func (c MarshalConfig) joinKey(names []string) string {
+	if c.JoinKeyFunc != nil {
+		return c.JoinKeyFunc(names)
+	}
+
+	return strings.Join(names, c.delimiter())
+}
+
+type marshalConfig struct {
+	MarshalConfig
+	Name         []string
+	NamelessAnon bool
+	Required     bool
+	OmitEmpty    bool
+	KeepZero     bool
+	Char         bool
+	Separator    string
+	Brackets     bool
+	Numbered     bool
+	TimeFormat   string
+	IntBool      bool
+	Source       string
+	PrefixMap    bool
+	Escape       bool
+	List         bool
+	Structured   bool
+	MimeValue    bool
+	Token68      bool
+	mimeStruct   bool
+	authStruct   bool
+	params       *paramsFormat
+	Secret       bool
+	Unique       bool
+	Sorted       bool
+	Count        bool
+	only         string
+	request      bool
+	styled       bool
+	path         string
+	depth        int
+	fields       *int
+}
+
+func newMarshalConfig(cfg MarshalConfig) marshalConfig {
+	return marshalConfig{
+		MarshalConfig: cfg,
+		fields:        new(int),
+	}
+}
+
+func (c *marshalConfig) countField() error {
+	*c.fields++
+
+	if c.MaxFields > 0 && *c.fields > c.MaxFields {
+		return &LimitError{Limit: "MaxFields", Max: c.MaxFields}
+	}
+
+	return nil
+}
+
+func (c *marshalConfig) applyOption(opt TagOption, typ reflect.Type) error {
+	// The default, alias, conditional required, group, validate and items
+	// options are only valid for unmarshaler so they will be ignored.
+	switch opt.Name {
+	case "default", "alias", "deprecated", "required_with", "required_without", "required_if",
+		"xor", "anyof", "validate", "minitems", "maxitems":
+		return nil
+	}
+
+	switch opt.Name {
+	case "style":
+		c.Style, c.styled = ParamStyle(opt.Value), true
+
+		return nil
+
+	case "explode":
+		explode, err := parseExplode(opt.Value)
+		if err != nil {
+			return err
+		}
+
+		c.Explode, c.styled = explode, true
+
+		return nil
+	}
+
+	if opt.Name == "keyfunc" {
+		fn, err := findKeyFunc(c.KeyFuncs, opt.Value)
+		if err != nil {
+			return err
+		}
+
+		c.KeyLookupFunc = fn
+
+		return nil
+	}
+
+	if _, ok := findOption(c.Options, opt.Name); ok {
+		codecs, err := applyCustomOption(c.Options, c.Codecs, opt, typ)
+		if err != nil {
+			return err
+		}
+
+		c.Codecs = codecs
+
+		return nil
+	}
+
+	switch opt.Name {
+	case "required":
+		c.Required = true
+	case "omitempty":
+		c.OmitEmpty = true
+	case "keepzero":
+		c.KeepZero = true
+	case "char":
+		c.Char = true
+	case "comma":
+		c.Separator = ","
+	case "space":
+		c.Separator = " "
+	case "semicolon":
+		c.Separator = ";"
+	case "brackets":
+		c.Brackets = true
+	case "numbered":
+		c.Numbered = true
+	case "repeated":
+		c.SliceStyle = SliceRepeated
+	case "indexed":
+		c.SliceStyle = SliceIndexed
+	case "joined":
+		c.SliceStyle = SliceJoined
+	case "unix", "unixmilli", "unixnano":
+		c.TimeFormat = opt.Name
+	case "int":
+		c.IntBool = true
+	case sourceQuery, sourceHeader, sourcePath, sourceForm, sourceCookie, sourceFragment:
+		c.Source = opt.Name
+	case "prefix":
+		c.PrefixMap = true
+	case "escape":
+		c.Escape = true
+	case "secret":
+		c.Secret = true
+	case "unique":
+		c.Unique = true
+	case "sorted":
+		c.Sorted = true
+	case "count":
+		c.Count = true
+	case "list":
+		c.List = true
+	case "mime", "auth", "link", "forwarded", "directives":
+		return c.setParams(paramsFormats[opt.Name])
+	case "structured":
+		c.Structured = true
+	case "value":
+		c.MimeValue = true
+	case "token68":
+		c.Token68 = true
+	case "":
+		// Allow empty option.
+	default:
+		return fmt.Errorf("unknown option %s", opt.Name)
+	}
+
+	if opt.Value != "" {
+		return fmt.Errorf("option %s does not take a value", opt.Name)
+	}
+
+	return nil
+}
+
+// skipSource reports whether a value field must be skipped because it is bound
+// to a different source than the one being marshaled.
+func (c *marshalConfig) skipSource() bool {
+	if c.request {
+		switch c.source() {
+		case sourceQuery, sourceHeader, sourceCookie:
+			return false
+		}
+
+		return true
+	}
+
+	if c.only == "" {
+		return false
+	}
+
+	return c.source() != c.only
+}
+
+// source returns the source of the field, where the fields without any source
+// option are bound to the query string.
+func (c *marshalConfig) source() string {
+	if c.Source == "" {
+		return sourceQuery
+	}
+
+	return c.Source
+}
+
+func (c *marshalConfig) name() string {
+	key := c.joinKey(c.Name)
+
+	if c.KeyLookupFunc != nil {
+		key = c.KeyLookupFunc(key)
+	}
+
+	return key
+}
+
+func (c *marshalConfig) childName(name string) string {
+	key := c.joinKey(append(c.Name[:len(c.Name):len(c.Name)], name))
+
+	if c.KeyLookupFunc != nil {
+		key = c.KeyLookupFunc(key)
+	}
+
+	return key
+}
+
+func formatString(src reflect.Value) string {
+	return src.String()
+}
+
+func formatInt(src reflect.Value) string {
+	return strconv.FormatInt(src.Int(), 10)
+}
+
+func formatUint(src reflect.Value) string {
+	return strconv.FormatUint(src.Uint(), 10)
+}
+
+func formatBool(src reflect.Value) string {
+	return strconv.FormatBool(src.Bool())
+}
+
+func formatIntBool(src reflect.Value) string {
+	if src.Bool() {
+		return "1"
+	}
+
+	return "0"
+}
+
+func getBoolFormatFunc(cfg marshalConfig) func(src reflect.Value) string {
+	if cfg.IntBool {
+		return formatIntBool
+	}
+
+	return formatBool
+}
+
+func formatFloat(src reflect.Value) string {
+	return strconv.FormatFloat(src.Float(), 'g', -1, src.Type().Bits())
+}
+
+// formatComplex formats the value in "a+bi" form without the surrounding
+// parentheses added by strconv.FormatComplex.
+func formatComplex(src reflect.Value) string {
+	val := strconv.FormatComplex(src.Complex(), 'g', -1, src.Type().Bits())
+
+	return val[1 : len(val)-1]
+}
+
+func formatRune(src reflect.Value) string {
+	return string(rune(src.Int()))
+}
+
+func formatByte(src reflect.Value) string {
+	return string([]byte{byte(src.Uint())})
+}
+
+func getCharFormatFunc(typ reflect.Type) func(src reflect.Value) string {
+	switch typ.Kind() {
+	case reflect.Int32:
+		return formatRune
+	case reflect.Uint8:
+		return formatByte
+	}
+
+	return nil
+}
+
+func formatTime(src reflect.Value) string {
+	return src.Interface().(time.Time).Format(time.RFC3339Nano)
+}
+
+func getTimeFormatFunc(cfg marshalConfig) func(src reflect.Value) string {
+	switch cfg.TimeFormat {
+	case "unix":
+		return func(src reflect.Value) string {
+			return strconv.FormatInt(src.Interface().(time.Time).Unix(), 10)
+		}
+	case "unixmilli":
+		return func(src reflect.Value) string {
+			return strconv.FormatInt(src.Interface().(time.Time).UnixMilli(), 10)
+		}
+	case "unixnano":
+		return func(src reflect.Value) string {
+			return strconv.FormatInt(src.Interface().(time.Time).UnixNano(), 10)
+		}
+	}
+
+	return formatTime
+}
+
+func withoutError(format func(src reflect.Value) string) func(src reflect.Value) (string, error) {
+	return func(src reflect.Value) (string, error) {
+		return format(src), nil
+	}
+}
+
+// getFormatFunc returns the function to format a slice element of the given
+// type into a single value.
+func getFormatFunc(cfg marshalConfig, typ reflect.Type) func(src reflect.Value) (string, error) {
+	if codec, ok := findCodec(cfg.Codecs, typ); ok {
+		return codec.format
+	}
+
+	if cfg.Char {
+		if format := getCharFormatFunc(typ); format != nil {
+			return withoutError(format)
+		}
+
+		return nil
+	}
+
+	if typ == timeReflectType {
+		return withoutError(getTimeFormatFunc(cfg))
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return withoutError(formatString)
+
+	case reflect.Bool:
+		return withoutError(getBoolFormatFunc(cfg))
+
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return withoutError(formatInt)
+
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return withoutError(formatUint)
+
+	case reflect.Float64, reflect.Float32:
+		return withoutError(formatFloat)
+
+	case reflect.Complex128, reflect.Complex64:
+		return withoutError(formatComplex)
+	}
+
+	return nil
+}
+
+func newSliceMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	elem := typ.Elem()
+
+	if format := getFormatFunc(cfg, elem); format != nil {
+		km := newKeyMarshaler(cfg)
+		if cfg.Brackets {
+			km.key += "[]"
+		}
+
+		sep := cfg.Separator
+		if sep == "" && cfg.SliceStyle == SliceJoined {
+			sep = cfg.sliceSeparator()
+		}
+
+		if cfg.List {
+			format, sep = quoteListFormat(format), ", "
+		}
+
+		var compare func(a, b reflect.Value) int
+		if cfg.Sorted && !cfg.Char {
+			if _, ok := findCodec(cfg.Codecs, elem); !ok {
+				compare = getCompareFunc(elem)
+			}
+		}
+
+		var indexKey func(i int) string
+
+		switch {
+		case cfg.Numbered:
+			indexKey = func(i int) string {
+				return km.key + strconv.Itoa(i)
+			}
+
+		case sep == "" && cfg.SliceStyle == SliceIndexed:
+			// Copy the name so it will not be overwritten by the sibling fields.
+			cfg.Name = slices.Clone(cfg.Name)
+
+			indexKey = func(i int) string {
+				return cfg.childName(strconv.Itoa(i))
+			}
+		}
+
+		return &sliceMarshaler{
+			keyMarshaler: km,
+			format:       format,
+			sep:          sep,
+			indexKey:     indexKey,
+			empty:        cfg.EmptySlice,
+			unique:       cfg.Unique,
+			sorted:       cfg.Sorted,
+			compare:      compare,
+		}, nil
+	}
+
+	if cfg.Char {
+		return nil, errInvalidChar
+	}
+
+	if indirectType(elem).Kind() == reflect.Struct {
+		return newIndexedSliceMarshaler(cfg, typ)
+	}
+
+	return nil, newUnsupportedTypeError(elem, "cannot marshal from slice of %s", elem.Kind().String())
+}
+
+func newValueMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	if codec, ok := findCodec(cfg.Codecs, typ); ok {
+		if codec.format == nil {
+			return nil, newUnsupportedTypeError(typ, "cannot marshal from %s", typ.String())
+		}
+
+		return &codecMarshaler{
+			keyMarshaler: newKeyMarshaler(cfg),
+			format:       codec.format,
+		}, nil
+	}
+
+	if vm, ok := newMapMethodMarshaler(cfg, typ); ok {
+		return vm, nil
+	}
+
+	var valReceiver bool
+
+	switch {
+	// A pointer to a type with the value receiver method is marshaled by the
+	// pointerMarshaler, so a nil pointer is not dereferenced to call it.
+	case typ.Kind() == reflect.Pointer && typ.Elem().Implements(valueMarshalerReflectType):
+
+	case typ.Implements(valueMarshalerReflectType):
+		valReceiver = true
+
+		fallthrough
+
+	case reflect.PointerTo(typ).Implements(valueMarshalerReflectType):
+		return &methodMarshaler{
+			keyMarshaler: newKeyMarshaler(cfg),
+			ptrReceiver:  !valReceiver,
+		}, nil
+	}
+
+	if cfg.Structured && typ.Kind() != reflect.Pointer {
+		return newStructuredMarshaler(cfg, typ)
+	}
+
+	if cfg.Char {
+		if kind := typ.Kind(); kind != reflect.Pointer && kind != reflect.Slice {
+			format := getCharFormatFunc(typ)
+			if format == nil {
+				return nil, errInvalidChar
+			}
+
+			return &charMarshaler{
+				keyMarshaler: newKeyMarshaler(cfg),
+				format:       format,
+			}, nil
+		}
+	}
+
+	if typ == timeReflectType {
+		return &timeMarshaler{
+			keyMarshaler: newKeyMarshaler(cfg),
+			format:       getTimeFormatFunc(cfg),
+		}, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		// The options are applied to the pointer itself, so omitempty only
+		// omits a nil pointer while a pointer to a zero value is still written.
+		elemCfg := cfg
+		elemCfg.Required = false
+		elemCfg.OmitEmpty = false
+
+		mv, err := newValueMarshaler(elemCfg, typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &pointerMarshaler{
+			key:      cfg.name(),
+			required: cfg.Required,
+			elem:     mv,
+		}, nil
+
+	case reflect.Struct:
+		if cfg.params != nil {
+			return newParamsMarshaler(cfg, typ)
+		}
+
+		// The omitempty option is allowed for compatibility with the other tag
+		// conventions, but it has no effect as the fields decide by themselves.
+		if cfg.Required {
+			return nil, errors.New("cannot set required option for struct")
+		}
+
+		if cfg.NamelessAnon {
+			cfg.Name = cfg.Name[:len(cfg.Name)-1]
+		}
+
+		return newStructMarshaler(cfg, typ)
+
+	case reflect.Slice:
+		if cfg.params != nil {
+			return newParamsMarshaler(cfg, typ)
+		}
+
+		return newSliceMarshaler(cfg, typ)
+
+	case reflect.Interface:
+		return newInterfaceMarshaler(cfg, typ)
+
+	case reflect.Map:
+		if cfg.PrefixMap {
+			return newPrefixMapMarshaler(cfg, typ)
+		}
+
+		if typ.Key().Kind() == reflect.String && indirectType(typ.Elem()).Kind() == reflect.Struct {
+			return newStructMapMarshaler(cfg, typ)
+		}
+
+	case reflect.String:
+		return &stringMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+
+	case reflect.Bool:
+		return &boolMarshaler{
+			keyMarshaler: newKeyMarshaler(cfg),
+			format:       getBoolFormatFunc(cfg),
+		}, nil
+
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return &intMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return &uintMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+
+	case reflect.Float64, reflect.Float32:
+		return &floatMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+
+	case reflect.Complex128, reflect.Complex64:
+		return &complexMarshaler{keyMarshaler: newKeyMarshaler(cfg)}, nil
+	}
+
+	return nil, newUnsupportedTypeError(typ, "cannot marshal from %s", typ.Kind().String())
+}
+
+func newFieldMarshaler(cfg marshalConfig, structFld reflect.StructField) (fieldMarshaler, error) {
+	name, opts := ParseTag(structFld.Tag.Get(cfg.tagName()))
+
+	// Follow the encoding/json standard where a field can still be named "-"
+	// by using a comma suffix.
+	if name == "-" && len(opts) == 0 {
+		return fieldMarshaler{}, errSkipField
+	}
+
+	var namelessAnon bool
+	if name == "" {
+		if structFld.Anonymous {
+			namelessAnon = true
+		}
+
+		name = cfg.fieldName(structFld)
+	}
+
+	fieldCfg := marshalConfig{
+		MarshalConfig: cfg.MarshalConfig,
+		Name:          append(cfg.Name, name),
+		NamelessAnon:  namelessAnon,
+		Source:        cfg.Source,
+		only:          cfg.only,
+		request:       cfg.request,
+		path:          joinFieldPath(cfg.path, structFld.Name),
+		depth:         cfg.depth,
+		fields:        cfg.fields,
+	}
+
+	for _, opt := range opts {
+		if err := fieldCfg.applyOption(opt, structFld.Type); err != nil {
+			return fieldMarshaler{}, err
+		}
+	}
+
+	if fieldCfg.styled {
+		if err := fieldCfg.applyStyle(); err != nil {
+			return fieldMarshaler{}, err
+		}
+	}
+
+	// The count field only mirrors the values of another field, so it is not
+	// written back.
+	if fieldCfg.Count {
+		return fieldMarshaler{}, errSkipField
+	}
+
+	if fieldCfg.PrefixMap && indirectType(structFld.Type).Kind() != reflect.Map {
+		return fieldMarshaler{}, errInvalidPrefix
+	}
+
+	if fieldCfg.List && !isSliceType(structFld.Type) {
+		return fieldMarshaler{}, errInvalidList
+	}
+
+	if fieldCfg.Unique && !isSliceType(structFld.Type) {
+		return fieldMarshaler{}, errInvalidUnique
+	}
+
+	if fieldCfg.Sorted && !isSliceType(structFld.Type) {
+		return fieldMarshaler{}, errInvalidSorted
+	}
+
+	if fieldCfg.params != nil && !fieldCfg.params.validType(structFld.Type) {
+		return fieldMarshaler{}, fieldCfg.params.invalid
+	}
+
+	if fieldCfg.MimeValue {
+		if !cfg.mimeStruct {
+			return fieldMarshaler{}, errInvalidValue
+		}
+
+		fieldCfg.Name = nil
+	}
+
+	if fieldCfg.Token68 {
+		if !cfg.authStruct {
+			return fieldMarshaler{}, errInvalidToken68
+		}
+
+		fieldCfg.Name = []string{authToken68Key}
+	}
+
+	if fieldCfg.Required && fieldCfg.OmitEmpty {
+		return fieldMarshaler{}, errors.New("a field cannot be set as both required and omitempty")
+	}
+
+	if fieldCfg.KeepZero && fieldCfg.OmitEmpty {
+		return fieldMarshaler{}, errors.New("a field cannot be set as both keepzero and omitempty")
+	}
+
+	if cfg.OmitZero && !fieldCfg.Required && !fieldCfg.KeepZero {
+		fieldCfg.OmitEmpty = true
+	}
+
+	isZero, hasZeroCheck := findZeroCheck(structFld.Type)
+
+	elemCfg := fieldCfg
+	if hasZeroCheck {
+		elemCfg.Required = false
+		elemCfg.OmitEmpty = false
+	}
+
+	vm, err := newValueMarshaler(elemCfg, structFld.Type)
+	if err != nil {
+		if skipUnsupported(cfg.SkipUnsupported, err) {
+			return fieldMarshaler{}, errSkipField
+		}
+
+		setUnsupportedField(err, structFld.Name)
+
+		return fieldMarshaler{}, fmt.Errorf("struct field %s: %w", structFld.Name, err)
+	}
+
+	if hasZeroCheck {
+		vm = &zeroCheckMarshaler{
+			keyMarshaler: newKeyMarshaler(fieldCfg),
+			isZero:       isZero,
+			elem:         vm,
+		}
+	}
+
+	if !isNestedMarshaler(vm) && fieldCfg.skipSource() {
+		return fieldMarshaler{}, errSkipField
+	}
+
+	if fieldCfg.Escape {
+		if isNestedMarshaler(vm) {
+			return fieldMarshaler{}, errors.New("cannot set escape option for struct")
+		}
+
+		vm = &escapeMarshaler{elem: vm}
+	}
+
+	if fieldCfg.SanitizeFunc != nil && !isNestedMarshaler(vm) {
+		vm = &sanitizeMarshaler{
+			path:     fieldCfg.path,
+			sanitize: fieldCfg.SanitizeFunc,
+			elem:     vm,
+		}
+	}
+
+	if fieldCfg.TraceFunc != nil && !isNestedMarshaler(vm) {
+		vm = &traceMarshaler{
+			key:    fieldCfg.name(),
+			path:   fieldCfg.path,
+			secret: fieldCfg.Secret,
+			trace:  fieldCfg.TraceFunc,
+			elem:   vm,
+		}
+	}
+
+	if fieldCfg.request && fieldCfg.source() != cfg.source() {
+		vm = &sourceMarshaler{source: fieldCfg.source(), elem: vm}
+	}
+
+	return fieldMarshaler{
+		index:     structFld.Index[len(structFld.Index)-1],
+		path:      fieldCfg.path,
+		marshaler: vm,
+	}, nil
+}
+
+func isNestedMarshaler(vm marshaler) bool {
+	switch vm := vm.(type) {
+	case *structMarshaler, *interfaceMarshaler, *indexedSliceMarshaler, *structMapMarshaler, *mapMethodMarshaler:
+		return true
+	case *pointerMarshaler:
+		return isNestedMarshaler(vm.elem)
+	case *zeroCheckMarshaler:
+		return isNestedMarshaler(vm.elem)
+	}
+
+	return false
+}
+
+func newStructMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	var fields []fieldMarshaler
+
+	cfg.depth++
+	if cfg.MaxDepth > 0 && cfg.depth > cfg.MaxDepth {
+		return nil, &LimitError{Limit: "MaxDepth", Max: cfg.MaxDepth}
+	}
+
+	n := typ.NumField()
+	for i := 0; i < n; i++ {
+		field, err := newFieldMarshaler(cfg, typ.Field(i))
+
+		if errors.Is(err, errSkipField) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cfg.countField(); err != nil {
+			return nil, err
 		}
 
 		fields = append(fields, field)
@@ -416,6 +1601,14 @@ func newStructMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error)
 }
 
 func newMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	if err := cfg.applyStyle(); err != nil {
+		return nil, err
+	}
+
+	if vm, ok := newMapMethodMarshaler(cfg, typ); ok {
+		return vm, nil
+	}
+
 	switch typ.Kind() {
 	case reflect.Struct:
 		return newStructMarshaler(cfg, typ)
@@ -432,12 +1625,26 @@ func newMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
 		}, nil
 	}
 
-	return nil, fmt.Errorf("cannot marshal from %s", typ.Kind().String())
+	return nil, newUnsupportedTypeError(typ, "cannot marshal from %s", typ.Kind().String())
 }
 
 type Marshaler struct {
-	cache  cache[reflect.Type, marshaler]
-	config MarshalConfig
+	cache   cache[reflect.Type, marshaler]
+	sources cache[sourceKey, marshaler]
+	config  MarshalConfig
+}
+
+// ClearCache releases all the compiled types, e.g. the types from a plugin
+// that is no longer used. They are compiled again on the next use.
+func (m *Marshaler) ClearCache() {
+	m.cache.Clear()
+	m.sources.Clear()
+}
+
+func (m *Marshaler) compile(typ reflect.Type) (marshaler, error) {
+	return m.cache.Get(typ, func(key reflect.Type) (marshaler, error) {
+		return newMarshaler(newMarshalConfig(m.config), key)
+	})
 }
 
 func (m *Marshaler) Marshal(src any, v map[string][]string) error {
@@ -447,14 +1654,160 @@ func (m *Marshaler) Marshal(src any, v map[string][]string) error {
 
 	val := reflect.ValueOf(src)
 
-	vm, err := m.cache.Get(val.Type(), func(key reflect.Type) (marshaler, error) {
-		return newMarshaler(marshalConfig{MarshalConfig: m.config}, key)
-	})
+	vm, err := m.compile(val.Type())
 	if err != nil {
 		return err
 	}
 
-	return vm.marshal(val, v)
+	return vm.marshal(marshalContext{}, val, v)
+}
+
+// KeyValues is a single key and its values in the output of MarshalSorted.
+type KeyValues struct {
+	Key    string
+	Values []string
+}
+
+// MarshalSorted marshals src into a list sorted lexicographically by key. The
+// values of each key keep the order in which they are marshaled, which makes
+// the output stable for building canonical requests (e.g. for signing).
+func (m *Marshaler) MarshalSorted(src any) ([]KeyValues, error) {
+	v := make(map[string][]string)
+
+	if err := m.Marshal(src, v); err != nil {
+		return nil, err
+	}
+
+	out := make([]KeyValues, 0, len(v))
+	for key, vals := range v {
+		out = append(out, KeyValues{Key: key, Values: vals})
+	}
+
+	slices.SortFunc(out, func(a, b KeyValues) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return out, nil
+}
+
+// MarshalIter iterates over the marshaled keys of a value, see Marshaler.Iter.
+type MarshalIter struct {
+	m   *Marshaler
+	src any
+	err error
+}
+
+// Iter returns an iterator over the marshaled keys of src and their values.
+// The error that stops the iteration is reported by MarshalIter.Err, in the
+// same way as bufio.Scanner:
+//
+//	it := m.Iter(src)
+//	for key, vals := range it.All() {
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+func (m *Marshaler) Iter(src any) *MarshalIter {
+	return &MarshalIter{m: m, src: src}
+}
+
+// All walks the fields in their declaration order and yields the keys of
+// each field sorted lexicographically, without marshaling the value into a
+// map first. The fields are marshaled one at a time into a scratch map that
+// is reused, so only the keys of the current field are held at once. The
+// iteration stops at the first error.
+func (it *MarshalIter) All() iter.Seq2[string, []string] {
+	return func(yield func(string, []string) bool) {
+		it.err = nil
+
+		val := reflect.ValueOf(it.src)
+
+		vm, err := it.m.compile(val.Type())
+		if err != nil {
+			it.err = err
+
+			return
+		}
+
+		var (
+			v    = make(map[string][]string)
+			keys []string
+		)
+
+		walkMarshaler(vm, val, "", v, func(_ string, err error) bool {
+			if err != nil {
+				it.err = err
+
+				return false
+			}
+
+			keys = keys[:0]
+			for key := range v {
+				keys = append(keys, key)
+			}
+
+			slices.Sort(keys)
+
+			for _, key := range keys {
+				if !yield(key, v[key]) {
+					return false
+				}
+			}
+
+			return true
+		})
+	}
+}
+
+// Err returns the error that stopped the last iteration of All, if any.
+func (it *MarshalIter) Err() error {
+	return it.err
+}
+
+// All returns an iterator over the marshaled keys of src and their values, as
+// in MarshalIter.All. It panics when src cannot be marshaled, so it is
+// intended for the values that are known to be valid. Use Iter to handle the
+// error instead.
+func (m *Marshaler) All(src any) iter.Seq2[string, []string] {
+	return func(yield func(string, []string) bool) {
+		it := m.Iter(src)
+
+		for key, vals := range it.All() {
+			if !yield(key, vals) {
+				return
+			}
+		}
+
+		if it.err != nil {
+			panic(it.err)
+		}
+	}
+}
+
+// walkMarshaler marshals the fields in the compiled tree one at a time into v,
+// which is cleared before each of them, and calls fn with the Go path of the
+// field and its error. It reports false once fn does.
+func walkMarshaler(vm marshaler, src reflect.Value, path string, v map[string][]string, fn func(path string, err error) bool) bool {
+	switch vm := vm.(type) {
+	case *structMarshaler:
+		for _, field := range vm.fields {
+			if !walkMarshaler(field.marshaler, src.Field(field.index), field.path, v, fn) {
+				return false
+			}
+		}
+
+		return true
+
+	case *pointerMarshaler:
+		if !src.IsNil() {
+			return walkMarshaler(vm.elem, src.Elem(), path, v, fn)
+		}
+	}
+
+	clear(v)
+
+	return fn(path, vm.marshal(marshalContext{}, src, v))
 }
 
 func NewMarshaler(cfg MarshalConfig) *Marshaler {
@@ -467,6 +1820,119 @@ func Marshal(src any, v map[string][]string) error {
 	return DefaultMarshaler.Marshal(src, v)
 }
 
+// MarshalSingle marshals src into a single-valued map, for sinks that only
+// accept a single value per key. A key without any value is written as an
+// empty string, while multiple values are handled according to MultiValue.
+func (m *Marshaler) MarshalSingle(src any) (map[string]string, error) {
+	v := make(map[string][]string)
+
+	if err := m.Marshal(src, v); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, len(v))
+
+	for key, vals := range v {
+		val, err := m.singleValue(key, vals)
+		if err != nil {
+			return nil, err
+		}
+
+		out[key] = val
+	}
+
+	return out, nil
+}
+
+// singleValue returns the values of the key as a single value according to
+// MultiValue.
+func (m *Marshaler) singleValue(key string, vals []string) (string, error) {
+	switch {
+	case len(vals) == 0:
+		return "", nil
+	case len(vals) == 1 || m.config.MultiValue == MultiValueFirst:
+		return vals[0], nil
+	case m.config.MultiValue == MultiValueJoin:
+		return strings.Join(vals, m.config.sliceSeparator()), nil
+	}
+
+	return "", fmt.Errorf("key %s: cannot marshal %d values into a single value", key, len(vals))
+}
+
+func MarshalSingle(src any) (map[string]string, error) {
+	return DefaultMarshaler.MarshalSingle(src)
+}
+
+// MarshalDiff marshals only the keys of current whose values differ from
+// base, which must be of the same type. The keys that are only present in base
+// are written with empty values, so they can be told apart from the unchanged
+// keys.
+func (m *Marshaler) MarshalDiff(base, current any, v map[string][]string) error {
+	if v == nil {
+		return errors.New("cannot marshal into a nil map")
+	}
+
+	if reflect.TypeOf(base) != reflect.TypeOf(current) {
+		return fmt.Errorf("cannot diff %T against %T", current, base)
+	}
+
+	from := make(map[string][]string)
+	if err := m.Marshal(base, from); err != nil {
+		return err
+	}
+
+	to := make(map[string][]string)
+	if err := m.Marshal(current, to); err != nil {
+		return err
+	}
+
+	for key, val := range to {
+		if !slices.Equal(from[key], val) {
+			v[key] = val
+		}
+	}
+
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			v[key] = []string{}
+		}
+	}
+
+	return nil
+}
+
+func MarshalDiff(base, current any, v map[string][]string) error {
+	return DefaultMarshaler.MarshalDiff(base, current, v)
+}
+
+// MustMarshal marshals src into a new map and panics on error. It is intended
+// for tests and fixtures, where an error is always a programming mistake.
+func (m *Marshaler) MustMarshal(src any) map[string][]string {
+	v := make(map[string][]string)
+
+	if err := m.Marshal(src, v); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func MustMarshal(src any) map[string][]string {
+	return DefaultMarshaler.MustMarshal(src)
+}
+
+func MarshalSorted(src any) ([]KeyValues, error) {
+	return DefaultMarshaler.MarshalSorted(src)
+}
+
 func MarshalHeader(src any, v http.Header) error {
 	return HeaderMarshaler.Marshal(src, v)
 }
+
+func MarshalMetadata(src any, v map[string][]string) error {
+	return MetadataMarshaler.Marshal(src, v)
+}
+
+func MarshalQueryString(src any, v url.Values) error {
+	return QueryStringMarshaler.Marshal(src, v)
+}
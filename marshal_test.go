@@ -17,8 +17,13 @@ limitations under the License.
 package structmap_test
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/adzil/structmap"
 	"github.com/stretchr/testify/assert"
@@ -50,6 +55,400 @@ func TestMarshal(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, expected, actual)
 	})
+
+	t.Run("WithCompileLimits", func(t *testing.T) {
+		type node struct {
+			Value string
+			Next  *node
+		}
+
+		type wideStruct struct {
+			A, B, C string
+		}
+
+		var limitErr *structmap.LimitError
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{MaxDepth: 3})
+
+		err := m.Marshal(node{}, make(map[string][]string))
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxDepth", limitErr.Limit)
+
+		m = structmap.NewMarshaler(structmap.MarshalConfig{MaxFields: 2})
+
+		err = m.Marshal(wideStruct{}, make(map[string][]string))
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxFields", limitErr.Limit)
+	})
+
+	t.Run("WithSkipUnsupported", func(t *testing.T) {
+		type testStruct struct {
+			Name     string        `map:"name"`
+			Done     chan struct{} `map:"done"`
+			Callback func()        `map:"callback"`
+		}
+
+		input := testStruct{Name: "alice"}
+
+		err := structmap.Marshal(input, map[string][]string{})
+		assert.ErrorContains(t, err, "cannot marshal from chan")
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{SkipUnsupported: true})
+		actual := make(map[string][]string)
+
+		err = m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"name": {"alice"}}, actual)
+	})
+
+	t.Run("WithOmitZero", func(t *testing.T) {
+		type nestedStruct struct {
+			Value string `map:"value"`
+		}
+
+		type testStruct struct {
+			Name   string       `map:"name,required"`
+			Page   int          `map:"page"`
+			Tags   []string     `map:"tags"`
+			Strict bool         `map:"strict,keepzero"`
+			Nested nestedStruct `map:"nested"`
+		}
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{OmitZero: true})
+		actual := make(map[string][]string)
+
+		err := m.Marshal(testStruct{Name: "alice"}, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"name":   {"alice"},
+			"strict": {"false"},
+		}, actual)
+
+		var invalid struct {
+			Value string `map:"value,omitempty,keepzero"`
+		}
+
+		err = m.Marshal(invalid, actual)
+		assert.ErrorContains(t, err, "keepzero")
+	})
+
+	t.Run("WithSliceStyle", func(t *testing.T) {
+		type testStruct struct {
+			Tags   []string `map:"tags"`
+			IDs    []int    `map:"ids,repeated"`
+			Sizes  []int    `map:"sizes,joined"`
+			Colors []string `map:"colors,semicolon"`
+		}
+
+		input := testStruct{
+			Tags:   []string{"a", "b"},
+			IDs:    []int{1, 2},
+			Sizes:  []int{3, 4},
+			Colors: []string{"red", "blue"},
+		}
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{SliceStyle: structmap.SliceIndexed})
+		actual := make(map[string][]string)
+
+		err := m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"tags.0": {"a"},
+			"tags.1": {"b"},
+			"ids":    {"1", "2"},
+			"sizes":  {"3,4"},
+			"colors": {"red;blue"},
+		}, actual)
+
+		m = structmap.NewMarshaler(structmap.MarshalConfig{
+			SliceStyle:     structmap.SliceJoined,
+			SliceSeparator: "|",
+		})
+		actual = make(map[string][]string)
+
+		err = m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"tags":   {"a|b"},
+			"ids":    {"1", "2"},
+			"sizes":  {"3|4"},
+			"colors": {"red;blue"},
+		}, actual)
+	})
+
+	t.Run("WithEmptySlice", func(t *testing.T) {
+		type testStruct struct {
+			Tags  []string `map:"tags"`
+			Sizes []int    `map:"sizes,comma"`
+			IDs   []int    `map:"ids,omitempty"`
+		}
+
+		input := testStruct{Tags: []string{}}
+
+		for _, tc := range []struct {
+			name     string
+			empty    structmap.EmptySlice
+			expected map[string][]string
+		}{
+			{"Key", structmap.EmptySliceKey, map[string][]string{"tags": nil, "sizes": {""}}},
+			{"Omit", structmap.EmptySliceOmit, map[string][]string{}},
+			{"Value", structmap.EmptySliceValue, map[string][]string{"tags": {""}, "sizes": {""}}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				m := structmap.NewMarshaler(structmap.MarshalConfig{EmptySlice: tc.empty})
+				actual := make(map[string][]string)
+
+				err := m.Marshal(input, actual)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			})
+		}
+	})
+
+	t.Run("WithTime", func(t *testing.T) {
+		type testStruct struct {
+			Date  time.Time `map:"date"`
+			Empty time.Time `map:"empty,omitempty"`
+		}
+
+		expected := map[string][]string{
+			"date": {"2023-08-17T10:00:00+07:00"},
+		}
+
+		input := testStruct{
+			Date: time.Date(2023, 8, 17, 10, 0, 0, 0, time.FixedZone("UTC+7", 7*60*60)),
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithFloat", func(t *testing.T) {
+		type testStruct struct {
+			Amount float64   `map:"amount"`
+			Rates  []float32 `map:"rates"`
+		}
+
+		expected := map[string][]string{
+			"amount": {"1234.56"},
+			"rates":  {"0.1", "1.25"},
+		}
+
+		input := testStruct{
+			Amount: 1234.56,
+			Rates:  []float32{0.1, 1.25},
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithCharOption", func(t *testing.T) {
+		type testStruct struct {
+			Symbol rune   `map:"symbol,char"`
+			Flag   byte   `map:"flag,char"`
+			Marks  []rune `map:"marks,char"`
+			Code   rune   `map:"code"`
+		}
+
+		expected := map[string][]string{
+			"symbol": {"€"},
+			"flag":   {"x"},
+			"marks":  {"✓", "✗"},
+			"code":   {"8364"},
+		}
+
+		input := testStruct{
+			Symbol: '€',
+			Flag:   'x',
+			Marks:  []rune{'✓', '✗'},
+			Code:   '€',
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithComplex", func(t *testing.T) {
+		type testStruct struct {
+			Impedance complex128  `map:"z"`
+			Roots     []complex64 `map:"roots"`
+		}
+
+		expected := map[string][]string{
+			"z":     {"50-25.5i"},
+			"roots": {"1+2i", "0-1i"},
+		}
+
+		input := testStruct{
+			Impedance: complex(50, -25.5),
+			Roots:     []complex64{complex(1, 2), complex(0, -1)},
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithPointer", func(t *testing.T) {
+		type nestedStruct struct {
+			Value string `map:"value"`
+		}
+
+		type testStruct struct {
+			Message *string       `map:"message"`
+			Nested  *nestedStruct `map:"nested"`
+		}
+
+		expected := map[string][]string{
+			"message":      {"hello"},
+			"nested.value": {"world"},
+		}
+
+		message := "hello"
+		input := &testStruct{
+			Message: &message,
+			Nested:  &nestedStruct{Value: "world"},
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithPointerOmitEmpty", func(t *testing.T) {
+		type testStruct struct {
+			Count  *int    `map:"count,omitempty"`
+			Name   *string `map:"name,omitempty"`
+			Limit  *int    `map:"limit,required"`
+			Offset *int    `map:"offset,omitempty"`
+		}
+
+		zero, empty := 0, ""
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(testStruct{Count: &zero, Name: &empty, Limit: &zero}, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"count": {"0"},
+			"name":  {""},
+			"limit": {"0"},
+		}, actual)
+
+		err = structmap.Marshal(testStruct{}, actual)
+		assert.ErrorContains(t, err, "key limit")
+	})
+
+	t.Run("WithSliceCompositions", func(t *testing.T) {
+		type item struct {
+			Name string `map:"name,required"`
+			Qty  int    `map:"qty,omitempty"`
+		}
+
+		type testStruct struct {
+			Tags    *[]string `map:"tags"`
+			Items   []item    `map:"items"`
+			Options []*item   `map:"options"`
+			Groups  *[]item   `map:"groups"`
+		}
+
+		tags := []string{"a", "b"}
+		input := testStruct{
+			Tags:    &tags,
+			Items:   []item{{Name: "x", Qty: 1}, {Name: "y"}},
+			Options: []*item{{Name: "z"}, nil},
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"tags":           {"a", "b"},
+			"items.0.name":   {"x"},
+			"items.0.qty":    {"1"},
+			"items.1.name":   {"y"},
+			"options.0.name": {"z"},
+		}, actual)
+
+		input.Items[1].Name = ""
+
+		err = structmap.Marshal(input, actual)
+		assert.ErrorContains(t, err, "key items.1.name")
+
+		// The element fields are counted once regardless of the slice length.
+		m := structmap.NewMarshaler(structmap.MarshalConfig{MaxFields: 10})
+		actual = make(map[string][]string)
+
+		err = m.Marshal(testStruct{Items: make([]item, 20)}, actual)
+		assert.ErrorContains(t, err, "key items.0.name")
+
+		input.Items = slices.Repeat([]item{{Name: "x"}}, 20)
+
+		err = m.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"x"}, actual["items.19.name"])
+	})
+
+	t.Run("WithEscapeOption", func(t *testing.T) {
+		type testStruct struct {
+			Redirect string   `map:"redirect,escape"`
+			Filters  []string `map:"filters,escape"`
+		}
+
+		expected := map[string][]string{
+			"redirect": {"https%3A%2F%2Fexample.com%2F%3Fa%3D1%26b%3D2"},
+			"filters":  {"a%2Cb", "c%20d"},
+		}
+
+		actual := make(map[string][]string)
+
+		err := structmap.Marshal(testStruct{
+			Redirect: "https://example.com/?a=1&b=2",
+			Filters:  []string{"a,b", "c d"},
+		}, actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithTraceFunc", func(t *testing.T) {
+		type Item struct {
+			Name string `map:"name"`
+		}
+
+		type testStruct struct {
+			Page  int    `map:"page"`
+			Items []Item `map:"items"`
+		}
+
+		var traces []string
+
+		m := structmap.NewMarshaler(structmap.MarshalConfig{
+			TraceFunc: func(key, fieldPath string, values []string) {
+				traces = append(traces, fmt.Sprintf("%s %s %v", key, fieldPath, values))
+			},
+		})
+
+		err := m.Marshal(testStruct{Page: 2, Items: []Item{{Name: "x"}}}, make(map[string][]string))
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"page Page [2]",
+			"items.0.name Items[0].Name [x]",
+		}, traces)
+	})
 }
 
 func TestMarshalHeader(t *testing.T) {
@@ -72,4 +471,376 @@ func TestMarshalHeader(t *testing.T) {
 	err := structmap.MarshalHeader(data, actual)
 	require.NoError(t, err)
 	assert.Equal(t, expected, actual)
+
+	t.Run("WithIndexedSlice", func(t *testing.T) {
+		type Item struct {
+			Name string `map:"name"`
+		}
+
+		type testHeader struct {
+			Items []Item `map:"items"`
+			Outer Item   `map:"outer"`
+		}
+
+		data := testHeader{Items: []Item{{Name: "a"}, {Name: "b"}}, Outer: Item{Name: "c"}}
+
+		output := make(map[string][]string)
+
+		err := structmap.MarshalHeader(data, output)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"Items.0.name": {"a"},
+			"Items.1.name": {"b"},
+			"Outer.name":   {"c"},
+		}, output)
+
+		// The keys are canonicalized again when they are added to the header.
+		header := make(http.Header)
+		for key, vals := range output {
+			for _, val := range vals {
+				header.Add(key, val)
+			}
+		}
+
+		var actual testHeader
+
+		err = structmap.UnmarshalHeader(header, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, data, actual)
+	})
+}
+
+func TestMarshalMetadata(t *testing.T) {
+	type testMetadata struct {
+		RequestID string `map:"X-Request-ID"`
+		UserAgent string `map:"User-Agent"`
+	}
+
+	data := testMetadata{RequestID: "abc", UserAgent: "client/1.0"}
+
+	actual := make(map[string][]string)
+
+	err := structmap.MarshalMetadata(data, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"x-request-id": {"abc"},
+		"user-agent":   {"client/1.0"},
+	}, actual)
+
+	var output testMetadata
+
+	err = structmap.UnmarshalMetadata(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, data, output)
+}
+
+func TestMarshalSorted(t *testing.T) {
+	type signedRequest struct {
+		Version string   `map:"Version"`
+		Action  string   `map:"Action"`
+		Names   []string `map:"name"`
+		Empty   string   `map:"empty,omitempty"`
+	}
+
+	actual, err := structmap.MarshalSorted(signedRequest{
+		Version: "2011-06-15",
+		Action:  "ListUsers",
+		Names:   []string{"zed", "alice"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []structmap.KeyValues{
+		{Key: "Action", Values: []string{"ListUsers"}},
+		{Key: "Version", Values: []string{"2011-06-15"}},
+		{Key: "name", Values: []string{"zed", "alice"}},
+	}, actual)
+}
+
+func TestMarshalSingle(t *testing.T) {
+	type testStruct struct {
+		Name  string   `map:"name"`
+		Tags  []string `map:"tags"`
+		Empty []string `map:"empty"`
+	}
+
+	input := testStruct{Name: "alice", Tags: []string{"a", "b"}}
+
+	actual, err := structmap.MarshalSingle(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "alice", "tags": "a", "empty": ""}, actual)
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{MultiValue: structmap.MultiValueJoin})
+
+	actual, err = m.MarshalSingle(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "alice", "tags": "a,b", "empty": ""}, actual)
+
+	m = structmap.NewMarshaler(structmap.MarshalConfig{MultiValue: structmap.MultiValueError})
+
+	_, err = m.MarshalSingle(input)
+	assert.ErrorContains(t, err, "key tags")
+}
+
+func TestMarshalDiff(t *testing.T) {
+	type testStruct struct {
+		Name  string   `map:"name"`
+		Email string   `map:"email,omitempty"`
+		Age   int      `map:"age"`
+		Tags  []string `map:"tags"`
+	}
+
+	base := testStruct{Name: "alice", Email: "alice@example.com", Age: 30, Tags: []string{"a", "b"}}
+	current := testStruct{Name: "alice", Age: 31, Tags: []string{"a", "c"}}
+
+	actual := make(map[string][]string)
+
+	err := structmap.MarshalDiff(base, current, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"email": {},
+		"age":   {"31"},
+		"tags":  {"a", "c"},
+	}, actual)
+
+	err = structmap.MarshalDiff(base, &current, actual)
+	assert.ErrorContains(t, err, "cannot diff")
+}
+
+func TestMarshalerAll(t *testing.T) {
+	type testStruct struct {
+		Name  string   `map:"name"`
+		Tags  []string `map:"tags"`
+		Limit int      `map:"limit,required"`
+	}
+
+	var keys []string
+
+	for key, vals := range structmap.DefaultMarshaler.All(testStruct{Name: "alice", Tags: []string{"a"}, Limit: 1}) {
+		keys = append(keys, key)
+
+		if key == "tags" {
+			assert.Equal(t, []string{"a"}, vals)
+
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"name", "tags"}, keys)
+
+	it := structmap.DefaultMarshaler.Iter(testStruct{Name: "alice"})
+
+	keys = nil
+
+	for key := range it.All() {
+		keys = append(keys, key)
+	}
+
+	assert.Equal(t, []string{"name", "tags"}, keys)
+	assert.ErrorContains(t, it.Err(), "key limit")
+
+	assert.Panics(t, func() {
+		for range structmap.DefaultMarshaler.All(testStruct{}) {
+		}
+	})
+}
+
+func TestMustMarshal(t *testing.T) {
+	type testStruct struct {
+		Name string `map:"name,required"`
+	}
+
+	assert.Equal(t, map[string][]string{"name": {"alice"}}, structmap.MustMarshal(testStruct{Name: "alice"}))
+
+	assert.Panics(t, func() {
+		structmap.MustMarshal(testStruct{})
+	})
+}
+
+func TestMarshalQueryString(t *testing.T) {
+	type pagination struct {
+		Page    int `url:"page,omitempty"`
+		PerPage int `url:"per_page,omitempty"`
+	}
+
+	type listOptions struct {
+		Query    string     `url:"q"`
+		Archived bool       `url:"archived,int"`
+		Labels   []string   `url:"labels,comma"`
+		IDs      []uint     `url:"id,brackets"`
+		Sort     []string   `url:"sort,numbered"`
+		Since    time.Time  `url:"since,unix"`
+		Before   time.Time  `url:"before,omitempty"`
+		Paging   pagination `url:"paging"`
+		Internal string     `url:"-"`
+	}
+
+	data := listOptions{
+		Query:    "bug",
+		Labels:   []string{"a", "b"},
+		IDs:      []uint{1, 2},
+		Sort:     []string{"name", "date"},
+		Since:    time.Unix(1692230400, 0),
+		Paging:   pagination{Page: 2},
+		Internal: "hidden",
+	}
+
+	expected := url.Values{
+		"q":            {"bug"},
+		"archived":     {"0"},
+		"labels":       {"a,b"},
+		"id[]":         {"1", "2"},
+		"sort0":        {"name"},
+		"sort1":        {"date"},
+		"since":        {"1692230400"},
+		"paging[page]": {"2"},
+	}
+
+	actual := make(url.Values)
+
+	err := structmap.MarshalQueryString(data, actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestFormMarshaler(t *testing.T) {
+	type user struct {
+		Name string `form:"name"`
+	}
+
+	type request struct {
+		Names   []string  `form:"names"`
+		Users   []user    `form:"users"`
+		Since   time.Time `form:"since"`
+		Ignored string    `form:"-"`
+	}
+
+	expected := map[string][]string{
+		"names":         {"a", "b"},
+		"users[0].name": {"x"},
+		"users[1].name": {"y"},
+		"since":         {"2023-08-17T10:00:00Z"},
+	}
+
+	input := request{
+		Names:   []string{"a", "b"},
+		Users:   []user{{Name: "x"}, {Name: "y"}},
+		Since:   time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC),
+		Ignored: "ignored",
+	}
+
+	actual := make(map[string][]string)
+
+	err := structmap.FormMarshaler.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+type ptrValue struct{}
+
+func (*ptrValue) MarshalValue() ([]string, error) {
+	return []string{"x"}, nil
+}
+
+func TestMarshalUnsupportedValueError(t *testing.T) {
+	type testStruct struct {
+		Value ptrValue `map:"value"`
+	}
+
+	err := structmap.Marshal(testStruct{}, make(map[string][]string))
+
+	var uve *structmap.UnsupportedValueError
+	require.ErrorAs(t, err, &uve)
+	assert.Equal(t, reflect.TypeOf(ptrValue{}), uve.Value.Type())
+
+	err = structmap.Marshal(&testStruct{}, make(map[string][]string))
+	assert.NoError(t, err)
+}
+
+type optionalValue string
+
+func (v optionalValue) MarshalValue() ([]string, error) {
+	switch v {
+	case "":
+		return nil, structmap.ErrOmitKey
+	case "empty":
+		return []string{}, nil
+	}
+
+	return []string{string(v)}, nil
+}
+
+func TestMarshalErrOmitKey(t *testing.T) {
+	type testStruct struct {
+		Omitted  optionalValue `map:"omitted"`
+		Empty    optionalValue `map:"empty"`
+		Set      optionalValue `map:"set"`
+		Required optionalValue `map:"required,required"`
+	}
+
+	actual := map[string][]string{"omitted": {"old"}}
+
+	err := structmap.Marshal(testStruct{Empty: "empty", Set: "x", Required: "y"}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"omitted":  {"old"},
+		"empty":    nil,
+		"set":      {"x"},
+		"required": {"y"},
+	}, actual)
+
+	err = structmap.Marshal(testStruct{}, make(map[string][]string))
+	assert.ErrorContains(t, err, "key required: ")
+}
+
+func TestMarshalUnique(t *testing.T) {
+	type testStruct struct {
+		Tags    []string `map:"tag,unique"`
+		Joined  []int    `map:"joined,comma,unique"`
+		Indexed []string `map:"indexed,indexed,unique"`
+	}
+
+	actual := make(map[string][]string)
+
+	err := structmap.Marshal(testStruct{
+		Tags:    []string{"a", "a", "b"},
+		Joined:  []int{1, 2, 1},
+		Indexed: []string{"x", "x", "y"},
+	}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"tag":       {"a", "b"},
+		"joined":    {"1,2"},
+		"indexed.0": {"x"},
+		"indexed.1": {"y"},
+	}, actual)
+}
+
+func TestMarshalSortedOption(t *testing.T) {
+	type testStruct struct {
+		Tags   []string  `map:"tag,sorted"`
+		IDs    []int     `map:"id,sorted,unique"`
+		Scores []float64 `map:"score,comma,sorted"`
+	}
+
+	src := testStruct{
+		Tags:   []string{"b", "c", "a"},
+		IDs:    []int{10, 9, 100, 9},
+		Scores: []float64{2.5, -1, 10},
+	}
+
+	actual := make(map[string][]string)
+
+	err := structmap.Marshal(src, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"tag":   {"a", "b", "c"},
+		"id":    {"9", "10", "100"},
+		"score": {"-1,2.5,10"},
+	}, actual)
+	assert.Equal(t, []string{"b", "c", "a"}, src.Tags)
+
+	var output testStruct
+
+	err = structmap.Unmarshal(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, []int{9, 10, 100}, output.IDs)
 }
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"strings"
+	"unicode"
+)
+
+// splitWords splits a Go identifier into its words, keeping the initialisms
+// together, e.g. "HTTPServerID" into "HTTP", "Server" and "ID".
+func splitWords(s string) []string {
+	var (
+		words []string
+		start int
+	)
+
+	runes := []rune(s)
+
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case cur == '_':
+			words = append(words, string(runes[start:i]))
+			start = i + 1
+
+			continue
+
+		case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+		case unicode.IsUpper(cur) && unicode.IsUpper(prev) && unicode.IsLower(next):
+		default:
+			continue
+		}
+
+		words = append(words, string(runes[start:i]))
+		start = i
+	}
+
+	words = append(words, string(runes[start:]))
+
+	out := words[:0]
+
+	for _, word := range words {
+		if word != "" {
+			out = append(out, word)
+		}
+	}
+
+	return out
+}
+
+func joinWords(s, sep string, transform func(s string) string) string {
+	words := splitWords(s)
+
+	for i, word := range words {
+		words[i] = transform(word)
+	}
+
+	return strings.Join(words, sep)
+}
+
+// SnakeCase converts the field name into "snake_case", e.g. "CreatedAt" into
+// "created_at". It can be used as the NameFunc of the configs.
+func SnakeCase(s string) string {
+	return joinWords(s, "_", strings.ToLower)
+}
+
+// KebabCase converts the field name into "kebab-case".
+func KebabCase(s string) string {
+	return joinWords(s, "-", strings.ToLower)
+}
+
+// ScreamingSnake converts the field name into "SCREAMING_SNAKE", e.g. for
+// environment variables.
+func ScreamingSnake(s string) string {
+	return joinWords(s, "_", strings.ToUpper)
+}
+
+// LowerCamel converts the field name into "lowerCamel", where a leading
+// initialism is lowercased as a whole, e.g. "HTTPServer" into "httpServer".
+func LowerCamel(s string) string {
+	words := splitWords(s)
+
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+		} else {
+			runes := []rune(strings.ToLower(word))
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+	}
+
+	return strings.Join(words, "")
+}
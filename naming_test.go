@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameFunc(t *testing.T) {
+	tests := []struct {
+		input     string
+		snake     string
+		kebab     string
+		screaming string
+		camel     string
+	}{
+		{"CreatedAt", "created_at", "created-at", "CREATED_AT", "createdAt"},
+		{"HTTPServerID", "http_server_id", "http-server-id", "HTTP_SERVER_ID", "httpServerId"},
+		{"Page2Size", "page2_size", "page2-size", "PAGE2_SIZE", "page2Size"},
+		{"ID", "id", "id", "ID", "id"},
+		{"user_name", "user_name", "user-name", "USER_NAME", "userName"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.snake, structmap.SnakeCase(tc.input))
+			assert.Equal(t, tc.kebab, structmap.KebabCase(tc.input))
+			assert.Equal(t, tc.screaming, structmap.ScreamingSnake(tc.input))
+			assert.Equal(t, tc.camel, structmap.LowerCamel(tc.input))
+		})
+	}
+
+	type Paging struct {
+		PageSize int
+	}
+
+	type testStruct struct {
+		CreatedAfter string
+		UserID       int `map:"uid"`
+		Paging
+	}
+
+	input := testStruct{CreatedAfter: "2023", UserID: 1, Paging: Paging{PageSize: 10}}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{NameFunc: structmap.SnakeCase})
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{NameFunc: structmap.SnakeCase})
+
+	actual := make(map[string][]string)
+	err := m.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"created_after": {"2023"},
+		"uid":           {"1"},
+		"page_size":     {"10"},
+	}, actual)
+
+	var output testStruct
+
+	err = u.Unmarshal(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+}
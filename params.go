@@ -0,0 +1,327 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	_ marshaler   = (*paramsMarshaler)(nil)
+	_ unmarshaler = (*paramsUnmarshaler)(nil)
+)
+
+// paramsFormat is a header value that holds a struct as its parameters, e.g.
+// the mime, auth, link, forwarded and directives options. The parameters are
+// keyed by their lowercase name, and the field with the value option is keyed
+// by mimeValueKey.
+type paramsFormat struct {
+	// name is the tag option of the format.
+	name string
+
+	// list formats a slice of structs as the elements of a comma-separated
+	// list instead of a single struct.
+	list bool
+
+	// value allows the field with the value option inside the struct.
+	value bool
+
+	// token68 allows the field with the token68 option inside the struct.
+	token68 bool
+
+	// invalid is returned when the option is set on an unsupported type.
+	invalid error
+
+	// format formats the non-empty parameters of a struct, where the bool
+	// fields listed in flags are "true" or "false". An empty result means
+	// there is nothing to write.
+	format func(params map[string]string, flags map[string]bool) (string, error)
+
+	// parse parses the header values into the parameters of every struct,
+	// where the flags present in a value are set into "true".
+	parse func(values []string, flags map[string]bool) ([]map[string]string, error)
+}
+
+// paramsFormats holds the formats keyed by their tag option.
+var paramsFormats = map[string]*paramsFormat{
+	mimeParams.name:       mimeParams,
+	authParams.name:       authParams,
+	linkParams.name:       linkParams,
+	forwardedParams.name:  forwardedParams,
+	directivesParams.name: directivesParams,
+}
+
+// validType reports whether the option can be set on a field of typ.
+func (f *paramsFormat) validType(typ reflect.Type) bool {
+	if f.list {
+		return isSliceType(typ)
+	}
+
+	return indirectType(typ).Kind() == reflect.Struct
+}
+
+// elemType returns the struct type of the field type.
+func (f *paramsFormat) elemType(typ reflect.Type) (reflect.Type, error) {
+	if f.list {
+		typ = typ.Elem()
+	}
+
+	if indirectType(typ).Kind() != reflect.Struct {
+		return nil, f.invalid
+	}
+
+	return indirectType(typ), nil
+}
+
+func (c *marshalConfig) setParams(params *paramsFormat) error {
+	if c.params != nil && c.params != params {
+		return fmt.Errorf("cannot combine %s and %s options", c.params.name, params.name)
+	}
+
+	c.params = params
+
+	return nil
+}
+
+func (cfg *unmarshalConfig) setParams(params *paramsFormat) error {
+	if cfg.params != nil && cfg.params != params {
+		return fmt.Errorf("cannot combine %s and %s options", cfg.params.name, params.name)
+	}
+
+	cfg.params = params
+
+	return nil
+}
+
+// paramFlags returns the keys of the bool fields of a parameters struct,
+// which are set by the presence of their parameter instead of its value. The
+// authToken68Key is included when the struct has a token68 field.
+func paramFlags(typ reflect.Type, tagName string, fieldName func(structFld reflect.StructField) string) map[string]bool {
+	flags := make(map[string]bool)
+
+	for i := 0; i < typ.NumField(); i++ {
+		structFld := typ.Field(i)
+		if !structFld.IsExported() {
+			continue
+		}
+
+		name, opts := ParseTag(structFld.Tag.Get(tagName))
+		if hasTagOption(opts, "token68") {
+			flags[authToken68Key] = true
+
+			continue
+		}
+
+		if indirectType(structFld.Type).Kind() != reflect.Bool {
+			continue
+		}
+
+		if name == "" {
+			name = fieldName(structFld)
+		}
+
+		flags[strings.ToLower(name)] = true
+	}
+
+	return flags
+}
+
+// paramsMarshaler formats a struct, or every struct of a slice, into a header
+// value using its paramsFormat.
+type paramsMarshaler struct {
+	keyMarshaler
+	params *paramsFormat
+	flags  map[string]bool
+	elem   marshaler
+}
+
+func (m *paramsMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	var elems []string
+
+	if !m.params.list {
+		s, err := m.marshalElem(src)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), err)
+		}
+
+		if s != "" {
+			elems = append(elems, s)
+		}
+	}
+
+	for i := 0; m.params.list && i < src.Len(); i++ {
+		elem := src.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				continue
+			}
+
+			elem = elem.Elem()
+		}
+
+		s, err := m.marshalElem(elem)
+		if err != nil {
+			return fmt.Errorf("key %s: slice index #%d: %w", ctx.key(m.key), i, err)
+		}
+
+		if s != "" {
+			elems = append(elems, s)
+		}
+	}
+
+	if len(elems) == 0 {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		return nil
+	}
+
+	ctx.set(v, m.key, strings.Join(elems, ", "))
+
+	return nil
+}
+
+func (m *paramsMarshaler) marshalElem(src reflect.Value) (string, error) {
+	out := make(map[string][]string)
+
+	if err := m.elem.marshal(marshalContext{}, src, out); err != nil {
+		return "", err
+	}
+
+	params := make(map[string]string, len(out))
+
+	for key, vals := range out {
+		if len(vals) > 0 && vals[0] != "" {
+			params[key] = vals[0]
+		}
+	}
+
+	return m.params.format(params, m.flags)
+}
+
+func newParamsMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	elemTyp, err := cfg.params.elemType(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	elemCfg := newMarshalConfig(cfg.MarshalConfig)
+	elemCfg.KeyLookupFunc = strings.ToLower
+	elemCfg.JoinKeyFunc = nil
+	elemCfg.mimeStruct = cfg.params.value
+	elemCfg.authStruct = cfg.params.token68
+	elemCfg.depth = cfg.depth
+	elemCfg.fields = cfg.fields
+
+	elem, err := newStructMarshaler(elemCfg, elemTyp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &paramsMarshaler{
+		keyMarshaler: newKeyMarshaler(cfg),
+		params:       cfg.params,
+		flags:        paramFlags(elemTyp, cfg.tagName(), cfg.fieldName),
+		elem:         elem,
+	}, nil
+}
+
+// paramsUnmarshaler parses a header value into a struct, or into a slice of
+// structs, using its paramsFormat.
+type paramsUnmarshaler struct {
+	typ    reflect.Type
+	params *paramsFormat
+	flags  map[string]bool
+	elem   unmarshaler
+}
+
+func (u *paramsUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	elems, err := u.params.parse(ctx.value, u.flags)
+	if err != nil {
+		return err
+	}
+
+	ctx.value = nil
+
+	if !u.params.list {
+		return u.elem.unmarshal(ctx, paramsMap(elems[0]), dst)
+	}
+
+	out := reflect.MakeSlice(u.typ, len(elems), len(elems))
+
+	for i, params := range elems {
+		if err := u.elem.unmarshal(ctx, paramsMap(params), out.Index(i)); err != nil {
+			return fmt.Errorf("slice index #%d: %w", i, err)
+		}
+	}
+
+	dst.Set(out)
+
+	return nil
+}
+
+func paramsMap(params map[string]string) map[string][]string {
+	v := make(map[string][]string, len(params))
+
+	for key, val := range params {
+		v[key] = []string{val}
+	}
+
+	return v
+}
+
+func newParamsUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	structTyp, err := cfg.params.elemType(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parameters are parsed with their own keys and values, so the input
+	// key and value transforms do not apply to them.
+	elemCfg := newUnmarshalConfig(cfg.UnmarshalConfig)
+	elemCfg.KeyLookupFunc = strings.ToLower
+	elemCfg.KeyFold = 0
+	elemCfg.KeyRewrites = nil
+	elemCfg.NullValues = nil
+	elemCfg.BracketIndex = false
+	elemCfg.mimeStruct = cfg.params.value
+	elemCfg.authStruct = cfg.params.token68
+	elemCfg.depth = cfg.depth
+	elemCfg.fields = cfg.fields
+
+	elem, err := newStructUnmarshaler(elemCfg, structTyp)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.params.list && typ.Elem().Kind() == reflect.Pointer {
+		elem = &pointerUnmarshaler{
+			elemTyp: typ.Elem().Elem(),
+			elem:    elem,
+		}
+	}
+
+	return &paramsUnmarshaler{
+		typ:    typ,
+		params: cfg.params,
+		flags:  paramFlags(structTyp, cfg.tagName(), cfg.fieldName),
+		elem:   elem,
+	}, nil
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ marshaler   = (*prefixMapMarshaler)(nil)
+	_ unmarshaler = (*prefixMapUnmarshaler)(nil)
+)
+
+var errInvalidPrefix = errors.New("prefix option is only valid for map with string keys")
+
+// prefixMapMarshaler marshals a map field with the prefix option, where every
+// entry is written under the field key followed by the map key, e.g.
+// "X-Amz-Meta-" and "color" into "X-Amz-Meta-color".
+type prefixMapMarshaler struct {
+	keyMarshaler
+	keyLookup func(s string) string
+	format    func(src reflect.Value) (string, error)
+	slice     bool
+	// elem marshals the values that cannot be formatted directly, e.g. the
+	// pointers or the ValueMarshaler implementations, under an empty key.
+	elem    marshaler
+	elemTyp reflect.Type
+}
+
+func (m *prefixMapMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if src.Len() == 0 {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		return nil
+	}
+
+	iter := src.MapRange()
+	for iter.Next() {
+		key := m.key + iter.Key().String()
+		if m.keyLookup != nil {
+			key = m.keyLookup(key)
+		}
+
+		key = ctx.key(key)
+
+		elem := iter.Value()
+
+		if m.elem != nil {
+			if err := m.marshalElem(key, elem, v); err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+
+			continue
+		}
+
+		if !m.slice {
+			val, err := m.format(elem)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+
+			v[key] = append(v[key][:0], val)
+
+			continue
+		}
+
+		out := v[key][:0]
+
+		for i := 0; i < elem.Len(); i++ {
+			val, err := m.format(elem.Index(i))
+			if err != nil {
+				return fmt.Errorf("key %s: slice index #%d: %w", key, i, err)
+			}
+
+			out = append(out, val)
+		}
+
+		v[key] = out
+	}
+
+	return nil
+}
+
+func (m *prefixMapMarshaler) marshalElem(key string, elem reflect.Value, v map[string][]string) error {
+	// The map values are not addressable, so they are copied for the methods
+	// with a pointer receiver.
+	val := reflect.New(m.elemTyp).Elem()
+	val.Set(elem)
+
+	out := make(map[string][]string, 1)
+	if err := m.elem.marshal(marshalContext{}, val, out); err != nil {
+		return err
+	}
+
+	if vals, ok := out[""]; ok {
+		v[key] = append(v[key][:0], vals...)
+	}
+
+	return nil
+}
+
+func newPrefixMapMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	if typ.Key().Kind() != reflect.String {
+		return nil, errInvalidPrefix
+	}
+
+	elem := typ.Elem()
+
+	slice := elem.Kind() == reflect.Slice
+	if _, ok := findCodec(cfg.Codecs, elem); ok {
+		slice = false
+	}
+
+	if slice {
+		elem = elem.Elem()
+	}
+
+	format := getFormatFunc(cfg, elem)
+	if format == nil {
+		return newPrefixMapElemMarshaler(cfg, typ)
+	}
+
+	return &prefixMapMarshaler{
+		keyMarshaler: newKeyMarshaler(cfg),
+		keyLookup:    cfg.KeyLookupFunc,
+		format:       format,
+		slice:        slice,
+	}, nil
+}
+
+// newPrefixMapElemMarshaler compiles the map values with the regular value
+// marshaler under an empty key, which is then moved to the entry key.
+func newPrefixMapElemMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	elemCfg := cfg
+	elemCfg.Name = nil
+	elemCfg.KeyLookupFunc = nil
+	elemCfg.PrefixMap = false
+	elemCfg.Required = false
+	elemCfg.OmitEmpty = false
+
+	elem, err := newValueMarshaler(elemCfg, typ.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	if isNestedMarshaler(elem) {
+		return nil, newUnsupportedTypeError(typ.Elem(), "cannot marshal from map of %s", typ.Elem().String())
+	}
+
+	return &prefixMapMarshaler{
+		keyMarshaler: newKeyMarshaler(cfg),
+		keyLookup:    cfg.KeyLookupFunc,
+		elem:         elem,
+		elemTyp:      typ.Elem(),
+	}, nil
+}
+
+// prefixMapUnmarshaler collects every key that starts with the field key into
+// a map, keyed by the rest of the key.
+type prefixMapUnmarshaler struct {
+	typ      reflect.Type
+	prefix   string
+	elem     unmarshaler
+	secret   bool
+	required bool
+}
+
+func (u *prefixMapUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	if ctx.mask != nil && !ctx.mask.touches(ctx.keyPrefix+u.prefix) {
+		return nil
+	}
+
+	var out reflect.Value
+
+	for key, val := range v {
+		rest, ok := strings.CutPrefix(key, u.prefix)
+		if !ok || rest == "" || len(val) == 0 {
+			continue
+		}
+
+		if err := ctx.budget.consume(val); err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+
+		if ctx.valueCheck != nil {
+			if err := ctx.valueCheck(key, val); err != nil {
+				return &FieldError{Key: key, Field: "[" + strconv.Quote(rest) + "]", Index: -1, Err: err}
+			}
+		}
+
+		if !out.IsValid() {
+			out = reflect.MakeMap(u.typ)
+		}
+
+		ctx.value = val
+
+		elem := reflect.New(u.typ.Elem()).Elem()
+		if err := u.elem.unmarshal(ctx, v, elem); err != nil {
+			fe, ok := err.(*FieldError)
+			if !ok {
+				fe = &FieldError{Index: -1, Err: err}
+
+				if len(val) == 1 {
+					fe.Value = val[0]
+				}
+			}
+
+			fe.Key = key
+			fe.Field = "[" + strconv.Quote(rest) + "]"
+
+			if u.secret {
+				fe.Value = ""
+				fe.Err = &redactedError{err: fe.Err}
+			}
+
+			return fe
+		}
+
+		out.SetMapIndex(reflect.ValueOf(rest).Convert(u.typ.Key()), elem)
+	}
+
+	if !out.IsValid() {
+		if u.required {
+			return fmt.Errorf(`no key found for required prefix "%s"`, u.prefix)
+		}
+
+		dst.SetZero()
+
+		return nil
+	}
+
+	dst.Set(out)
+
+	return nil
+}
+
+func newPrefixMapUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	if typ.Key().Kind() != reflect.String {
+		return nil, errInvalidPrefix
+	}
+
+	elem := typ.Elem()
+
+	elemCfg := cfg
+	elemCfg.PrefixMap = false
+	elemCfg.Required = false
+
+	unm, nested, err := newValueUnmarshaler(elemCfg, elem)
+	if err != nil {
+		return nil, err
+	}
+
+	if nested {
+		return nil, newUnsupportedTypeError(elem, "cannot unmarshal into map of %s", elem.String())
+	}
+
+	prefix := cfg.lookupKey(cfg.joinKey(cfg.Prefix))
+
+	if cfg.Source == sourceHeader {
+		prefix = http.CanonicalHeaderKey(prefix)
+	}
+
+	return &prefixMapUnmarshaler{
+		typ:      typ,
+		prefix:   prefix,
+		elem:     unm,
+		secret:   cfg.Secret,
+		required: cfg.Required,
+	}, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapVersion implements the value methods with a pointer receiver, which
+// cannot be called on the map values directly.
+type mapVersion struct {
+	Major, Minor int
+}
+
+func (v *mapVersion) MarshalValue() ([]string, error) {
+	return []string{fmt.Sprintf("v%d.%d", v.Major, v.Minor)}, nil
+}
+
+func (v *mapVersion) UnmarshalValue(val []string) error {
+	if _, err := fmt.Sscanf(val[0], "v%d.%d", &v.Major, &v.Minor); err != nil {
+		return fmt.Errorf("invalid version %q", val[0])
+	}
+
+	return nil
+}
+
+func TestPrefixMap(t *testing.T) {
+	type objectHeader struct {
+		ContentType string            `map:"content-type"`
+		Meta        map[string]string `map:"x-amz-meta-,prefix"`
+	}
+
+	t.Run("WithHeader", func(t *testing.T) {
+		input := make(http.Header)
+		input.Set("Content-Type", "image/png")
+		input.Set("X-Amz-Meta-Color", "red")
+		input.Set("X-Amz-Meta-Owner", "alice")
+
+		var actual objectHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, objectHeader{
+			ContentType: "image/png",
+			Meta:        map[string]string{"Color": "red", "Owner": "alice"},
+		}, actual)
+
+		output := make(http.Header)
+
+		err = structmap.MarshalHeader(actual, output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+	})
+
+	t.Run("WithTypedValues", func(t *testing.T) {
+		type testStruct struct {
+			Limits map[string]int      `map:"limit.,prefix"`
+			Tags   map[string][]string `map:"tag.,prefix"`
+		}
+
+		input := map[string][]string{
+			"limit.cpu": {"2"},
+			"limit.mem": {"512"},
+			"tag.env":   {"dev", "test"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{
+			Limits: map[string]int{"cpu": 2, "mem": 512},
+			Tags:   map[string][]string{"env": {"dev", "test"}},
+		}, actual)
+
+		output := make(map[string][]string)
+
+		err = structmap.Marshal(actual, output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+
+		err = structmap.Unmarshal(map[string][]string{"limit.cpu": {"two"}}, &actual)
+		assert.ErrorContains(t, err, "key limit.cpu")
+
+		err = structmap.Unmarshal(map[string][]string{}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{}, actual)
+	})
+
+	t.Run("WithValueTypes", func(t *testing.T) {
+		type testStruct struct {
+			Flags    map[string]bool       `map:"flag.,prefix"`
+			Times    map[string]time.Time  `map:"time.,prefix"`
+			Limits   map[string]*int       `map:"limit.,prefix"`
+			Versions map[string]mapVersion `map:"version.,prefix"`
+		}
+
+		limit := 5
+
+		input := map[string][]string{
+			"flag.debug":  {"true"},
+			"time.start":  {"2023-01-02T03:04:05Z"},
+			"limit.cpu":   {"5"},
+			"version.api": {"v1.2"},
+		}
+
+		expected := testStruct{
+			Flags:    map[string]bool{"debug": true},
+			Times:    map[string]time.Time{"start": time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+			Limits:   map[string]*int{"cpu": &limit},
+			Versions: map[string]mapVersion{"api": {Major: 1, Minor: 2}},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		output := make(map[string][]string)
+
+		err = structmap.Marshal(actual, output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+
+		err = structmap.Unmarshal(map[string][]string{"version.api": {"1.2"}}, &actual)
+		assert.ErrorContains(t, err, "key version.api")
+	})
+
+	t.Run("WithRequired", func(t *testing.T) {
+		var actual struct {
+			Meta map[string]string `map:"meta-,prefix,required"`
+		}
+
+		err := structmap.Unmarshal(map[string][]string{"meta": {"x"}}, &actual)
+		assert.EqualError(t, err, `no key found for required prefix "meta-"`)
+
+		err = structmap.Unmarshal(map[string][]string{"meta-a": {"x"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "x"}, actual.Meta)
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Meta string `map:"meta-,prefix"`
+		}
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.ErrorContains(t, err, "prefix option")
+
+		err = structmap.Marshal(actual, map[string][]string{})
+		assert.ErrorContains(t, err, "prefix option")
+	})
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ ValueMarshaler   = Range{}
+	_ ValueUnmarshaler = (*Range)(nil)
+	_ ValueMarshaler   = ContentRange{}
+	_ ValueUnmarshaler = (*ContentRange)(nil)
+)
+
+var errInvalidRange = errors.New("invalid range")
+
+// defaultRangeUnit is used to format the ranges without any unit.
+const defaultRangeUnit = "bytes"
+
+// ByteRange is a single range of a Range header, e.g. "0-499", "500-" or
+// "-200".
+type ByteRange struct {
+	// Start is the first position of the range.
+	Start int64
+
+	// End is the last position of the range, inclusive, or -1 when the range
+	// is open-ended.
+	End int64
+
+	// Suffix is the length of a suffix range, which selects the last bytes
+	// instead of using Start and End when it is positive.
+	Suffix int64
+}
+
+func (r ByteRange) String() string {
+	switch {
+	case r.Suffix > 0:
+		return "-" + strconv.FormatInt(r.Suffix, 10)
+	case r.End < 0:
+		return strconv.FormatInt(r.Start, 10) + "-"
+	}
+
+	return strconv.FormatInt(r.Start, 10) + "-" + strconv.FormatInt(r.End, 10)
+}
+
+// validate checks the range as parseByteRange would, so a marshaled range can
+// always be read back.
+func (r ByteRange) validate() error {
+	switch {
+	case r.Suffix > 0:
+		return nil
+	case r.Suffix < 0:
+		return fmt.Errorf("%w suffix %d", errInvalidRange, r.Suffix)
+	case r.Start < 0 || r.End < -1 || (r.End >= 0 && r.End < r.Start):
+		return fmt.Errorf("%w %d-%d", errInvalidRange, r.Start, r.End)
+	}
+
+	return nil
+}
+
+func parseByteRange(s string) (ByteRange, error) {
+	first, last, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return ByteRange{}, fmt.Errorf("%w %q", errInvalidRange, s)
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return ByteRange{}, fmt.Errorf("%w %q", errInvalidRange, s)
+		}
+
+		return ByteRange{Suffix: suffix}, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return ByteRange{}, fmt.Errorf("%w %q", errInvalidRange, s)
+	}
+
+	if last == "" {
+		return ByteRange{Start: start, End: -1}, nil
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return ByteRange{}, fmt.Errorf("%w %q", errInvalidRange, s)
+	}
+
+	return ByteRange{Start: start, End: end}, nil
+}
+
+// Range is the value of a Range header as defined by RFC 9110 section 14.2,
+// e.g. "bytes=0-499, 1000-".
+type Range struct {
+	// Unit is the range unit. Defaults to "bytes" on marshal.
+	Unit string
+
+	Ranges []ByteRange
+}
+
+func (r Range) MarshalValue() ([]string, error) {
+	if len(r.Ranges) == 0 {
+		return nil, nil
+	}
+
+	unit := r.Unit
+	if unit == "" {
+		unit = defaultRangeUnit
+	}
+
+	specs := make([]string, len(r.Ranges))
+	for i, br := range r.Ranges {
+		if err := br.validate(); err != nil {
+			return nil, err
+		}
+
+		specs[i] = br.String()
+	}
+
+	return []string{unit + "=" + strings.Join(specs, ", ")}, nil
+}
+
+func (r *Range) UnmarshalValue(v []string) error {
+	unit, specs, ok := strings.Cut(v[0], "=")
+	if !ok || unit == "" {
+		return fmt.Errorf("%w %q", errInvalidRange, v[0])
+	}
+
+	out := Range{Unit: strings.TrimSpace(unit)}
+
+	for _, spec := range strings.Split(specs, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+
+		br, err := parseByteRange(spec)
+		if err != nil {
+			return err
+		}
+
+		out.Ranges = append(out.Ranges, br)
+	}
+
+	if len(out.Ranges) == 0 {
+		return fmt.Errorf("%w %q", errInvalidRange, v[0])
+	}
+
+	*r = out
+
+	return nil
+}
+
+// ContentRange is the value of a Content-Range header as defined by RFC 9110
+// section 14.4, e.g. "bytes 0-499/1234" or "bytes */1234" for an unsatisfied
+// range.
+type ContentRange struct {
+	// Unit is the range unit. Defaults to "bytes" on marshal.
+	Unit string
+
+	// Start and End are the first and last position of the range, inclusive.
+	// Both are -1 for an unsatisfied range.
+	Start, End int64
+
+	// Size is the complete length of the representation, or -1 when it is
+	// unknown.
+	Size int64
+}
+
+func (r ContentRange) MarshalValue() ([]string, error) {
+	if r == (ContentRange{}) {
+		return nil, nil
+	}
+
+	unit := r.Unit
+	if unit == "" {
+		unit = defaultRangeUnit
+	}
+
+	size := "*"
+	if r.Size >= 0 {
+		size = strconv.FormatInt(r.Size, 10)
+	}
+
+	if r.Start < 0 {
+		if r.Size < 0 {
+			return nil, errors.New("unsatisfied range requires a size")
+		}
+
+		return []string{unit + " */" + size}, nil
+	}
+
+	if r.End < r.Start || (r.Size >= 0 && r.End >= r.Size) {
+		return nil, fmt.Errorf("%w %d-%d/%s", errInvalidRange, r.Start, r.End, size)
+	}
+
+	return []string{unit + " " + strconv.FormatInt(r.Start, 10) + "-" + strconv.FormatInt(r.End, 10) + "/" + size}, nil
+}
+
+func (r *ContentRange) UnmarshalValue(v []string) error {
+	unit, resp, ok := strings.Cut(strings.TrimSpace(v[0]), " ")
+	if !ok || unit == "" {
+		return fmt.Errorf("%w %q", errInvalidRange, v[0])
+	}
+
+	spec, size, ok := strings.Cut(strings.TrimSpace(resp), "/")
+	if !ok {
+		return fmt.Errorf("%w %q", errInvalidRange, v[0])
+	}
+
+	out := ContentRange{Unit: unit, Start: -1, End: -1, Size: -1}
+
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%w %q", errInvalidRange, v[0])
+		}
+
+		out.Size = n
+	}
+
+	if spec == "*" {
+		if out.Size < 0 {
+			return fmt.Errorf("%w %q", errInvalidRange, v[0])
+		}
+
+		*r = out
+
+		return nil
+	}
+
+	br, err := parseByteRange(spec)
+	if err != nil || br.Suffix > 0 || br.End < 0 || (out.Size >= 0 && br.End >= out.Size) {
+		return fmt.Errorf("%w %q", errInvalidRange, v[0])
+	}
+
+	out.Start, out.End = br.Start, br.End
+	*r = out
+
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRange(t *testing.T) {
+	type testHeader struct {
+		Range        *structmap.Range       `map:"range"`
+		ContentRange structmap.ContentRange `map:"content-range,omitempty"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Range":         {"bytes=0-499, 1000-, -200"},
+			"Content-Range": {"bytes 0-499/1234"},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testHeader{
+			Range: &structmap.Range{Unit: "bytes", Ranges: []structmap.ByteRange{
+				{Start: 0, End: 499},
+				{Start: 1000, End: -1},
+				{Suffix: 200},
+			}},
+			ContentRange: structmap.ContentRange{Unit: "bytes", Start: 0, End: 499, Size: 1234},
+		}, actual)
+
+		input = http.Header{"Content-Range": {"bytes */1234"}}
+
+		err = structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, structmap.ContentRange{Unit: "bytes", Start: -1, End: -1, Size: 1234}, actual.ContentRange)
+
+		input = http.Header{"Content-Range": {"bytes 0-499/*"}}
+
+		err = structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, structmap.ContentRange{Unit: "bytes", Start: 0, End: 499, Size: -1}, actual.ContentRange)
+
+		for _, val := range []string{"bytes=500-100", "bytes=", "0-100", "bytes=-0"} {
+			err = structmap.UnmarshalHeader(http.Header{"Range": {val}}, &actual)
+			assert.ErrorContains(t, err, "key Range: invalid range", val)
+		}
+
+		for _, val := range []string{"bytes 0-1234/1234", "bytes */*", "bytes 0-/100"} {
+			err = structmap.UnmarshalHeader(http.Header{"Content-Range": {val}}, &actual)
+			assert.ErrorContains(t, err, "key Content-Range: invalid range", val)
+		}
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{
+			Range: &structmap.Range{Ranges: []structmap.ByteRange{
+				{Start: 0, End: 499},
+				{Start: 1000, End: -1},
+				{Suffix: 200},
+			}},
+			ContentRange: structmap.ContentRange{Start: 0, End: 499, Size: -1},
+		}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Range":         {"bytes=0-499, 1000-, -200"},
+			"Content-Range": {"bytes 0-499/*"},
+		}, actual)
+
+		actual = make(http.Header)
+
+		err = structmap.MarshalHeader(testHeader{ContentRange: structmap.ContentRange{Start: -1, End: -1, Size: 10}}, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{"Content-Range": {"bytes */10"}}, actual)
+
+		err = structmap.MarshalHeader(testHeader{ContentRange: structmap.ContentRange{Start: 5, End: 10, Size: 10}}, actual)
+		assert.ErrorContains(t, err, "invalid range 5-10/10")
+
+		for br, msg := range map[structmap.ByteRange]string{
+			{Start: 500, End: 499}: "invalid range 500-499",
+			{Start: -1, End: 10}:   "invalid range -1-10",
+			{Start: 0, End: -2}:    "invalid range 0--2",
+			{Suffix: -1}:           "invalid range suffix -1",
+		} {
+			err = structmap.MarshalHeader(testHeader{Range: &structmap.Range{Ranges: []structmap.ByteRange{br}}}, actual)
+			assert.ErrorContains(t, err, msg)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import "slices"
+
+var (
+	_ ValueMarshaler   = Raw(nil)
+	_ ValueUnmarshaler = (*Raw)(nil)
+)
+
+// Raw holds the values of its key verbatim, without any parsing on unmarshal
+// or formatting on marshal, e.g. for the pass-through fields.
+type Raw []string
+
+func (r Raw) MarshalValue() ([]string, error) {
+	return slices.Clone(r), nil
+}
+
+func (r *Raw) UnmarshalValue(v []string) error {
+	*r = slices.Clone(v)
+
+	return nil
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var _ marshaler = (*sourceMarshaler)(nil)
+
+// The source options select which part of an HTTP request a field is bound
+// from. Nested struct fields inherit the source of their parent.
+const (
+	sourceQuery  = "query"
+	sourceHeader = "header"
+	sourcePath   = "path"
+	sourceForm   = "form"
+	sourceCookie = "cookie"
+
+	// sourceFragment reads the parameters encoded in the URL fragment, e.g.
+	// the OAuth implicit flow response "#access_token=...&state=...".
+	sourceFragment = "fragment"
+)
+
+// requestValues holds the values of each source. The request is nil when only
+// the URL is given, which leaves the request sources empty.
+type requestValues struct {
+	req      *http.Request
+	url      *url.URL
+	keys     func(v map[string][]string) map[string][]string
+	query    url.Values
+	form     url.Values
+	cookies  map[string][]string
+	fragment url.Values
+}
+
+func newRequestValues(r *http.Request, cfg UnmarshalConfig) *requestValues {
+	return &requestValues{
+		req:   r,
+		url:   r.URL,
+		keys:  cfg.inputKeys,
+		query: cfg.inputKeys(r.URL.Query()),
+	}
+}
+
+func (rv *requestValues) values(source, key string) (map[string][]string, error) {
+	switch source {
+	case sourceFragment:
+		if rv.fragment == nil {
+			fragment, err := url.ParseQuery(rv.url.EscapedFragment())
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse fragment: %w", err)
+			}
+
+			rv.fragment = rv.keys(fragment)
+		}
+
+		return rv.fragment, nil
+
+	case sourceQuery:
+		return rv.query, nil
+	}
+
+	if rv.req == nil {
+		return nil, nil
+	}
+
+	switch source {
+	case sourceHeader:
+		return rv.req.Header, nil
+
+	case sourcePath:
+		if val := rv.req.PathValue(key); val != "" {
+			return map[string][]string{key: {val}}, nil
+		}
+
+		return nil, nil
+
+	case sourceForm:
+		if rv.form == nil {
+			if err := rv.req.ParseForm(); err != nil {
+				return nil, fmt.Errorf("cannot parse form: %w", err)
+			}
+
+			rv.form = rv.keys(rv.req.PostForm)
+		}
+
+		return rv.form, nil
+
+	case sourceCookie:
+		if rv.cookies == nil {
+			cookies := make(map[string][]string)
+
+			for _, cookie := range rv.req.Cookies() {
+				cookies[cookie.Name] = append(cookies[cookie.Name], cookie.Value)
+			}
+
+			rv.cookies = rv.keys(cookies)
+		}
+
+		return rv.cookies, nil
+	}
+
+	return nil, fmt.Errorf("unknown source %s", source)
+}
+
+// UnmarshalRequest binds the request into dst. Each field reads from the
+// source selected by its "query", "header", "path", "form" or "cookie" option,
+// or from the query string when none is set. Header keys are always
+// canonicalized and path values are read using http.Request.PathValue.
+func (u *Unmarshaler) UnmarshalRequest(r *http.Request, dst any) error {
+	vu, elem, err := u.getUnmarshaler(dst)
+	if err != nil {
+		return err
+	}
+
+	ctx := u.config.newContext()
+	ctx.request = newRequestValues(r, u.config)
+
+	return vu.unmarshal(ctx, ctx.request.query, elem)
+}
+
+func UnmarshalRequest(r *http.Request, dst any) error {
+	return DefaultUnmarshaler.UnmarshalRequest(r, dst)
+}
+
+// UnmarshalURL binds the URL into dst. The fields read from the query string
+// by default, or from the parameters encoded in the fragment when they have
+// the "fragment" option. The fields of the other sources are left unset.
+func (u *Unmarshaler) UnmarshalURL(rawURL *url.URL, dst any) error {
+	vu, elem, err := u.getUnmarshaler(dst)
+	if err != nil {
+		return err
+	}
+
+	ctx := u.config.newContext()
+	ctx.request = &requestValues{
+		url:   rawURL,
+		keys:  u.config.inputKeys,
+		query: u.config.inputKeys(rawURL.Query()),
+	}
+
+	return vu.unmarshal(ctx, ctx.request.query, elem)
+}
+
+func UnmarshalURL(rawURL *url.URL, dst any) error {
+	return DefaultUnmarshaler.UnmarshalURL(rawURL, dst)
+}
+
+// UnmarshalResponse binds the response headers into dst, using the same
+// canonical key lookup as UnmarshalHeader.
+func UnmarshalResponse(resp *http.Response, dst any) error {
+	return HeaderUnmarshaler.Unmarshal(resp.Header, dst)
+}
+
+type sourceKey struct {
+	typ    reflect.Type
+	source string
+}
+
+// marshalSource marshals only the fields of src that are bound to the source.
+// Fields without any source option are bound to the query string.
+func (m *Marshaler) marshalSource(src any, source string, v map[string][]string) error {
+	val := reflect.ValueOf(src)
+
+	vm, err := m.sources.Get(sourceKey{typ: val.Type(), source: source}, func(key sourceKey) (marshaler, error) {
+		cfg := newMarshalConfig(m.config)
+		cfg.only = key.source
+
+		return newMarshaler(cfg, key.typ)
+	})
+	if err != nil {
+		return err
+	}
+
+	return vm.marshal(marshalContext{}, val, v)
+}
+
+// Merge selects how ApplyToRequest combines the marshaled values with the
+// existing values of the same key.
+type Merge int
+
+const (
+	// MergeReplace replaces the existing values.
+	MergeReplace Merge = iota
+
+	// MergeAppend adds the values after the existing ones.
+	MergeAppend
+
+	// MergeKeep only sets the keys that are not present yet.
+	MergeKeep
+)
+
+func (merge Merge) apply(v map[string][]string, key string, vals []string) {
+	switch merge {
+	case MergeAppend:
+		v[key] = append(v[key], vals...)
+	case MergeKeep:
+		if _, ok := v[key]; !ok {
+			v[key] = vals
+		}
+	default:
+		v[key] = vals
+	}
+}
+
+// sourceMarshaler writes its field into the map of its source instead, so a
+// request is marshaled in a single pass. The fields are only wrapped when their
+// source differs from their parent, as the nested fields inherit it.
+type sourceMarshaler struct {
+	source string
+	elem   marshaler
+}
+
+func (m *sourceMarshaler) marshal(ctx marshalContext, src reflect.Value, _ map[string][]string) error {
+	out := ctx.sources[m.source]
+	if out == nil {
+		out = make(map[string][]string)
+		ctx.sources[m.source] = out
+	}
+
+	return m.elem.marshal(ctx, src, out)
+}
+
+// marshalRequest marshals the query, header and cookie fields of src into the
+// maps keyed by their source. The other fields are skipped.
+func (m *Marshaler) marshalRequest(src any) (map[string]map[string][]string, error) {
+	val := reflect.ValueOf(src)
+
+	// The request marshaler is cached under the empty source.
+	vm, err := m.sources.Get(sourceKey{typ: val.Type()}, func(key sourceKey) (marshaler, error) {
+		cfg := newMarshalConfig(m.config)
+		cfg.request = true
+
+		return newMarshaler(cfg, key.typ)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query := make(map[string][]string)
+	ctx := marshalContext{sources: map[string]map[string][]string{sourceQuery: query}}
+
+	if err := vm.marshal(ctx, val, query); err != nil {
+		return nil, err
+	}
+
+	return ctx.sources, nil
+}
+
+// applyRequest sets the query, header and cookie fields of src into the
+// request, combining them with the existing values of the same keys. The
+// request is left untouched when src cannot be marshaled.
+func (m *Marshaler) applyRequest(r *http.Request, src any, merge Merge) error {
+	sources, err := m.marshalRequest(src)
+	if err != nil {
+		return err
+	}
+
+	if query := sources[sourceQuery]; len(query) > 0 {
+		q := r.URL.Query()
+		for key, vals := range query {
+			merge.apply(q, key, vals)
+		}
+
+		r.URL.RawQuery = q.Encode()
+	}
+
+	header := sources[sourceHeader]
+
+	if len(header) > 0 && r.Header == nil {
+		r.Header = make(http.Header)
+	}
+
+	for key, vals := range header {
+		merge.apply(r.Header, http.CanonicalHeaderKey(key), vals)
+	}
+
+	if cookies := sources[sourceCookie]; len(cookies) > 0 {
+		applyCookies(r, cookies, merge)
+	}
+
+	return nil
+}
+
+func applyCookies(r *http.Request, cookies map[string][]string, merge Merge) {
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+
+	existing := r.Cookies()
+
+	if merge == MergeReplace {
+		r.Header.Del("Cookie")
+
+		for _, c := range existing {
+			if _, ok := cookies[c.Name]; !ok {
+				r.AddCookie(c)
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cookies)) {
+		if merge == MergeKeep && slices.ContainsFunc(existing, func(c *http.Cookie) bool { return c.Name == name }) {
+			continue
+		}
+
+		for _, val := range cookies[name] {
+			r.AddCookie(&http.Cookie{Name: name, Value: val})
+		}
+	}
+}
+
+// ApplyToRequest marshals the query, header and cookie fields of src into an
+// existing request, combining them with its values of the same keys using
+// merge, e.g. for a client middleware to decorate the requests it did not
+// create. The path and form fields are ignored.
+func (m *Marshaler) ApplyToRequest(r *http.Request, src any, merge Merge) error {
+	return m.applyRequest(r, src, merge)
+}
+
+func ApplyToRequest(r *http.Request, src any, merge Merge) error {
+	return DefaultMarshaler.ApplyToRequest(r, src, merge)
+}
+
+// NewRequest creates a request with the fields of opts marshaled into it. The
+// "path" fields replace their "{name}" wildcards in rawURL, and the "form"
+// fields are sent as an application/x-www-form-urlencoded body. The remaining
+// fields are applied as in Transport.
+func (m *Marshaler) NewRequest(ctx context.Context, method, rawURL string, opts any) (*http.Request, error) {
+	path := make(map[string][]string)
+	if err := m.marshalSource(opts, sourcePath, path); err != nil {
+		return nil, err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(path)) {
+		vals := path[key]
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("key %s: %w", key, errMissingValue)
+		}
+
+		rawURL = strings.ReplaceAll(rawURL, "{"+key+"}", url.PathEscape(vals[0]))
+	}
+
+	form := make(url.Values)
+	if err := m.marshalSource(opts, sourceForm, form); err != nil {
+		return nil, err
+	}
+
+	var (
+		r   *http.Request
+		err error
+	)
+
+	if len(form) > 0 {
+		r, err = http.NewRequestWithContext(ctx, method, rawURL, strings.NewReader(form.Encode()))
+	} else {
+		r, err = http.NewRequestWithContext(ctx, method, rawURL, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(form) > 0 {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if err := m.applyRequest(r, opts, MergeReplace); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func NewRequest(ctx context.Context, method, rawURL string, opts any) (*http.Request, error) {
+	return DefaultMarshaler.NewRequest(ctx, method, rawURL, opts)
+}
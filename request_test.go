@@ -0,0 +1,282 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRequest(t *testing.T) {
+	type Auth struct {
+		Token string `map:"x-auth-token,required"`
+	}
+
+	type updateRequest struct {
+		ID      int    `map:"id,path"`
+		Version int    `map:"version"`
+		Name    string `map:"name,form"`
+		Session string `map:"session,cookie"`
+		Auth    `map:",header"`
+	}
+
+	expected := updateRequest{
+		ID:      42,
+		Version: 3,
+		Name:    "hello",
+		Session: "abc",
+		Auth:    Auth{Token: "secret"},
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/items/42?version=3&name=ignored", strings.NewReader("name=hello"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Auth-Token", "secret")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	r.SetPathValue("id", "42")
+
+	var actual updateRequest
+
+	err := structmap.UnmarshalRequest(r, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	r.Header.Del("X-Auth-Token")
+
+	err = structmap.UnmarshalRequest(r, &actual)
+	assert.ErrorContains(t, err, "X-Auth-Token")
+}
+
+func TestUnmarshalResponse(t *testing.T) {
+	type RateLimit struct {
+		Limit     int `map:"ratelimit-limit"`
+		Remaining int `map:"ratelimit-remaining"`
+	}
+
+	type listResponse struct {
+		ETag       string `map:"etag,required"`
+		TotalCount int    `map:"x-total-count"`
+		RateLimit
+	}
+
+	w := httptest.NewRecorder()
+	w.Header().Set("ETag", `"abc"`)
+	w.Header().Set("X-Total-Count", "120")
+	w.Header().Set("RateLimit-Remaining", "7")
+
+	var actual listResponse
+
+	err := structmap.UnmarshalResponse(w.Result(), &actual)
+	require.NoError(t, err)
+	assert.Equal(t, listResponse{
+		ETag:       `"abc"`,
+		TotalCount: 120,
+		RateLimit:  RateLimit{Remaining: 7},
+	}, actual)
+
+	err = structmap.UnmarshalResponse(httptest.NewRecorder().Result(), &actual)
+	assert.ErrorContains(t, err, "Etag")
+}
+
+func TestUnmarshalURL(t *testing.T) {
+	type callback struct {
+		State       string `map:"state,required"`
+		AccessToken string `map:"access_token,fragment,required"`
+		ExpiresIn   int    `map:"expires_in,fragment"`
+		Session     string `map:"session,cookie"`
+	}
+
+	u, err := url.Parse("https://example.com/cb?state=xyz#access_token=a%2Bb&expires_in=3600")
+	require.NoError(t, err)
+
+	var actual callback
+
+	err = structmap.UnmarshalURL(u, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, callback{State: "xyz", AccessToken: "a+b", ExpiresIn: 3600}, actual)
+
+	u.Fragment = ""
+
+	err = structmap.UnmarshalURL(u, &actual)
+	assert.ErrorContains(t, err, "access_token")
+}
+
+func TestApplyToRequest(t *testing.T) {
+	type decoration struct {
+		Tags    []string `map:"tag"`
+		Trace   string   `map:"x-trace-id,header"`
+		Session string   `map:"session,cookie"`
+		ID      string   `map:"id,path"`
+	}
+
+	src := decoration{Tags: []string{"b"}, Trace: "new", Session: "new", ID: "ignored"}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/items?tag=a&page=2", nil)
+		r.Header.Set("X-Trace-Id", "old")
+		r.AddCookie(&http.Cookie{Name: "session", Value: "old"})
+		r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+		return r
+	}
+
+	cookies := func(r *http.Request) []string {
+		var out []string
+		for _, c := range r.Cookies() {
+			out = append(out, c.String())
+		}
+
+		return out
+	}
+
+	t.Run("WithReplace", func(t *testing.T) {
+		r := newRequest()
+
+		err := structmap.ApplyToRequest(r, src, structmap.MergeReplace)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"tag": {"b"}, "page": {"2"}}, r.URL.Query())
+		assert.Equal(t, []string{"new"}, r.Header.Values("X-Trace-Id"))
+		assert.Equal(t, []string{"theme=dark", "session=new"}, cookies(r))
+		assert.Equal(t, "/items", r.URL.Path)
+	})
+
+	t.Run("WithAppend", func(t *testing.T) {
+		r := newRequest()
+
+		err := structmap.ApplyToRequest(r, src, structmap.MergeAppend)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"tag": {"a", "b"}, "page": {"2"}}, r.URL.Query())
+		assert.Equal(t, []string{"old", "new"}, r.Header.Values("X-Trace-Id"))
+		assert.Equal(t, []string{"session=old", "theme=dark", "session=new"}, cookies(r))
+	})
+
+	t.Run("WithKeep", func(t *testing.T) {
+		r := newRequest()
+
+		err := structmap.ApplyToRequest(r, decoration{Tags: []string{"b"}, Trace: "new", Session: "new"}, structmap.MergeKeep)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"tag": {"a"}, "page": {"2"}}, r.URL.Query())
+		assert.Equal(t, []string{"old"}, r.Header.Values("X-Trace-Id"))
+		assert.Equal(t, []string{"session=old", "theme=dark"}, cookies(r))
+
+		r = httptest.NewRequest(http.MethodGet, "/items", nil)
+
+		err = structmap.ApplyToRequest(r, src, structmap.MergeKeep)
+		require.NoError(t, err)
+		assert.Equal(t, "b", r.URL.Query().Get("tag"))
+		assert.Equal(t, []string{"session=new"}, cookies(r))
+	})
+
+	t.Run("WithError", func(t *testing.T) {
+		type auth struct {
+			Token string `map:"x-token,required"`
+		}
+
+		type testStruct struct {
+			Tags []string `map:"tag"`
+			Auth auth     `map:"auth,header"`
+			ID   string   `map:"id,path,required"`
+		}
+
+		r := newRequest()
+
+		err := structmap.ApplyToRequest(r, testStruct{Tags: []string{"b"}}, structmap.MergeReplace)
+		assert.ErrorContains(t, err, "key auth.x-token")
+
+		// The query is not applied when a later source fails.
+		assert.Equal(t, url.Values{"tag": {"a"}, "page": {"2"}}, r.URL.Query())
+
+		// The path fields are ignored, including their required option.
+		err = structmap.ApplyToRequest(r, testStruct{Auth: auth{Token: "t"}}, structmap.MergeReplace)
+		require.NoError(t, err)
+		assert.Equal(t, "t", r.Header.Get("auth.x-token"))
+	})
+}
+
+func TestNewRequest(t *testing.T) {
+	type Auth struct {
+		Token string `map:"x-auth-token,required"`
+	}
+
+	type updateRequest struct {
+		ID      string `map:"id,path"`
+		Version int    `map:"version"`
+		Name    string `map:"name,form"`
+		Session string `map:"session,cookie"`
+		Auth    `map:",header"`
+	}
+
+	input := updateRequest{
+		ID:      "a/b",
+		Version: 3,
+		Name:    "hello world",
+		Session: "abc",
+		Auth:    Auth{Token: "secret"},
+	}
+
+	r, err := structmap.NewRequest(context.Background(), http.MethodPost, "https://example.com/items/{id}?debug=1", input)
+	require.NoError(t, err)
+	assert.Equal(t, "/items/a%2Fb", r.URL.EscapedPath())
+	assert.Equal(t, "1", r.URL.Query().Get("debug"))
+	assert.Equal(t, "3", r.URL.Query().Get("version"))
+	assert.Equal(t, "secret", r.Header.Get("X-Auth-Token"))
+	assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "name=hello+world", string(body))
+
+	cookie, err := r.Cookie("session")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", cookie.Value)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		r, err := structmap.NewRequest(context.Background(), http.MethodPost, "/items/{id}", input)
+		require.NoError(t, err)
+		r.SetPathValue("id", input.ID)
+
+		var actual updateRequest
+
+		err = structmap.UnmarshalRequest(r, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, input, actual)
+	})
+
+	t.Run("WithMissingRequired", func(t *testing.T) {
+		_, err := structmap.NewRequest(context.Background(), http.MethodGet, "/items/{id}", updateRequest{})
+		assert.ErrorContains(t, err, "x-auth-token")
+	})
+
+	t.Run("WithEmptyPathValue", func(t *testing.T) {
+		type listRequest struct {
+			IDs []string `map:"ids,path"`
+		}
+
+		for _, ids := range [][]string{nil, {}} {
+			_, err := structmap.NewRequest(context.Background(), http.MethodGet, "/items/{ids}", listRequest{IDs: ids})
+			assert.ErrorContains(t, err, "key ids: missing required value")
+		}
+	})
+}
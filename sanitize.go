@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var _ marshaler = (*sanitizeMarshaler)(nil)
+
+var errHeaderValue = errors.New("header value cannot contain CR, LF or NUL")
+
+// sanitizeMarshaler passes every key and values written by its element to the
+// SanitizeFunc before they are written.
+type sanitizeMarshaler struct {
+	path     string
+	sanitize func(key string, values []string) (string, []string, error)
+	elem     marshaler
+}
+
+func (m *sanitizeMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	out := make(map[string][]string)
+
+	if err := m.elem.marshal(ctx, src, out); err != nil {
+		return err
+	}
+
+	// A field may write multiple keys, e.g. with the prefix option, so they
+	// are sanitized in order to always report the same error.
+	for _, key := range slices.Sorted(maps.Keys(out)) {
+		newKey, vals, err := m.sanitize(key, out[key])
+		if err != nil {
+			return &FieldError{Key: key, Field: joinFieldPath(ctx.fieldPath, m.path), Index: -1, Err: err}
+		}
+
+		v[newKey] = vals
+	}
+
+	return nil
+}
+
+// SanitizeHeader is a SanitizeFunc for the HTTP headers. It rejects the keys
+// that are not a valid token and the values with a CR, LF or NUL, which would
+// otherwise allow a header injection.
+func SanitizeHeader(key string, values []string) (string, []string, error) {
+	if key == "" || strings.IndexFunc(key, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+		return "", nil, fmt.Errorf("invalid header key %q", key)
+	}
+
+	for _, val := range values {
+		if strings.ContainsAny(val, "\r\n\x00") {
+			return "", nil, errHeaderValue
+		}
+	}
+
+	return key, values, nil
+}
+
+// isTokenRune reports whether r is a tchar of RFC 9110 section 5.6.2.
+func isTokenRune(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return true
+	}
+
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeFunc(t *testing.T) {
+	type testStruct struct {
+		Name  string            `map:"name"`
+		Extra map[string]string `map:"x-,prefix"`
+	}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{
+		SanitizeFunc: func(key string, values []string) (string, []string, error) {
+			if key == "x-blocked" {
+				return "", nil, errors.New("blocked key")
+			}
+
+			for i, val := range values {
+				values[i] = strings.TrimSpace(val)
+			}
+
+			return strings.TrimPrefix(key, "x-"), values, nil
+		},
+	})
+
+	actual := make(map[string][]string)
+
+	err := m.Marshal(testStruct{Name: " alice ", Extra: map[string]string{"a": " b"}}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"alice"}, "a": {"b"}}, actual)
+
+	err = m.Marshal(testStruct{Extra: map[string]string{"blocked": "b"}}, make(map[string][]string))
+
+	var fieldErr *structmap.FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "x-blocked", fieldErr.Key)
+	assert.Equal(t, "Extra", fieldErr.Field)
+	assert.ErrorContains(t, err, "blocked key")
+}
+
+func TestSanitizeHeader(t *testing.T) {
+	type testHeader struct {
+		Token  string `map:"x-token"`
+		Nested struct {
+			ID string `map:"id"`
+		} `map:"x-nested"`
+		Meta map[string]string `map:"x-meta-,prefix"`
+	}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{
+		Delimiter:     "-",
+		KeyLookupFunc: http.CanonicalHeaderKey,
+		SanitizeFunc:  structmap.SanitizeHeader,
+	})
+
+	input := testHeader{Token: "abc"}
+	input.Nested.ID = "1"
+
+	actual := make(http.Header)
+
+	err := m.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, http.Header{"X-Token": {"abc"}, "X-Nested-Id": {"1"}}, actual)
+
+	input.Nested.ID = "1\r\nSet-Cookie: a=b"
+
+	err = m.Marshal(input, make(http.Header))
+	assert.EqualError(t, err, "key X-Nested-Id: header value cannot contain CR, LF or NUL")
+
+	input.Nested.ID = "1"
+	input.Meta = map[string]string{"bad key": "x"}
+
+	err = m.Marshal(input, make(http.Header))
+	assert.ErrorContains(t, err, `invalid header key "X-Meta-bad key"`)
+}
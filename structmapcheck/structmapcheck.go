@@ -0,0 +1,374 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package structmapcheck defines an analyzer that reports the invalid
+// structmap tags at vet time: unknown or malformed options, duplicate keys
+// and unsupported field types. The options are checked by compiling a stand-in
+// of each field with the structmap runtime itself, so both agree on the rules.
+package structmapcheck
+
+import (
+	"errors"
+	"go/ast"
+	"go/types"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/adzil/structmap"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "structmapcheck",
+	Doc:      "check the structmap struct tags",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var (
+	tagName string
+	options string
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&tagName, "tag", "map", "struct tag to check")
+	Analyzer.Flags.StringVar(&options, "options", "", "comma-separated custom tag options to accept")
+}
+
+var rawReflectType = reflect.TypeOf(structmap.Raw(nil))
+
+// builtinTypes are the named types with a built-in support in the structmap
+// runtime, keyed by their package path and name.
+var builtinTypes = map[string]reflect.Type{
+	"time.Time":        reflect.TypeOf(time.Time{}),
+	"net/mail.Address": reflect.TypeOf(mail.Address{}),
+	"regexp.Regexp":    reflect.TypeOf(regexp.Regexp{}),
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.StructType)(nil)}, func(n ast.Node) {
+		checkStruct(pass, n.(*ast.StructType))
+	})
+
+	return nil, nil
+}
+
+// fieldKey identifies the keys that collide within a struct.
+type fieldKey struct {
+	name   string
+	source string
+}
+
+func checkStruct(pass *analysis.Pass, st *ast.StructType) {
+	seen := make(map[fieldKey]string)
+
+	for _, fld := range st.Fields.List {
+		if fld.Tag == nil {
+			continue
+		}
+
+		tagValue, err := strconv.Unquote(fld.Tag.Value)
+		if err != nil {
+			continue
+		}
+
+		tag, ok := reflect.StructTag(tagValue).Lookup(tagName)
+		if !ok {
+			continue
+		}
+
+		name, opts := structmap.ParseTag(tag)
+		if name == "-" && len(opts) == 0 {
+			continue
+		}
+
+		typ := pass.TypesInfo.TypeOf(fld.Type)
+
+		for _, ident := range fld.Names {
+			if !ident.IsExported() {
+				continue
+			}
+
+			if err := checkField(typ, tag, opts); err != nil {
+				pass.Reportf(ident.Pos(), "invalid %s tag: %s", tagName, err)
+			}
+
+			// The count option reads the key of another field by design.
+			if hasOption(opts, "count") {
+				continue
+			}
+
+			key := fieldKey{name: name, source: fieldSource(opts)}
+			if key.name == "" {
+				key.name = ident.Name
+			}
+
+			if prev, ok := seen[key]; ok {
+				pass.Reportf(ident.Pos(), "duplicate %s key %q, also used by field %s", tagName, key.name, prev)
+
+				continue
+			}
+
+			seen[key] = ident.Name
+		}
+	}
+}
+
+// checkField compiles a struct with a single stand-in field of typ and the tag,
+// returning the error from the structmap runtime.
+func checkField(typ types.Type, tag string, opts []structmap.TagOption) error {
+	// The structured option binds a struct by its own fields, which the
+	// stand-in of the struct does not have.
+	if hasOption(opts, "structured") && hasStruct(typ) {
+		return nil
+	}
+
+	standIn, ok := standInType(typ)
+	if !ok {
+		return nil
+	}
+
+	st := reflect.StructOf([]reflect.StructField{
+		{Name: "F", Type: standIn, Tag: reflect.StructTag(tagName + ":" + strconv.Quote(tag))},
+	})
+
+	cfg := structmap.Config{
+		Marshal:   structmap.MarshalConfig{TagName: tagName},
+		Unmarshal: structmap.UnmarshalConfig{TagName: tagName},
+	}
+
+	// The validators, key funcs and custom options are only registered at
+	// runtime, so any of them is accepted here.
+	for _, opt := range opts {
+		switch opt.Name {
+		case "validate":
+			if cfg.Unmarshal.Validators == nil {
+				cfg.Unmarshal.Validators = make(map[string]structmap.ValidatorFunc)
+			}
+
+			cfg.Unmarshal.Validators[opt.Value] = func(any) error { return nil }
+
+		case "keyfunc":
+			if cfg.Marshal.KeyFuncs == nil {
+				cfg.Marshal.KeyFuncs = make(map[string]func(string) string)
+				cfg.Unmarshal.KeyFuncs = cfg.Marshal.KeyFuncs
+			}
+
+			cfg.Marshal.KeyFuncs[opt.Value] = nil
+		}
+	}
+
+	for _, name := range strings.Split(options, ",") {
+		if name == "" {
+			continue
+		}
+
+		opt := structmap.Option{
+			Name: name,
+			Codec: func(string, reflect.Type) (structmap.Codec, error) {
+				return structmap.Codec{}, nil
+			},
+		}
+
+		cfg.Marshal.Options = append(cfg.Marshal.Options, opt)
+		cfg.Unmarshal.Options = append(cfg.Unmarshal.Options, opt)
+	}
+
+	err := structmap.CheckType(st, cfg)
+	if err == nil {
+		return nil
+	}
+
+	// Drop the context of the stand-in struct from the message.
+	msg := err.Error()
+	if _, rest, ok := strings.Cut(msg, "}: "); ok {
+		msg = rest
+	}
+
+	return errors.New(strings.TrimPrefix(msg, "struct field F: "))
+}
+
+func hasOption(opts []structmap.TagOption, name string) bool {
+	for _, opt := range opts {
+		if opt.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasStruct reports whether typ is a struct, or a pointer or slice of it at
+// any depth.
+func hasStruct(typ types.Type) bool {
+	switch t := typ.Underlying().(type) {
+	case *types.Struct:
+		return true
+	case *types.Pointer:
+		return hasStruct(t.Elem())
+	case *types.Slice:
+		return hasStruct(t.Elem())
+	}
+
+	return false
+}
+
+func fieldSource(opts []structmap.TagOption) string {
+	for _, opt := range opts {
+		switch opt.Name {
+		case "query", "header", "path", "form", "cookie", "fragment":
+			return opt.Name
+		}
+	}
+
+	return ""
+}
+
+// standInType returns a reflect type that compiles the same as typ. It reports
+// false for the types whose support is only known at runtime, e.g. the
+// interfaces and the types that may have a registered codec.
+func standInType(typ types.Type) (reflect.Type, bool) {
+	if hasMethod(typ, "MarshalValue") || hasMethod(typ, "UnmarshalValue") {
+		return rawReflectType, true
+	}
+
+	if hasMethod(typ, "MarshalMap") || hasMethod(typ, "UnmarshalMap") {
+		return nil, false
+	}
+
+	if named, ok := typ.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil {
+			if typ, ok := builtinTypes[obj.Pkg().Path()+"."+obj.Name()]; ok {
+				return typ, true
+			}
+		}
+
+		switch named.Underlying().(type) {
+		case *types.Basic, *types.Struct, *types.Pointer, *types.Slice:
+		default:
+			// A codec may be registered for the named array, map or other
+			// types.
+			return nil, false
+		}
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		return basicType(t)
+
+	case *types.Pointer:
+		elem, ok := standInType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+
+		return reflect.PointerTo(elem), true
+
+	case *types.Slice:
+		elem, ok := standInType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+
+		return reflect.SliceOf(elem), true
+
+	case *types.Array:
+		elem, ok := standInType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+
+		return reflect.ArrayOf(int(t.Len()), elem), true
+
+	case *types.Map:
+		key, ok := standInType(t.Key())
+		if !ok || !key.Comparable() {
+			return nil, false
+		}
+
+		elem, ok := standInType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+
+		return reflect.MapOf(key, elem), true
+
+	case *types.Struct:
+		// The nested struct is checked on its own declaration.
+		return reflect.TypeOf(struct{}{}), true
+
+	case *types.Chan:
+		return reflect.TypeOf(make(chan int)), true
+
+	case *types.Signature:
+		return reflect.TypeOf(func() {}), true
+	}
+
+	return nil, false
+}
+
+var basicTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:          reflect.TypeOf(false),
+	types.Int:           reflect.TypeOf(int(0)),
+	types.Int8:          reflect.TypeOf(int8(0)),
+	types.Int16:         reflect.TypeOf(int16(0)),
+	types.Int32:         reflect.TypeOf(int32(0)),
+	types.Int64:         reflect.TypeOf(int64(0)),
+	types.Uint:          reflect.TypeOf(uint(0)),
+	types.Uint8:         reflect.TypeOf(uint8(0)),
+	types.Uint16:        reflect.TypeOf(uint16(0)),
+	types.Uint32:        reflect.TypeOf(uint32(0)),
+	types.Uint64:        reflect.TypeOf(uint64(0)),
+	types.Uintptr:       reflect.TypeOf(uintptr(0)),
+	types.Float32:       reflect.TypeOf(float32(0)),
+	types.Float64:       reflect.TypeOf(float64(0)),
+	types.Complex64:     reflect.TypeOf(complex64(0)),
+	types.Complex128:    reflect.TypeOf(complex128(0)),
+	types.String:        reflect.TypeOf(""),
+	types.UnsafePointer: reflect.TypeOf(unsafe.Pointer(nil)),
+}
+
+func basicType(t *types.Basic) (reflect.Type, bool) {
+	typ, ok := basicTypes[t.Kind()]
+
+	return typ, ok
+}
+
+func hasMethod(typ types.Type, name string) bool {
+	if _, ok := typ.Underlying().(*types.Interface); ok {
+		return false
+	}
+
+	for _, t := range []types.Type{typ, types.NewPointer(typ)} {
+		if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name); obj != nil {
+			if _, ok := obj.(*types.Func); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package a
+
+import (
+	"regexp"
+	"time"
+)
+
+type Value struct{}
+
+func (v *Value) UnmarshalValue(vals []string) error { return nil }
+
+func (v Value) MarshalValue() ([]string, error) { return nil, nil }
+
+type Inner struct {
+	Name string `map:"name"`
+}
+
+type Query struct {
+	Name     string            `map:"name,required"`
+	Limit    int               `map:"limit,default=10"`
+	Since    time.Time         `map:"since"`
+	Match    *regexp.Regexp    `map:"match,required"`
+	Custom   Value             `map:"custom"`
+	Tags     []string          `map:"tags,comma"`
+	Meta     map[string]string `map:"meta.,prefix"`
+	Inner    Inner             `map:"inner"`
+	Start    time.Time         `map:"start,validate=future_date"`
+	Any      any               `map:"any"`
+	Skipped  func()            `map:"-"`
+	Token    string            `map:"token,header"`
+	TokenQ   string            `map:"token"`
+	NameLen  int               `map:"name,count"`
+	Priority []Inner           `map:"priority,structured"`
+	internal chan int          `map:"internal"`
+
+	Bad       string   `map:"bad,unknownopt"`          // want `invalid map tag: unknown option unknownopt`
+	Required  string   `map:"req,required=yes"`        // want `invalid map tag: option required does not take a value`
+	Default   int      `map:"def,default=ten"`         // want `invalid map tag: invalid default value`
+	Dup       string   `map:"name"`                    // want `duplicate map key "name", also used by field Name`
+	Handler   func()   `map:"handler"`                 // want `invalid map tag: cannot marshal from func`
+	Channels  chan int `map:"channels"`                // want `invalid map tag: cannot marshal from chan`
+	Nested    Inner    `map:"nested,required"`         // want `invalid map tag: cannot set required option for struct`
+	BothOmits string   `map:"both,required,omitempty"` // want `invalid map tag: a field cannot be set as both required and omitempty`
+	Hints     chan int `map:"hints,structured"`        // want `invalid map tag: structured option is only valid for bool, integer, float, string, \[\]byte, struct or slice`
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package structmaptest provides test helpers for types used with structmap.
+package structmaptest
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+)
+
+// RoundTrip marshals src using the default marshaler, unmarshals the result
+// into a new value and asserts that both are equal.
+func RoundTrip[T any](t testing.TB, src T) bool {
+	t.Helper()
+
+	return RoundTripWith(t, &structmap.DefaultMarshaler, &structmap.DefaultUnmarshaler, src)
+}
+
+// RoundTripWith is like RoundTrip but uses the given marshaler and
+// unmarshaler.
+func RoundTripWith[T any](t testing.TB, m *structmap.Marshaler, u *structmap.Unmarshaler, src T) bool {
+	t.Helper()
+
+	v := make(map[string][]string)
+
+	if err := m.Marshal(src, v); err != nil {
+		t.Errorf("cannot marshal %T: %v", src, err)
+
+		return false
+	}
+
+	var dst T
+
+	if err := u.Unmarshal(v, &dst); err != nil {
+		t.Errorf("cannot unmarshal %T from %v: %v", dst, v, err)
+
+		return false
+	}
+
+	return assert.Equal(t, src, dst, "round trip through %v", v)
+}
+
+// Compiles asserts that the type T can be compiled under cfg in both
+// directions.
+func Compiles[T any](t testing.TB, cfg structmap.Config) bool {
+	t.Helper()
+
+	if err := structmap.Check[T](cfg); err != nil {
+		t.Errorf("cannot compile: %v", err)
+
+		return false
+	}
+
+	return true
+}
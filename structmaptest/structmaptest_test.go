@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmaptest_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/adzil/structmap/structmaptest"
+	"github.com/stretchr/testify/assert"
+)
+
+type nestedQuery struct {
+	Tags []string `map:"tags"`
+}
+
+type listQuery struct {
+	Name   string      `map:"name"`
+	Limit  int         `map:"limit"`
+	Nested nestedQuery `map:"nested"`
+}
+
+type lossyQuery struct {
+	Name   string `map:"name"`
+	Hidden string `map:"-"`
+}
+
+type invalidQuery struct {
+	Channel chan int `map:"channel"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Run("WithEqualValues", func(t *testing.T) {
+		structmaptest.RoundTrip(t, listQuery{
+			Name:   "alice",
+			Limit:  10,
+			Nested: nestedQuery{Tags: []string{"a", "b"}},
+		})
+	})
+
+	t.Run("WithLossyValues", func(t *testing.T) {
+		mock := new(testing.T)
+
+		assert.False(t, structmaptest.RoundTrip(mock, lossyQuery{Name: "alice", Hidden: "secret"}))
+		assert.True(t, mock.Failed())
+	})
+
+	t.Run("WithMarshalError", func(t *testing.T) {
+		mock := new(testing.T)
+
+		assert.False(t, structmaptest.RoundTrip(mock, invalidQuery{}))
+		assert.True(t, mock.Failed())
+	})
+}
+
+func TestCompiles(t *testing.T) {
+	structmaptest.Compiles[listQuery](t, structmap.Config{})
+
+	mock := new(testing.T)
+
+	assert.False(t, structmaptest.Compiles[invalidQuery](mock, structmap.Config{}))
+	assert.True(t, mock.Failed())
+}
@@ -0,0 +1,1095 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ marshaler   = (*structuredMarshaler)(nil)
+	_ unmarshaler = (*structuredUnmarshaler)(nil)
+)
+
+var (
+	errInvalidStructured = errors.New("invalid structured field")
+	errStructuredType    = errors.New("structured option is only valid for bool, integer, float, string, []byte, struct or slice")
+)
+
+// Token is an RFC 8941 token, which is serialized without quotes unlike the
+// strings, e.g. the "text/html" of `"text/html";q=1`.
+type Token string
+
+var tokenReflectType = reflect.TypeOf(Token(""))
+
+// sfItem is a parsed item or inner list of an RFC 8941 structured field. The
+// value is one of bool, int64, float64, string, Token, []byte or []sfItem for
+// an inner list.
+type sfItem struct {
+	value  any
+	params []sfParam
+}
+
+type sfParam struct {
+	key   string
+	value any
+}
+
+func (it sfItem) param(key string) (any, bool) {
+	// The last occurrence wins, as in RFC 8941 section 4.2.3.2.
+	for i := len(it.params) - 1; i >= 0; i-- {
+		if it.params[i].key == key {
+			return it.params[i].value, true
+		}
+	}
+
+	return nil, false
+}
+
+// sfParser parses the structured fields following RFC 8941 section 4.2.
+type sfParser struct {
+	s string
+	i int
+}
+
+func (p *sfParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", errInvalidStructured, p.i, fmt.Sprintf(format, args...))
+}
+
+func (p *sfParser) eof() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *sfParser) skipSP() {
+	for !p.eof() && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for !p.eof() && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// parse parses the whole input with fn, allowing only the leading and
+// trailing spaces around it.
+func (p *sfParser) parse(fn func() error) error {
+	p.skipSP()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	p.skipSP()
+
+	if !p.eof() {
+		return p.errorf("unexpected %q", p.s[p.i])
+	}
+
+	return nil
+}
+
+// members parses the comma-separated members of a list or dictionary.
+func (p *sfParser) members(fn func() error) error {
+	for !p.eof() {
+		if err := fn(); err != nil {
+			return err
+		}
+
+		p.skipOWS()
+
+		if p.eof() {
+			return nil
+		}
+
+		if p.s[p.i] != ',' {
+			return p.errorf("expected comma")
+		}
+
+		p.i++
+		p.skipOWS()
+
+		if p.eof() {
+			return p.errorf("trailing comma")
+		}
+	}
+
+	return nil
+}
+
+func (p *sfParser) parseList() ([]sfItem, error) {
+	var items []sfItem
+
+	err := p.members(func() error {
+		item, err := p.parseItemOrInnerList()
+		items = append(items, item)
+
+		return err
+	})
+
+	return items, err
+}
+
+func (p *sfParser) parseDict() (map[string]sfItem, error) {
+	dict := make(map[string]sfItem)
+
+	err := p.members(func() error {
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+
+		var item sfItem
+
+		if !p.eof() && p.s[p.i] == '=' {
+			p.i++
+
+			if item, err = p.parseItemOrInnerList(); err != nil {
+				return err
+			}
+		} else {
+			item.value = true
+
+			if item.params, err = p.parseParams(); err != nil {
+				return err
+			}
+		}
+
+		dict[key] = item
+
+		return nil
+	})
+
+	return dict, err
+}
+
+func (p *sfParser) parseItemOrInnerList() (sfItem, error) {
+	if p.eof() || p.s[p.i] != '(' {
+		return p.parseItem()
+	}
+
+	p.i++
+
+	var inner []sfItem
+
+	for {
+		p.skipSP()
+
+		if p.eof() {
+			return sfItem{}, p.errorf("unterminated inner list")
+		}
+
+		if p.s[p.i] == ')' {
+			p.i++
+
+			params, err := p.parseParams()
+
+			return sfItem{value: inner, params: params}, err
+		}
+
+		item, err := p.parseItem()
+		if err != nil {
+			return sfItem{}, err
+		}
+
+		inner = append(inner, item)
+
+		if !p.eof() && p.s[p.i] != ' ' && p.s[p.i] != ')' {
+			return sfItem{}, p.errorf("expected space or closing parenthesis")
+		}
+	}
+}
+
+func (p *sfParser) parseItem() (sfItem, error) {
+	value, err := p.parseBareItem()
+	if err != nil {
+		return sfItem{}, err
+	}
+
+	params, err := p.parseParams()
+
+	return sfItem{value: value, params: params}, err
+}
+
+func (p *sfParser) parseParams() ([]sfParam, error) {
+	var params []sfParam
+
+	for !p.eof() && p.s[p.i] == ';' {
+		p.i++
+		p.skipSP()
+
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		var value any = true
+
+		if !p.eof() && p.s[p.i] == '=' {
+			p.i++
+
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+
+		params = append(params, sfParam{key: key, value: value})
+	}
+
+	return params, nil
+}
+
+func (p *sfParser) parseKey() (string, error) {
+	start := p.i
+
+	if p.eof() || !(isLowerAlpha(p.s[p.i]) || p.s[p.i] == '*') {
+		return "", p.errorf("invalid key")
+	}
+
+	for !p.eof() && isKeyChar(p.s[p.i]) {
+		p.i++
+	}
+
+	return p.s[start:p.i], nil
+}
+
+func (p *sfParser) parseBareItem() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("missing item")
+	}
+
+	switch c := p.s[p.i]; {
+	case c == '-' || isDigitByte(c):
+		return p.parseNumber()
+	case c == '"':
+		return p.parseString()
+	case c == '*' || isAlphaByte(c):
+		return p.parseToken(), nil
+	case c == ':':
+		return p.parseBytes()
+	case c == '?':
+		return p.parseBool()
+	}
+
+	return nil, p.errorf("unexpected %q", p.s[p.i])
+}
+
+func (p *sfParser) parseNumber() (any, error) {
+	start := p.i
+
+	if p.s[p.i] == '-' {
+		p.i++
+	}
+
+	intStart := p.i
+
+	for !p.eof() && isDigitByte(p.s[p.i]) {
+		p.i++
+	}
+
+	intLen := p.i - intStart
+
+	if p.eof() || p.s[p.i] != '.' {
+		if intLen < 1 || intLen > 15 {
+			return nil, p.errorf("invalid integer %q", p.s[start:p.i])
+		}
+
+		return strconv.ParseInt(p.s[start:p.i], 10, 64)
+	}
+
+	p.i++
+	fracStart := p.i
+
+	for !p.eof() && isDigitByte(p.s[p.i]) {
+		p.i++
+	}
+
+	// The decimals have at most 12 integer digits and 3 fraction digits.
+	if fracLen := p.i - fracStart; intLen < 1 || intLen > 12 || fracLen < 1 || fracLen > 3 {
+		return nil, p.errorf("invalid decimal %q", p.s[start:p.i])
+	}
+
+	return strconv.ParseFloat(p.s[start:p.i], 64)
+}
+
+func (p *sfParser) parseString() (any, error) {
+	var sb strings.Builder
+
+	for p.i++; !p.eof(); p.i++ {
+		switch c := p.s[p.i]; {
+		case c == '\\':
+			p.i++
+
+			if p.eof() || (p.s[p.i] != '"' && p.s[p.i] != '\\') {
+				return nil, p.errorf("invalid escape")
+			}
+
+			sb.WriteByte(p.s[p.i])
+		case c == '"':
+			p.i++
+
+			return sb.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return nil, p.errorf("invalid string character %q", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return nil, p.errorf("unterminated string")
+}
+
+func (p *sfParser) parseToken() any {
+	start := p.i
+
+	for p.i++; !p.eof(); p.i++ {
+		if c := p.s[p.i]; !isTokenRune(rune(c)) && c != ':' && c != '/' {
+			break
+		}
+	}
+
+	return Token(p.s[start:p.i])
+}
+
+func (p *sfParser) parseBytes() (any, error) {
+	end := strings.IndexByte(p.s[p.i+1:], ':')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(p.s[p.i+1 : p.i+1+end])
+	if err != nil {
+		return nil, p.errorf("invalid byte sequence")
+	}
+
+	p.i += end + 2
+
+	return b, nil
+}
+
+func (p *sfParser) parseBool() (any, error) {
+	if p.i+1 >= len(p.s) || (p.s[p.i+1] != '0' && p.s[p.i+1] != '1') {
+		return nil, p.errorf("invalid boolean")
+	}
+
+	p.i += 2
+
+	return p.s[p.i-1] == '1', nil
+}
+
+func isLowerAlpha(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isAlphaByte(c byte) bool {
+	return isLowerAlpha(c) || ('A' <= c && c <= 'Z')
+}
+
+func isDigitByte(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isKeyChar(c byte) bool {
+	return isLowerAlpha(c) || isDigitByte(c) || strings.IndexByte("_-.*", c) >= 0
+}
+
+// formatKey formats a dictionary or parameter key of RFC 8941 section 4.1.1.3.
+func formatKey(sb *strings.Builder, key string) error {
+	if key == "" || !(isLowerAlpha(key[0]) || key[0] == '*') {
+		return fmt.Errorf("%w key %q", errInvalidStructured, key)
+	}
+
+	for i := 0; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return fmt.Errorf("%w key %q", errInvalidStructured, key)
+		}
+	}
+
+	sb.WriteString(key)
+
+	return nil
+}
+
+// formatBareItem formats a bare item of RFC 8941 section 4.1.3.1.
+func formatBareItem(sb *strings.Builder, value any) error {
+	switch val := value.(type) {
+	case bool:
+		if val {
+			sb.WriteString("?1")
+		} else {
+			sb.WriteString("?0")
+		}
+
+	case int64:
+		if val > 999_999_999_999_999 || val < -999_999_999_999_999 {
+			return fmt.Errorf("%w integer %d: out of range", errInvalidStructured, val)
+		}
+
+		sb.WriteString(strconv.FormatInt(val, 10))
+
+	case float64:
+		val = math.RoundToEven(val*1000) / 1000
+		if math.IsNaN(val) || math.Abs(val) >= 1e12 {
+			return fmt.Errorf("%w decimal %v: out of range", errInvalidStructured, val)
+		}
+
+		s := strconv.FormatFloat(val, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+
+		sb.WriteString(s)
+
+	case string:
+		sb.WriteByte('"')
+
+		for i := 0; i < len(val); i++ {
+			c := val[i]
+			if c < 0x20 || c > 0x7e {
+				return fmt.Errorf("%w string %q: invalid character", errInvalidStructured, val)
+			}
+
+			if c == '"' || c == '\\' {
+				sb.WriteByte('\\')
+			}
+
+			sb.WriteByte(c)
+		}
+
+		sb.WriteByte('"')
+
+	case Token:
+		if val == "" || !(val[0] == '*' || isAlphaByte(val[0])) ||
+			strings.IndexFunc(string(val), func(r rune) bool { return !isTokenRune(r) && r != ':' && r != '/' }) >= 0 {
+			return fmt.Errorf("%w token %q", errInvalidStructured, val)
+		}
+
+		sb.WriteString(string(val))
+
+	case []byte:
+		sb.WriteString(":" + base64.StdEncoding.EncodeToString(val) + ":")
+	}
+
+	return nil
+}
+
+func formatParams(sb *strings.Builder, params []sfParam) error {
+	for _, param := range params {
+		sb.WriteByte(';')
+
+		if err := formatKey(sb, param.key); err != nil {
+			return err
+		}
+
+		if param.value == true {
+			continue
+		}
+
+		sb.WriteByte('=')
+
+		if err := formatBareItem(sb, param.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatItemOrInnerList(sb *strings.Builder, item sfItem) error {
+	inner, ok := item.value.([]sfItem)
+	if !ok {
+		if err := formatBareItem(sb, item.value); err != nil {
+			return err
+		}
+
+		return formatParams(sb, item.params)
+	}
+
+	sb.WriteByte('(')
+
+	for i, it := range inner {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+
+		if err := formatItemOrInnerList(sb, it); err != nil {
+			return err
+		}
+	}
+
+	sb.WriteByte(')')
+
+	return formatParams(sb, item.params)
+}
+
+// sfShape is how a Go type is bound to a structured field.
+type sfShape int
+
+const (
+	// sfBare is a bool, integer, float, string or []byte bound to an item
+	// without its parameters.
+	sfBare sfShape = iota
+
+	// sfParamItem is a struct bound to an item with its parameters, where the
+	// field with the value option holds the item and the other fields hold
+	// the parameters.
+	sfParamItem
+
+	// sfInnerList is a slice bound to an inner list.
+	sfInnerList
+
+	// sfList is a slice bound to a list of items or inner lists.
+	sfList
+
+	// sfDict is a struct without the value field bound to a dictionary, where
+	// each field is a member.
+	sfDict
+)
+
+type sfField struct {
+	key   string
+	index int
+	typ   *sfType
+}
+
+// sfType is the compiled binding between a Go type and a structured field.
+type sfType struct {
+	shape  sfShape
+	value  int
+	fields []sfField
+	elem   *sfType
+}
+
+// sfCompiler compiles the sfType of the struct fields by their tag name.
+type sfCompiler struct {
+	tagName   string
+	fieldName func(structFld reflect.StructField) string
+}
+
+func (c sfCompiler) compile(typ reflect.Type) (*sfType, error) {
+	switch {
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8:
+		elem, err := c.compileMember(typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &sfType{shape: sfList, elem: elem}, nil
+
+	case typ.Kind() == reflect.Struct:
+		fields, value, err := c.compileFields(typ)
+		if err != nil {
+			return nil, err
+		}
+
+		if value >= 0 {
+			return c.compileItem(typ)
+		}
+
+		for i := range fields {
+			if fields[i].typ, err = c.compileMember(typ.Field(fields[i].index).Type); err != nil {
+				return nil, fmt.Errorf("struct field %s: %w", typ.Field(fields[i].index).Name, err)
+			}
+		}
+
+		return &sfType{shape: sfDict, fields: fields}, nil
+	}
+
+	return c.compileItem(typ)
+}
+
+// compileMember compiles a list or dictionary member, which is either an item
+// or an inner list.
+func (c sfCompiler) compileMember(typ reflect.Type) (*sfType, error) {
+	if typ.Kind() != reflect.Slice || typ.Elem().Kind() == reflect.Uint8 {
+		return c.compileItem(typ)
+	}
+
+	elem, err := c.compileItem(typ.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	return &sfType{shape: sfInnerList, elem: elem}, nil
+}
+
+func (c sfCompiler) compileItem(typ reflect.Type) (*sfType, error) {
+	if typ.Kind() != reflect.Struct {
+		if !isBareType(typ) {
+			return nil, errStructuredType
+		}
+
+		return &sfType{shape: sfBare}, nil
+	}
+
+	fields, value, err := c.compileFields(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	if value < 0 {
+		return nil, errors.New("structured item struct must have a value field")
+	}
+
+	for _, fld := range append(fields, sfField{index: value}) {
+		if structFld := typ.Field(fld.index); !isBareType(structFld.Type) {
+			return nil, fmt.Errorf("struct field %s: %w", structFld.Name, errStructuredType)
+		}
+	}
+
+	return &sfType{shape: sfParamItem, value: value, fields: fields}, nil
+}
+
+// compileFields returns the keyed fields of a struct and the index of its
+// field with the value option, or -1 when there is none.
+func (c sfCompiler) compileFields(typ reflect.Type) ([]sfField, int, error) {
+	var fields []sfField
+
+	value := -1
+
+	for i := 0; i < typ.NumField(); i++ {
+		structFld := typ.Field(i)
+		if !structFld.IsExported() {
+			continue
+		}
+
+		name, opts := ParseTag(structFld.Tag.Get(c.tagName))
+		if name == "-" && len(opts) == 0 {
+			continue
+		}
+
+		if hasTagOption(opts, "value") {
+			if value >= 0 {
+				return nil, -1, errors.New("structured struct cannot have more than one value field")
+			}
+
+			value = i
+
+			continue
+		}
+
+		if name == "" {
+			name = c.fieldName(structFld)
+		}
+
+		fields = append(fields, sfField{key: strings.ToLower(name), index: i})
+	}
+
+	return fields, value, nil
+}
+
+func hasTagOption(opts []TagOption, name string) bool {
+	for _, opt := range opts {
+		if opt.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isBareType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+func bareValue(src reflect.Value) any {
+	switch src.Kind() {
+	case reflect.Bool:
+		return src.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return src.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// The values above the integer range are rejected on format anyway.
+		return int64(min(src.Uint(), math.MaxInt64))
+	case reflect.Float32, reflect.Float64:
+		return src.Float()
+	case reflect.String:
+		if src.Type() == tokenReflectType {
+			return Token(src.String())
+		}
+
+		return src.String()
+	}
+
+	return src.Bytes()
+}
+
+func (t *sfType) item(src reflect.Value) sfItem {
+	switch t.shape {
+	case sfParamItem:
+		item := sfItem{value: bareValue(src.Field(t.value))}
+
+		for _, fld := range t.fields {
+			if val := src.Field(fld.index); !val.IsZero() {
+				item.params = append(item.params, sfParam{key: fld.key, value: bareValue(val)})
+			}
+		}
+
+		return item
+
+	case sfInnerList:
+		inner := make([]sfItem, src.Len())
+		for i := range inner {
+			inner[i] = t.elem.item(src.Index(i))
+		}
+
+		return sfItem{value: inner}
+	}
+
+	return sfItem{value: bareValue(src)}
+}
+
+// format formats src, reporting false when there is nothing to write, i.e. an
+// empty list or dictionary.
+func (t *sfType) format(src reflect.Value) (string, bool, error) {
+	var sb strings.Builder
+
+	switch t.shape {
+	case sfList:
+		for i := 0; i < src.Len(); i++ {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+
+			if err := formatItemOrInnerList(&sb, t.elem.item(src.Index(i))); err != nil {
+				return "", false, fmt.Errorf("list index #%d: %w", i, err)
+			}
+		}
+
+	case sfDict:
+		// The zero members are omitted, as there is no way to tell them apart
+		// from the missing ones.
+		for _, fld := range t.fields {
+			val := src.Field(fld.index)
+			if val.IsZero() {
+				continue
+			}
+
+			if sb.Len() > 0 {
+				sb.WriteString(", ")
+			}
+
+			if err := formatMember(&sb, fld.key, fld.typ.item(val)); err != nil {
+				return "", false, fmt.Errorf("member %s: %w", fld.key, err)
+			}
+		}
+
+	default:
+		if err := formatItemOrInnerList(&sb, t.item(src)); err != nil {
+			return "", false, err
+		}
+	}
+
+	return sb.String(), sb.Len() > 0, nil
+}
+
+func formatMember(sb *strings.Builder, key string, item sfItem) error {
+	if err := formatKey(sb, key); err != nil {
+		return err
+	}
+
+	if item.value == true {
+		return formatParams(sb, item.params)
+	}
+
+	sb.WriteByte('=')
+
+	return formatItemOrInnerList(sb, item)
+}
+
+func sfTypeName(value any) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "decimal"
+	case string:
+		return "string"
+	case Token:
+		return "token"
+	case []byte:
+		return "byte sequence"
+	}
+
+	return "inner list"
+}
+
+func setBareValue(value any, dst reflect.Value) error {
+	ok := false
+
+	switch dst.Kind() {
+	case reflect.Bool:
+		var b bool
+		if b, ok = value.(bool); ok {
+			dst.SetBool(b)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, ok = value.(int64); ok {
+			if dst.OverflowInt(n) {
+				return fmt.Errorf("%w integer %d: overflows %s", errInvalidStructured, n, dst.Type())
+			}
+
+			dst.SetInt(n)
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n int64
+		if n, ok = value.(int64); ok {
+			if n < 0 || dst.OverflowUint(uint64(n)) {
+				return fmt.Errorf("%w integer %d: overflows %s", errInvalidStructured, n, dst.Type())
+			}
+
+			dst.SetUint(uint64(n))
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch n := value.(type) {
+		case float64:
+			dst.SetFloat(n)
+
+			ok = true
+		case int64:
+			dst.SetFloat(float64(n))
+
+			ok = true
+		}
+
+	case reflect.String:
+		switch s := value.(type) {
+		case string:
+			// A token field does not accept a string, as they are serialized
+			// differently.
+			if dst.Type() != tokenReflectType {
+				dst.SetString(s)
+
+				ok = true
+			}
+		case Token:
+			dst.SetString(string(s))
+
+			ok = true
+		}
+
+	case reflect.Slice:
+		var b []byte
+		if b, ok = value.([]byte); ok {
+			dst.SetBytes(b)
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: cannot use %s as %s", errInvalidStructured, sfTypeName(value), dst.Type())
+	}
+
+	return nil
+}
+
+func (t *sfType) setItem(item sfItem, dst reflect.Value) error {
+	switch t.shape {
+	case sfParamItem:
+		if err := setBareValue(item.value, dst.Field(t.value)); err != nil {
+			return err
+		}
+
+		for _, fld := range t.fields {
+			if val, ok := item.param(fld.key); ok {
+				if err := setBareValue(val, dst.Field(fld.index)); err != nil {
+					return fmt.Errorf("parameter %s: %w", fld.key, err)
+				}
+			}
+		}
+
+		return nil
+
+	case sfInnerList:
+		inner, ok := item.value.([]sfItem)
+		if !ok {
+			return fmt.Errorf("%w: cannot use %s as %s", errInvalidStructured, sfTypeName(item.value), dst.Type())
+		}
+
+		out := reflect.MakeSlice(dst.Type(), len(inner), len(inner))
+
+		for i, it := range inner {
+			if err := t.elem.setItem(it, out.Index(i)); err != nil {
+				return fmt.Errorf("inner list index #%d: %w", i, err)
+			}
+		}
+
+		dst.Set(out)
+
+		return nil
+	}
+
+	return setBareValue(item.value, dst)
+}
+
+// parse parses the structured field s into dst, which is reset first.
+func (t *sfType) parse(s string, dst reflect.Value) error {
+	p := &sfParser{s: s}
+
+	dst.Set(reflect.Zero(dst.Type()))
+
+	switch t.shape {
+	case sfList:
+		var items []sfItem
+
+		err := p.parse(func() (err error) {
+			items, err = p.parseList()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		out := reflect.MakeSlice(dst.Type(), len(items), len(items))
+
+		for i, item := range items {
+			if err := t.elem.setItem(item, out.Index(i)); err != nil {
+				return fmt.Errorf("list index #%d: %w", i, err)
+			}
+		}
+
+		dst.Set(out)
+
+		return nil
+
+	case sfDict:
+		var dict map[string]sfItem
+
+		err := p.parse(func() (err error) {
+			dict, err = p.parseDict()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, fld := range t.fields {
+			if item, ok := dict[fld.key]; ok {
+				if err := fld.typ.setItem(item, dst.Field(fld.index)); err != nil {
+					return fmt.Errorf("member %s: %w", fld.key, err)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	var item sfItem
+
+	err := p.parse(func() (err error) {
+		item, err = p.parseItem()
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.setItem(item, dst)
+}
+
+// structuredMarshaler formats a value into an RFC 8941 structured field. See
+// sfShape for how the Go types are bound to the items, lists and
+// dictionaries.
+type structuredMarshaler struct {
+	keyMarshaler
+	sf *sfType
+}
+
+func (m *structuredMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if m.sf.shape != sfList && m.sf.shape != sfDict && src.IsZero() {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		if m.omitEmpty {
+			return nil
+		}
+	}
+
+	val, ok, err := m.sf.format(src)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", ctx.key(m.key), err)
+	}
+
+	if !ok {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		return nil
+	}
+
+	ctx.set(v, m.key, val)
+
+	return nil
+}
+
+func newStructuredMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, error) {
+	sf, err := sfCompiler{tagName: cfg.tagName(), fieldName: cfg.fieldName}.compile(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &structuredMarshaler{
+		keyMarshaler: newKeyMarshaler(cfg),
+		sf:           sf,
+	}, nil
+}
+
+// structuredUnmarshaler parses an RFC 8941 structured field into a value. The
+// multiple values of a list or dictionary are combined as in RFC 8941 section
+// 4.2.
+type structuredUnmarshaler struct {
+	sf *sfType
+}
+
+func (u *structuredUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	return u.sf.parse(strings.Join(ctx.value, ", "), dst)
+}
+
+func newStructuredUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	sf, err := sfCompiler{tagName: cfg.tagName(), fieldName: cfg.fieldName}.compile(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &structuredUnmarshaler{sf: sf}, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredOption(t *testing.T) {
+	type Priority struct {
+		Urgency     int  `map:"u"`
+		Incremental bool `map:"i"`
+	}
+
+	type Brand struct {
+		Name    string `map:",value"`
+		Version string `map:"v"`
+	}
+
+	type Item struct {
+		Value structmap.Token `map:",value"`
+		Q     float64         `map:"q"`
+		Final bool            `map:"final"`
+	}
+
+	type Dict struct {
+		Tokens []structmap.Token `map:"tokens"`
+		Digest []byte            `map:"sha-256"`
+		Item   Item              `map:"item"`
+		Flag   bool              `map:"flag"`
+	}
+
+	type testHeader struct {
+		Priority Priority `map:"priority,structured"`
+		UA       []Brand  `map:"sec-ch-ua,structured"`
+		Mobile   bool     `map:"sec-ch-ua-mobile,structured"`
+		Platform *string  `map:"sec-ch-ua-platform,structured"`
+		Dict     Dict     `map:"example-dict,structured"`
+		Items    []Item   `map:"example-list,structured"`
+		Ratio    float64  `map:"example-ratio,structured,omitempty"`
+	}
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		input := http.Header{
+			"Priority":           {"u=5, i"},
+			"Sec-Ch-Ua":          {`"Chromium";v="118", "Not=A?Brand";v="24"`},
+			"Sec-Ch-Ua-Mobile":   {"?1"},
+			"Sec-Ch-Ua-Platform": {`"Windows"`},
+			"Example-Dict":       {`tokens=(a b/c *d), sha-256=:aGVsbG8=:`, `item=gzip;q=0.5;final, flag;x=1`},
+			"Example-List":       {"br;q=1;q=0.25, identity"},
+			"Example-Ratio":      {"-12.5"},
+		}
+
+		var actual testHeader
+
+		err := structmap.UnmarshalHeader(input, &actual)
+		require.NoError(t, err)
+
+		platform := "Windows"
+
+		assert.Equal(t, testHeader{
+			Priority: Priority{Urgency: 5, Incremental: true},
+			UA:       []Brand{{Name: "Chromium", Version: "118"}, {Name: "Not=A?Brand", Version: "24"}},
+			Mobile:   true,
+			Platform: &platform,
+			Dict: Dict{
+				Tokens: []structmap.Token{"a", "b/c", "*d"},
+				Digest: []byte("hello"),
+				Item:   Item{Value: "gzip", Q: 0.5, Final: true},
+				Flag:   true,
+			},
+			Items: []Item{{Value: "br", Q: 0.25}, {Value: "identity"}},
+			Ratio: -12.5,
+		}, actual)
+	})
+
+	t.Run("UnmarshalInvalid", func(t *testing.T) {
+		for key, val := range map[string]string{
+			"Priority":         "u=5,",
+			"Sec-Ch-Ua":        `"Chromium";v=118`,
+			"Sec-Ch-Ua-Mobile": "1",
+			"Example-Dict":     "tokens=a",
+			"Example-List":     `"br"`,
+			"Example-Ratio":    "1.2345",
+		} {
+			var actual testHeader
+
+			err := structmap.UnmarshalHeader(http.Header{key: {val}}, &actual)
+			assert.ErrorContains(t, err, "key "+key+": ", key)
+			assert.ErrorContains(t, err, "invalid structured field", key)
+		}
+
+		var actual struct {
+			Urgency int8 `map:"priority,structured"`
+		}
+
+		err := structmap.UnmarshalHeader(http.Header{"Priority": {"1000"}}, &actual)
+		assert.ErrorContains(t, err, "overflows int8")
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		input := testHeader{
+			Priority: Priority{Urgency: 1, Incremental: true},
+			UA:       []Brand{{Name: "Chromium", Version: "118"}, {Name: `Not "A" Brand`}},
+			Dict: Dict{
+				Tokens: []structmap.Token{"a", "b"},
+				Digest: []byte("hello"),
+				Item:   Item{Value: "gzip", Q: 0.12345, Final: true},
+			},
+			Items: []Item{{Value: "br", Q: 1}},
+		}
+
+		actual := make(http.Header)
+
+		err := structmap.MarshalHeader(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, http.Header{
+			"Priority":         {"u=1, i"},
+			"Sec-Ch-Ua":        {`"Chromium";v="118", "Not \"A\" Brand"`},
+			"Sec-Ch-Ua-Mobile": {"?0"},
+			"Example-Dict":     {"tokens=(a b), sha-256=:aGVsbG8=:, item=gzip;q=0.123;final"},
+			"Example-List":     {"br;q=1.0"},
+		}, actual)
+
+		var output testHeader
+
+		err = structmap.UnmarshalHeader(actual, &output)
+		require.NoError(t, err)
+		assert.Equal(t, Priority{Urgency: 1, Incremental: true}, output.Priority)
+		assert.Equal(t, input.UA, output.UA)
+
+		err = structmap.MarshalHeader(testHeader{Items: []Item{{Value: "not a token"}}}, actual)
+		assert.ErrorContains(t, err, `key Example-List: list index #0: invalid structured field token "not a token"`)
+
+		err = structmap.MarshalHeader(testHeader{Priority: Priority{Urgency: 1e16}}, actual)
+		assert.ErrorContains(t, err, "key Priority: member u: invalid structured field integer")
+	})
+
+	t.Run("WithInvalidType", func(t *testing.T) {
+		var actual struct {
+			Value map[string]string `map:"value,structured"`
+		}
+
+		err := structmap.UnmarshalHeader(nil, &actual)
+		assert.ErrorContains(t, err, "structured option is only valid for")
+
+		var nested struct {
+			Value struct {
+				Item struct {
+					Name string `map:"name"`
+				} `map:"item"`
+			} `map:"value,structured"`
+		}
+
+		err = structmap.MarshalHeader(nested, make(http.Header))
+		assert.ErrorContains(t, err, "structured item struct must have a value field")
+	})
+}
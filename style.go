@@ -0,0 +1,140 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParamStyle is an OpenAPI 3 parameter serialization style, which can be set
+// in the config or per field using the "style" and "explode" options, e.g.
+// `map:"ids,style=pipeDelimited"`.
+type ParamStyle string
+
+const (
+	StyleForm           ParamStyle = "form"
+	StyleSpaceDelimited ParamStyle = "spaceDelimited"
+	StylePipeDelimited  ParamStyle = "pipeDelimited"
+	StyleDeepObject     ParamStyle = "deepObject"
+)
+
+// Explode overrides the default explode of a ParamStyle, which is true for
+// the form and deepObject styles and false otherwise.
+type Explode int
+
+const (
+	ExplodeDefault Explode = iota
+	ExplodeTrue
+	ExplodeFalse
+)
+
+type paramStyle struct {
+	slice   SliceStyle
+	sep     string
+	joinKey func(names []string) string
+}
+
+func (s ParamStyle) resolve(explode Explode) (paramStyle, error) {
+	var sep string
+
+	switch s {
+	case StyleForm:
+		if explode != ExplodeFalse {
+			return paramStyle{slice: SliceRepeated}, nil
+		}
+
+		sep = ","
+
+	case StyleSpaceDelimited:
+		sep = " "
+
+	case StylePipeDelimited:
+		sep = "|"
+
+	case StyleDeepObject:
+		if explode == ExplodeFalse {
+			return paramStyle{}, fmt.Errorf("style %s must be exploded", s)
+		}
+
+		return paramStyle{slice: SliceRepeated, joinKey: joinBracketKey}, nil
+
+	default:
+		return paramStyle{}, fmt.Errorf("unknown style %s", s)
+	}
+
+	if explode == ExplodeTrue {
+		return paramStyle{slice: SliceRepeated}, nil
+	}
+
+	return paramStyle{slice: SliceJoined, sep: sep}, nil
+}
+
+func parseExplode(val string) (Explode, error) {
+	if val == "" {
+		return ExplodeTrue, nil
+	}
+
+	explode, err := strconv.ParseBool(val)
+	if err != nil {
+		return ExplodeDefault, fmt.Errorf("option explode: %w", err)
+	}
+
+	if explode {
+		return ExplodeTrue, nil
+	}
+
+	return ExplodeFalse, nil
+}
+
+func (c *MarshalConfig) applyStyle() error {
+	if c.Style == "" {
+		return nil
+	}
+
+	style, err := c.Style.resolve(c.Explode)
+	if err != nil {
+		return err
+	}
+
+	c.SliceStyle, c.SliceSeparator = style.slice, style.sep
+
+	if style.joinKey != nil {
+		c.JoinKeyFunc = style.joinKey
+	}
+
+	return nil
+}
+
+func (cfg *UnmarshalConfig) applyStyle() error {
+	if cfg.Style == "" {
+		return nil
+	}
+
+	style, err := cfg.Style.resolve(cfg.Explode)
+	if err != nil {
+		return err
+	}
+
+	cfg.SliceStyle, cfg.SliceSeparator = style.slice, style.sep
+
+	if style.joinKey != nil {
+		cfg.JoinKeyFunc = style.joinKey
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamStyle(t *testing.T) {
+	type Filter struct {
+		Color string `map:"color"`
+		Size  int    `map:"size"`
+	}
+
+	type testStruct struct {
+		Form      []string `map:"form,style=form"`
+		FormFlat  []string `map:"flat,style=form,explode=false"`
+		Space     []string `map:"space,style=spaceDelimited"`
+		Pipe      []int    `map:"pipe,style=pipeDelimited"`
+		PipeMulti []int    `map:"multi,style=pipeDelimited,explode"`
+		Filter    Filter   `map:"filter,style=deepObject"`
+	}
+
+	input := testStruct{
+		Form:      []string{"a", "b"},
+		FormFlat:  []string{"a", "b"},
+		Space:     []string{"a", "b"},
+		Pipe:      []int{1, 2},
+		PipeMulti: []int{1, 2},
+		Filter:    Filter{Color: "red", Size: 2},
+	}
+
+	expected := map[string][]string{
+		"form":          {"a", "b"},
+		"flat":          {"a,b"},
+		"space":         {"a b"},
+		"pipe":          {"1|2"},
+		"multi":         {"1", "2"},
+		"filter[color]": {"red"},
+		"filter[size]":  {"2"},
+	}
+
+	actual := make(map[string][]string)
+
+	err := structmap.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	var output testStruct
+
+	err = structmap.Unmarshal(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	t.Run("WithConfig", func(t *testing.T) {
+		type testStruct struct {
+			IDs  []int    `map:"ids"`
+			Tags []string `map:"tags,style=form"`
+		}
+
+		cfg := structmap.Config{
+			Marshal:   structmap.MarshalConfig{Style: structmap.StyleForm, Explode: structmap.ExplodeFalse},
+			Unmarshal: structmap.UnmarshalConfig{Style: structmap.StyleForm, Explode: structmap.ExplodeFalse},
+		}
+
+		schema := structmap.MustCompile[testStruct](cfg)
+
+		input := testStruct{IDs: []int{1, 2}, Tags: []string{"a", "b"}}
+
+		actual := make(map[string][]string)
+		err := schema.Marshal(input, actual)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"ids": {"1,2"}, "tags": {"a,b"}}, actual)
+
+		var output testStruct
+
+		err = schema.Unmarshal(actual, &output)
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+	})
+
+	t.Run("WithInvalidStyle", func(t *testing.T) {
+		var invalid struct {
+			Filter Filter `map:"filter,style=deepObject,explode=false"`
+		}
+
+		err := structmap.Unmarshal(nil, &invalid)
+		assert.ErrorContains(t, err, "style deepObject must be exploded")
+
+		var unknown struct {
+			IDs []int `map:"ids,style=matrix"`
+		}
+
+		err = structmap.Marshal(unknown, make(map[string][]string))
+		assert.ErrorContains(t, err, "unknown style matrix")
+	})
+}
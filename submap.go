@@ -0,0 +1,153 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"reflect"
+	"strings"
+)
+
+var (
+	_ marshaler   = (*mapMethodMarshaler)(nil)
+	_ unmarshaler = (*mapMethodUnmarshaler)(nil)
+	_ keyMatcher  = (*mapMethodUnmarshaler)(nil)
+)
+
+var (
+	mapMarshalerReflectType   = reflect.TypeOf((*MapMarshaler)(nil)).Elem()
+	mapUnmarshalerReflectType = reflect.TypeOf((*MapUnmarshaler)(nil)).Elem()
+)
+
+// MapMarshaler is implemented by the types whose values span multiple dynamic
+// keys. MarshalMap writes them into v, where prefix is the key of the field
+// (e.g. "filter"), or empty for the top-level value.
+type MapMarshaler interface {
+	MarshalMap(prefix string, v map[string][]string) error
+}
+
+// MapUnmarshaler is the counterpart of MapMarshaler, which receives the whole
+// input map to read the keys under the prefix.
+type MapUnmarshaler interface {
+	UnmarshalMap(prefix string, v map[string][]string) error
+}
+
+// implementsMethod reports whether typ implements iface, and whether it needs
+// a pointer receiver to do so.
+func implementsMethod(typ, iface reflect.Type) (ok, ptrReceiver bool) {
+	switch {
+	case typ.Implements(iface):
+		return true, false
+
+	case reflect.PointerTo(typ).Implements(iface):
+		return true, true
+	}
+
+	return false, false
+}
+
+type mapMethodMarshaler struct {
+	prefix      string
+	delimiter   string
+	ptrReceiver bool
+}
+
+func (m *mapMethodMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if m.ptrReceiver {
+		if !src.CanAddr() {
+			return &UnsupportedValueError{Value: src, msg: "unable to call MarshalMap to an unadressable value"}
+		}
+
+		src = src.Addr()
+	}
+
+	// An element of an indexed slice or a struct map is prefixed without the
+	// trailing delimiter, e.g. "items.0".
+	prefix := ctx.key(m.prefix)
+	if m.prefix == "" {
+		prefix = strings.TrimSuffix(prefix, m.delimiter)
+	}
+
+	return src.Interface().(MapMarshaler).MarshalMap(prefix, v)
+}
+
+func newMapMethodMarshaler(cfg marshalConfig, typ reflect.Type) (marshaler, bool) {
+	ok, ptrReceiver := implementsMethod(typ, mapMarshalerReflectType)
+	if !ok {
+		return nil, false
+	}
+
+	var prefix string
+	if len(cfg.Name) > 0 {
+		prefix = cfg.name()
+	}
+
+	return &mapMethodMarshaler{
+		prefix:      prefix,
+		delimiter:   cfg.delimiter(),
+		ptrReceiver: ptrReceiver,
+	}, true
+}
+
+type mapMethodUnmarshaler struct {
+	prefix      string
+	delimiter   string
+	newFn       func(dst reflect.Value)
+	ptrReceiver bool
+}
+
+func (u *mapMethodUnmarshaler) unmarshal(_ unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	if u.newFn != nil {
+		u.newFn(dst)
+	}
+
+	if u.ptrReceiver {
+		dst = dst.Addr()
+	}
+
+	return dst.Interface().(MapUnmarshaler).UnmarshalMap(u.prefix, v)
+}
+
+// matchKey reports whether key is the prefix itself or nested under it, so the
+// prefix "filter" matches "filter.name" and "filter[0]" but not "filters".
+func (u *mapMethodUnmarshaler) matchKey(key string) bool {
+	if u.prefix == "" {
+		return true
+	}
+
+	rest, ok := strings.CutPrefix(key, u.prefix)
+
+	return ok && (rest == "" || strings.HasPrefix(rest, u.delimiter) || rest[0] == '[')
+}
+
+func newMapMethodUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, bool) {
+	ok, ptrReceiver := implementsMethod(typ, mapUnmarshalerReflectType)
+	if !ok {
+		return nil, false
+	}
+
+	var prefix string
+	if len(cfg.Prefix) > 0 {
+		prefix = cfg.lookupKey(cfg.joinKey(cfg.Prefix))
+	}
+
+	return &mapMethodUnmarshaler{
+		prefix:      prefix,
+		delimiter:   cfg.delimiter(),
+		newFn:       buildNewFunc(typ),
+		ptrReceiver: ptrReceiver,
+	}, true
+}
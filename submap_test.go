@@ -0,0 +1,153 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// boundsFilter is encoded as "<prefix>.<field>.min" and "<prefix>.<field>.max"
+// for any number of fields.
+type boundsFilter map[string][2]int
+
+func (f boundsFilter) MarshalMap(prefix string, v map[string][]string) error {
+	for field, bounds := range f {
+		v[prefix+"."+field+".min"] = []string{strconv.Itoa(bounds[0])}
+		v[prefix+"."+field+".max"] = []string{strconv.Itoa(bounds[1])}
+	}
+
+	return nil
+}
+
+func (f *boundsFilter) UnmarshalMap(prefix string, v map[string][]string) error {
+	for key, vals := range v {
+		rest, ok := strings.CutPrefix(key, prefix+".")
+		if !ok {
+			continue
+		}
+
+		field, bound, ok := strings.Cut(rest, ".")
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.Atoi(vals[0])
+		if err != nil {
+			return err
+		}
+
+		if *f == nil {
+			*f = make(boundsFilter)
+		}
+
+		bounds := (*f)[field]
+
+		switch bound {
+		case "min":
+			bounds[0] = n
+		case "max":
+			bounds[1] = n
+		}
+
+		(*f)[field] = bounds
+	}
+
+	return nil
+}
+
+func TestMapMarshaler(t *testing.T) {
+	type testStruct struct {
+		Query  string       `map:"q"`
+		Ranges boundsFilter `map:"range"`
+	}
+
+	input := testStruct{
+		Query:  "shoes",
+		Ranges: boundsFilter{"price": {10, 50}, "size": {40, 42}},
+	}
+
+	expected := map[string][]string{
+		"q":               {"shoes"},
+		"range.price.min": {"10"},
+		"range.price.max": {"50"},
+		"range.size.min":  {"40"},
+		"range.size.max":  {"42"},
+	}
+
+	actual := make(map[string][]string)
+
+	err := structmap.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	var output testStruct
+
+	err = structmap.Unmarshal(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	actual["range.price.min"] = []string{"x"}
+
+	err = structmap.Unmarshal(actual, &output)
+	assert.ErrorContains(t, err, "invalid syntax")
+
+	var top boundsFilter
+
+	err = structmap.Unmarshal(map[string][]string{".a.max": {"3"}}, &top)
+	require.NoError(t, err)
+	assert.Equal(t, boundsFilter{"a": {0, 3}}, top)
+
+	t.Run("WithFilterKnown", func(t *testing.T) {
+		actual, err := structmap.FilterKnown[testStruct](map[string][]string{
+			"q":               {"shoes"},
+			"range.price.min": {"10"},
+			"ranges.size.min": {"40"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"q":               {"shoes"},
+			"range.price.min": {"10"},
+		}, actual)
+	})
+
+	t.Run("WithUnaddressableValue", func(t *testing.T) {
+		var input struct {
+			Ranges pointerBoundsFilter `map:"range"`
+		}
+
+		var valueErr *structmap.UnsupportedValueError
+
+		err := structmap.Marshal(input, map[string][]string{})
+		require.ErrorAs(t, err, &valueErr)
+		assert.ErrorContains(t, err, "unadressable")
+	})
+}
+
+// pointerBoundsFilter implements MapMarshaler with a pointer receiver.
+type pointerBoundsFilter struct {
+	boundsFilter
+}
+
+func (f *pointerBoundsFilter) MarshalMap(prefix string, v map[string][]string) error {
+	return f.boundsFilter.MarshalMap(prefix, v)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// TagOption is a single option of a struct tag, which is either a bare word
+// (e.g. "required") or a "name=value" pair.
+type TagOption struct {
+	Name  string
+	Value string
+}
+
+// ParseTag splits the struct tag into its key name and options. The options
+// are separated by commas and a value is given after the first equal sign. A
+// backslash escapes the next character, so "default=a\,b" has the "a,b" value.
+// The legacy "default:value" form is also accepted.
+func ParseTag(tag string) (name string, opts []TagOption) {
+	parts := splitEscaped(tag, ',')
+	name = unescapeTag(parts[0])
+
+	for _, part := range parts[1:] {
+		var opt TagOption
+
+		if val, ok := strings.CutPrefix(part, "default:"); ok {
+			opt = TagOption{Name: "default", Value: val}
+		} else if kv := splitEscaped(part, '='); len(kv) > 1 {
+			opt = TagOption{Name: kv[0], Value: strings.Join(kv[1:], "=")}
+		} else {
+			opt = TagOption{Name: part}
+		}
+
+		opt.Name = unescapeTag(opt.Name)
+		opt.Value = unescapeTag(opt.Value)
+		opts = append(opts, opt)
+	}
+
+	return name, opts
+}
+
+// splitEscaped splits s around each sep that is not escaped by a backslash.
+// The escapes are kept as-is in the returned parts.
+func splitEscaped(s string, sep byte) []string {
+	var (
+		parts []string
+		start int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func unescapeTag(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// Option registers a custom tag option, e.g. from an adapter package. When a
+// field has the option, Codec is called with the option value and the field
+// type, and the returned codec takes precedence for the field and its
+// elements.
+type Option struct {
+	Name  string
+	Codec func(value string, typ reflect.Type) (Codec, error)
+}
+
+func findOption(opts []Option, name string) (Option, bool) {
+	for i := len(opts) - 1; i >= 0; i-- {
+		if opts[i].Name == name {
+			return opts[i], true
+		}
+	}
+
+	return Option{}, false
+}
+
+// applyCustomOption returns codecs with the codec of the custom option opt
+// appended into it.
+func applyCustomOption(options []Option, codecs []Codec, opt TagOption, typ reflect.Type) ([]Codec, error) {
+	custom, ok := findOption(options, opt.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown option %s", opt.Name)
+	}
+
+	codec, err := custom.Codec(opt.Value, typ)
+	if err != nil {
+		return nil, fmt.Errorf("option %s: %w", opt.Name, err)
+	}
+
+	return append(slices.Clip(codecs), codec), nil
+}
+
+// redactedValue replaces the values of the "secret" fields in the traces.
+const redactedValue = "[redacted]"
+
+func redactValues(val []string) []string {
+	if val == nil {
+		return nil
+	}
+
+	out := make([]string, len(val))
+	for i := range out {
+		out[i] = redactedValue
+	}
+
+	return out
+}
+
+// redactedError hides the message of an error from a "secret" field, which
+// may contain its value. The original error is still available to errors.As.
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return "invalid value " + redactedValue
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// builtinKeyFuncs lists the key transforms that can always be selected by the
+// "keyfunc" option.
+var builtinKeyFuncs = map[string]func(s string) string{
+	"canonical": http.CanonicalHeaderKey,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"none":      nil,
+}
+
+func findKeyFunc(funcs map[string]func(s string) string, name string) (func(s string) string, error) {
+	if fn, ok := funcs[name]; ok {
+		return fn, nil
+	}
+
+	if fn, ok := builtinKeyFuncs[name]; ok {
+		return fn, nil
+	}
+
+	return nil, fmt.Errorf("unknown key func %s", name)
+}
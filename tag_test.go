@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTag(t *testing.T) {
+	name, opts := structmap.ParseTag(`tags,required,default=a\,b|c,sep=\=,default:x`)
+	assert.Equal(t, "tags", name)
+	assert.Equal(t, []structmap.TagOption{
+		{Name: "required"},
+		{Name: "default", Value: "a,b|c"},
+		{Name: "sep", Value: "="},
+		{Name: "default", Value: "x"},
+	}, opts)
+
+	name, opts = structmap.ParseTag(`a\,b`)
+	assert.Equal(t, "a,b", name)
+	assert.Empty(t, opts)
+}
+
+func TestOption(t *testing.T) {
+	layout := structmap.Option{
+		Name: "layout",
+		Codec: func(value string, typ reflect.Type) (structmap.Codec, error) {
+			if typ != reflect.TypeOf(time.Time{}) {
+				return structmap.Codec{}, errors.New("only valid for time.Time")
+			}
+
+			return structmap.NewCodec(
+				func(val time.Time) (string, error) { return val.Format(value), nil },
+				func(s string) (time.Time, error) { return time.Parse(value, s) },
+			), nil
+		},
+	}
+
+	type testStruct struct {
+		Date    time.Time `map:"date,layout=2006-01-02"`
+		Created time.Time `map:"created"`
+	}
+
+	input := testStruct{
+		Date:    time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC),
+		Created: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+	}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{Options: []structmap.Option{layout}})
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{Options: []structmap.Option{layout}})
+
+	actual := make(map[string][]string)
+	err := m.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"date":    {"2023-04-05"},
+		"created": {"2023-04-05T06:07:08Z"},
+	}, actual)
+
+	var output testStruct
+
+	err = u.Unmarshal(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	var invalid struct {
+		Count int `map:"count,layout=2006"`
+	}
+
+	err = u.Unmarshal(nil, &invalid)
+	assert.ErrorContains(t, err, "option layout: only valid for time.Time")
+
+	err = structmap.Unmarshal(nil, &output)
+	assert.ErrorContains(t, err, "unknown option layout")
+
+	var bare struct {
+		Name string `map:"name,required=yes"`
+	}
+
+	err = structmap.Unmarshal(nil, &bare)
+	assert.ErrorContains(t, err, "option required does not take a value")
+}
+
+func TestKeyFuncOption(t *testing.T) {
+	type testStruct struct {
+		ETag        string `map:"etag,keyfunc=none"`
+		ContentType string `map:"content-type"`
+		Trace       string `map:"trace-id,keyfunc=shout"`
+	}
+
+	input := testStruct{ETag: `"abc"`, ContentType: "text/plain", Trace: "1"}
+
+	expected := map[string][]string{
+		"etag":         {`"abc"`},
+		"Content-Type": {"text/plain"},
+		"TRACE-ID!":    {"1"},
+	}
+
+	shout := map[string]func(s string) string{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+	}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{KeyLookupFunc: http.CanonicalHeaderKey, KeyFuncs: shout})
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{KeyLookupFunc: http.CanonicalHeaderKey, KeyFuncs: shout})
+
+	actual := make(map[string][]string)
+	err := m.Marshal(input, actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	var output testStruct
+
+	err = u.Unmarshal(actual, &output)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	err = structmap.Unmarshal(nil, &output)
+	assert.ErrorContains(t, err, "unknown key func shout")
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errUnterminatedExpr = errors.New("unterminated template expression")
+
+// templateOp holds the expansion rules of an RFC 6570 operator.
+type templateOp struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var templateOps = map[byte]templateOp{
+	'+': {sep: ",", reserved: true},
+	'#': {first: "#", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// Expand fills the RFC 6570 URI template (e.g. "/users/{id}{?page,limit}")
+// with the fields of src, where the variable names are the marshaled keys. A
+// multi-valued key is expanded as a list, and a missing or empty key is
+// undefined.
+func (m *Marshaler) Expand(template string, src any) (string, error) {
+	v := make(map[string][]string)
+	if err := m.Marshal(src, v); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			sb.WriteString(template)
+
+			return sb.String(), nil
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", errUnterminatedExpr
+		}
+
+		sb.WriteString(template[:start])
+
+		if err := expandExpr(&sb, template[start+1:start+end], v); err != nil {
+			return "", fmt.Errorf("expression %s: %w", template[start:start+end+1], err)
+		}
+
+		template = template[start+end+1:]
+	}
+}
+
+func Expand(template string, src any) (string, error) {
+	return DefaultMarshaler.Expand(template, src)
+}
+
+func expandExpr(sb *strings.Builder, expr string, v map[string][]string) error {
+	var op templateOp
+
+	if len(expr) > 0 {
+		if o, ok := templateOps[expr[0]]; ok {
+			op, expr = o, expr[1:]
+		} else {
+			op.sep = ","
+		}
+	}
+
+	first := true
+
+	for _, spec := range strings.Split(expr, ",") {
+		name, explode := strings.CutSuffix(spec, "*")
+
+		maxLen := -1
+
+		if n, prefix, ok := strings.Cut(name, ":"); ok {
+			var err error
+			if maxLen, err = strconv.Atoi(prefix); err != nil || maxLen <= 0 || maxLen >= 10000 {
+				return fmt.Errorf("invalid prefix %s", prefix)
+			}
+
+			name = n
+		}
+
+		if name == "" {
+			return errors.New("empty variable name")
+		}
+
+		vals, ok := getValue(v, name)
+		if !ok {
+			continue
+		}
+
+		if first {
+			sb.WriteString(op.first)
+			first = false
+		} else {
+			sb.WriteString(op.sep)
+		}
+
+		if len(vals) == 1 && maxLen > 0 {
+			vals = []string{truncateRunes(vals[0], maxLen)}
+		}
+
+		writeVarspec(sb, op, name, vals, explode)
+	}
+
+	return nil
+}
+
+func writeVarspec(sb *strings.Builder, op templateOp, name string, vals []string, explode bool) {
+	if explode {
+		for i, val := range vals {
+			if i > 0 {
+				sb.WriteString(op.sep)
+			}
+
+			writeNamedValue(sb, op, name, val)
+		}
+
+		return
+	}
+
+	if len(vals) == 1 {
+		writeNamedValue(sb, op, name, vals[0])
+
+		return
+	}
+
+	if op.named {
+		sb.WriteString(name + "=")
+	}
+
+	for i, val := range vals {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		sb.WriteString(escapeTemplate(val, op.reserved))
+	}
+}
+
+func writeNamedValue(sb *strings.Builder, op templateOp, name, val string) {
+	if op.named {
+		sb.WriteString(name)
+
+		if val == "" {
+			sb.WriteString(op.ifEmpty)
+
+			return
+		}
+
+		sb.WriteByte('=')
+	}
+
+	sb.WriteString(escapeTemplate(val, op.reserved))
+}
+
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+
+		n--
+	}
+
+	return s
+}
+
+func isTemplateUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isTemplateReserved(c byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0
+}
+
+// escapeTemplate percent-encodes s, where the reserved characters and the
+// existing percent-encoded triplets are kept as-is when reserved is set.
+func escapeTemplate(s string, reserved bool) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case isTemplateUnreserved(c):
+			sb.WriteByte(c)
+
+		case reserved && isTemplateReserved(c):
+			sb.WriteByte(c)
+
+		case reserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			sb.WriteString(s[i : i+3])
+			i += 2
+
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&15])
+		}
+	}
+
+	return sb.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
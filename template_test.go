@@ -0,0 +1,75 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	type testStruct struct {
+		ID     string   `map:"id"`
+		Path   string   `map:"path"`
+		Page   int      `map:"page,omitempty"`
+		Limit  int      `map:"limit"`
+		Empty  string   `map:"empty"`
+		Tags   []string `map:"tags"`
+		Format string   `map:"format,omitempty"`
+	}
+
+	input := testStruct{
+		ID:    "a b",
+		Path:  "foo/bar",
+		Limit: 10,
+		Tags:  []string{"red", "green"},
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"/users/{id}", "/users/a%20b"},
+		{"/files{/path}", "/files/foo%2Fbar"},
+		{"/files/{+path}", "/files/foo/bar"},
+		{"/items{?page,limit}", "/items?limit=10"},
+		{"/items{?tags}", "/items?tags=red,green"},
+		{"/items{?tags*}", "/items?tags=red&tags=green"},
+		{"/items?x=1{&limit,empty}", "/items?x=1&limit=10&empty="},
+		{"/map{;tags*,empty}", "/map;tags=red;tags=green;empty"},
+		{"{.format}", ""},
+		{"{id:1}{#path}", "a#foo/bar"},
+		{"X{.tags*}", "X.red.green"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.template, func(t *testing.T) {
+			actual, err := structmap.Expand(tc.template, input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	_, err := structmap.Expand("/users/{id", input)
+	assert.ErrorContains(t, err, "unterminated")
+
+	_, err = structmap.Expand("/users/{id:x}", input)
+	assert.ErrorContains(t, err, "invalid prefix x")
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"context"
+	"net/http"
+)
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+type transportValuesKey struct{}
+
+// WithTransportValues returns a copy of ctx carrying src, which is applied by
+// Transport to the outgoing request on top of its own Values.
+func WithTransportValues(ctx context.Context, src any) context.Context {
+	return context.WithValue(ctx, transportValuesKey{}, src)
+}
+
+// Transport is an http.RoundTripper that marshals the fields of a struct into
+// the query string, headers and cookies of every outgoing request, based on
+// the same source options used by UnmarshalRequest.
+type Transport struct {
+	// Base is the underlying RoundTripper. http.DefaultTransport is used when
+	// it is nil.
+	Base http.RoundTripper
+
+	// Marshaler is used to marshal the values. DefaultMarshaler is used when
+	// it is nil.
+	Marshaler *Marshaler
+
+	// Values is applied to every request, before any values set in the
+	// request context using WithTransportValues.
+	Values any
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+
+	return t.Base
+}
+
+func (t *Transport) marshaler() *Marshaler {
+	if t.Marshaler == nil {
+		return &DefaultMarshaler
+	}
+
+	return t.Marshaler
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctxValues := r.Context().Value(transportValuesKey{})
+
+	if t.Values == nil && ctxValues == nil {
+		return t.base().RoundTrip(r)
+	}
+
+	// A RoundTripper must not modify the original request.
+	r = r.Clone(r.Context())
+
+	for _, src := range []any{t.Values, ctxValues} {
+		if src == nil {
+			continue
+		}
+
+		if err := t.marshaler().applyRequest(r, src, MergeReplace); err != nil {
+			if r.Body != nil {
+				r.Body.Close()
+			}
+
+			return nil, err
+		}
+	}
+
+	return t.base().RoundTrip(r)
+}
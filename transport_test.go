@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestTransport(t *testing.T) {
+	type clientValues struct {
+		APIKey string `map:"api-key"`
+		Tenant string `map:"x-tenant-id,header,omitempty"`
+	}
+
+	type tracing struct {
+		TraceID string `map:"x-trace-id,header"`
+		Session string `map:"session,cookie"`
+	}
+
+	var actual *http.Request
+
+	tr := &structmap.Transport{
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			actual = r
+			return httptest.NewRecorder().Result(), nil
+		}),
+		Values: clientValues{APIKey: "secret", Tenant: "acme"},
+	}
+
+	t.Run("WithValues", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/items?page=2", nil)
+
+		_, err := tr.RoundTrip(r)
+		require.NoError(t, err)
+		assert.Equal(t, "page=2", r.URL.RawQuery)
+		assert.Equal(t, "2", actual.URL.Query().Get("page"))
+		assert.Equal(t, "secret", actual.URL.Query().Get("api-key"))
+		assert.Equal(t, "acme", actual.Header.Get("X-Tenant-Id"))
+		assert.Empty(t, r.Header.Get("X-Tenant-Id"))
+	})
+
+	t.Run("WithContextValues", func(t *testing.T) {
+		ctx := structmap.WithTransportValues(context.Background(), tracing{TraceID: "abc", Session: "xyz"})
+		r := httptest.NewRequest(http.MethodGet, "/items", nil).WithContext(ctx)
+
+		_, err := tr.RoundTrip(r)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", actual.URL.Query().Get("api-key"))
+		assert.Equal(t, "abc", actual.Header.Get("X-Trace-Id"))
+
+		cookie, err := actual.Cookie("session")
+		require.NoError(t, err)
+		assert.Equal(t, "xyz", cookie.Value)
+	})
+
+	t.Run("WithInvalidValues", func(t *testing.T) {
+		ctx := structmap.WithTransportValues(context.Background(), struct {
+			Ch chan int `map:"ch"`
+		}{})
+		r := httptest.NewRequest(http.MethodGet, "/items", nil).WithContext(ctx)
+
+		_, err := tr.RoundTrip(r)
+		assert.Error(t, err)
+	})
+}
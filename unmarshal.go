@@ -19,10 +19,17 @@ package structmap
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
+	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -30,18 +37,98 @@ var (
 	_ unmarshaler = (*structUnmarshaler)(nil)
 	_ unmarshaler = (*stringUnmarshaler)(nil)
 	_ unmarshaler = (*intUnmarshaler)(nil)
+	_ unmarshaler = (*countUnmarshaler)(nil)
+	_ unmarshaler = (*uintUnmarshaler)(nil)
+	_ unmarshaler = (*boolUnmarshaler)(nil)
+	_ unmarshaler = (*floatUnmarshaler)(nil)
+	_ unmarshaler = (*complexUnmarshaler)(nil)
+	_ unmarshaler = (*runeUnmarshaler)(nil)
+	_ unmarshaler = (*byteUnmarshaler)(nil)
 	_ unmarshaler = (*methodUnmarshaler)(nil)
 	_ unmarshaler = (*sliceUnmarshaler)(nil)
+	_ unmarshaler = (*timeUnmarshaler)(nil)
 )
 
 var (
-	errSkipField = errors.New("skip field")
+	errSkipField    = errors.New("skip field")
+	errInvalidItems = errors.New("minitems and maxitems options are only valid for slice")
+	errInvalidCount = errors.New("count option is only valid for int or uint")
+	errTooFewItems  = errors.New("too few items")
+	errTooManyItems = errors.New("too many items")
 )
 
+// UnsupportedTypeError is returned when a type cannot be marshaled or
+// unmarshaled at all, as opposed to an invalid tag or option.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+
+	// Field is the Go path of the struct field with the type, e.g.
+	// "Items.Handler", or empty when the type is not in a struct.
+	Field string
+
+	msg string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return e.msg
+}
+
+func newUnsupportedTypeError(typ reflect.Type, format string, a ...any) error {
+	return &UnsupportedTypeError{Type: typ, msg: fmt.Sprintf(format, a...)}
+}
+
+// UnsupportedValueError is returned when a value cannot be marshaled, even
+// though its type is supported, e.g. an interface holding an unsupported type.
+type UnsupportedValueError struct {
+	Value reflect.Value
+
+	// Field is the Go path of the struct field with the value, if known.
+	Field string
+
+	msg string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return e.msg
+}
+
+// setUnsupportedField prepends the struct field name into the field path of
+// the UnsupportedTypeError in err, if any.
+func setUnsupportedField(err error, name string) {
+	var ute *UnsupportedTypeError
+	if !errors.As(err, &ute) {
+		return
+	}
+
+	if ute.Field == "" {
+		ute.Field = name
+	} else {
+		ute.Field = joinFieldPath(name, ute.Field)
+	}
+}
+
+// skipUnsupported reports whether err is caused by an unsupported type that
+// must be skipped in the lenient mode.
+func skipUnsupported(lenient bool, err error) bool {
+	var ute *UnsupportedTypeError
+
+	return lenient && errors.As(err, &ute)
+}
+
 var (
 	valueUnmarshalerReflectType = reflect.TypeOf((*ValueUnmarshaler)(nil)).Elem()
+	timeReflectType             = reflect.TypeOf(time.Time{})
 )
 
+// timeLayouts lists the accepted time layouts in the order they are tried.
+// Layouts without zone information are parsed in the configured location.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	time.DateTime,
+	time.DateOnly,
+}
+
 var (
 	DefaultUnmarshaler Unmarshaler
 
@@ -50,14 +137,137 @@ var (
 			KeyLookupFunc: http.CanonicalHeaderKey,
 		},
 	}
+
+	// MetadataUnmarshaler matches the keys written by MetadataMarshaler.
+	MetadataUnmarshaler = Unmarshaler{
+		config: UnmarshalConfig{
+			KeyLookupFunc: strings.ToLower,
+		},
+	}
+
+	// SchemaUnmarshaler reads the `schema` tags and options used by
+	// github.com/gorilla/schema.
+	SchemaUnmarshaler = Unmarshaler{
+		config: UnmarshalConfig{
+			TagName: "schema",
+		},
+	}
+
+	// FormUnmarshaler reads the `form` tags and the bracket index convention
+	// used by github.com/go-playground/form.
+	FormUnmarshaler = Unmarshaler{
+		config: UnmarshalConfig{
+			TagName:      "form",
+			BracketIndex: true,
+		},
+	}
 )
 
+// LimitError is returned when the input or the target type exceeds one of the
+// limits set in the UnmarshalConfig or MarshalConfig.
+type LimitError struct {
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("exceeded %s limit of %d", e.Limit, e.Max)
+}
+
+// FieldError is returned when a value cannot be unmarshaled into a field.
+type FieldError struct {
+	// Key is the resolved key of the value, e.g. "items.2.count".
+	Key string
+
+	// Field is the Go path of the field, e.g. "Items[2].Count".
+	Field string
+
+	// Index is the position of the offending value in a slice field, or -1
+	// when the field is not a slice.
+	Index int
+
+	// Value is the offending raw value, if there is a single one.
+	Value string
+
+	// Validator is the name of the validator that rejects the value, if any.
+	Validator string
+
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	var b strings.Builder
+
+	if e.Key != "" {
+		b.WriteString("key " + e.Key + ": ")
+	}
+
+	if e.Index >= 0 {
+		fmt.Fprintf(&b, "slice index #%d: ", e.Index)
+	}
+
+	if e.Validator != "" {
+		b.WriteString("validator " + e.Validator + ": ")
+	}
+
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidatorFunc validates the unmarshaled value of a field, which is given as
+// the field type, e.g. time.Time for a time.Time field.
+type ValidatorFunc func(value any) error
+
 type ValueUnmarshaler interface {
 	UnmarshalValue(v []string) error
 }
 
+type valueBudget struct {
+	maxValueLen int
+	maxTotalLen int
+	total       int
+}
+
+func (b *valueBudget) consume(val []string) error {
+	if b == nil {
+		return nil
+	}
+
+	for _, s := range val {
+		if b.maxValueLen > 0 && len(s) > b.maxValueLen {
+			return &LimitError{Limit: "MaxValueLen", Max: b.maxValueLen}
+		}
+
+		b.total += len(s)
+
+		if b.maxTotalLen > 0 && b.total > b.maxTotalLen {
+			return &LimitError{Limit: "MaxTotalLen", Max: b.maxTotalLen}
+		}
+	}
+
+	return nil
+}
+
 type unmarshalContext struct {
-	value []string
+	value   []string
+	budget  *valueBudget
+	request *requestValues
+
+	// The key prefix and field path are only tracked when tracing or masking,
+	// as the keys of the indexed slice elements are relative to their index.
+	trace     func(key, fieldPath string, values []string)
+	mask      *fieldMask
+	keyPrefix string
+	fieldPath string
+
+	deprecated func(key, alias string)
+	valueFunc  func(key, value string) (string, error)
+	valueCheck func(key string, values []string) error
 }
 
 type unmarshaler interface {
@@ -77,31 +287,119 @@ func (u *pointerUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]stri
 	return u.elem.unmarshal(ctx, v, dst.Elem())
 }
 
+// fieldAlias is an alternative key of a field, which is only read when the
+// field key itself is missing.
+type fieldAlias struct {
+	name       string
+	deprecated bool
+}
+
+// fieldValidator is a named validator selected by the "validate" option.
+type fieldValidator struct {
+	name string
+	fn   ValidatorFunc
+}
+
+// fieldCondition makes a field required depending on the presence or the
+// value of another key.
+type fieldCondition struct {
+	option string
+	key    string
+	value  string
+}
+
+func (c fieldCondition) check(v map[string][]string) error {
+	val, ok := getValue(v, c.key)
+
+	switch c.option {
+	case "required_with":
+		if ok {
+			return fmt.Errorf(`value is required with key "%s"`, c.key)
+		}
+	case "required_without":
+		if !ok {
+			return fmt.Errorf(`value is required without key "%s"`, c.key)
+		}
+	case "required_if":
+		if ok && slices.Contains(val, c.value) {
+			return fmt.Errorf(`value is required when key "%s" is "%s"`, c.key, c.value)
+		}
+	}
+
+	return nil
+}
+
 type fieldUnmarshaler struct {
 	name        string
+	aliases     []fieldAlias
+	conditions  []fieldCondition
+	groups      []fieldGroupOption
+	validators  []fieldValidator
+	field       string
 	required    bool
 	nested      bool
 	index       int
+	nullValues  []string
+	defaults    []string
+	indexKey    func(i int) string
+	slice       reflect.Type
+	escaped     bool
+	secret      bool
+	source      string
 	unmarshaler unmarshaler
 }
 
-func (c *fieldUnmarshaler) applyOption(opt string) error {
-	switch opt {
-	case "required":
-		c.required = true
-	case "omitempty":
-		// This option is only valid for marhsaler so it will be ignored.
-	case "":
-		// Allow empty option.
-	default:
-		return fmt.Errorf("unknown option %s", opt)
-	}
+type structUnmarshaler struct {
+	fields []fieldUnmarshaler
+	// direct indexes the fields that are only read from their own key by the
+	// key name, so they can be looked up from the input keys instead.
+	direct map[string][]int
+	// conditional is set when any of the fields has a conditional required
+	// option, which is checked after all fields are unmarshaled.
+	conditional bool
+	// groups are the field groups, which are checked after all fields are
+	// unmarshaled.
+	groups []fieldGroup
+}
 
-	return nil
+// fieldGroupOption is the xor or anyof option of a field.
+type fieldGroupOption struct {
+	option string
+	name   string
 }
 
-type structUnmarshaler struct {
-	fields []fieldUnmarshaler
+// fieldGroup is a group of fields where at most one of them (xor), or at least
+// one of them (anyof) must be provided.
+type fieldGroup struct {
+	fieldGroupOption
+	fields []int
+}
+
+func (g fieldGroup) check(ctx unmarshalContext, fields []fieldUnmarshaler, v map[string][]string) error {
+	var keys, provided []string
+
+	for _, i := range g.fields {
+		field := fields[i]
+
+		if ctx.mask != nil && !ctx.mask.covers(ctx.keyPrefix+field.name) {
+			continue
+		}
+
+		keys = append(keys, strconv.Quote(field.name))
+
+		if field.provided(v) {
+			provided = append(provided, strconv.Quote(field.name))
+		}
+	}
+
+	switch {
+	case g.option == "xor" && len(provided) > 1:
+		return fmt.Errorf("group %s: keys %s are mutually exclusive", g.name, strings.Join(provided, ", "))
+	case g.option == "anyof" && len(keys) > 0 && len(provided) == 0:
+		return fmt.Errorf("group %s: one of keys %s is required", g.name, strings.Join(keys, ", "))
+	}
+
+	return nil
 }
 
 func getValue(v map[string][]string, key string) ([]string, bool) {
@@ -117,17 +415,188 @@ func getValue(v map[string][]string, key string) ([]string, bool) {
 	return val, true
 }
 
+// getIndexedValue collects the values of the key itself, followed by the
+// indexed keys (e.g. "key[0]", "key[1]") until the first missing index.
+func getIndexedValue(v map[string][]string, key string, indexKey func(i int) string) ([]string, bool) {
+	val, _ := getValue(v, key)
+
+	for i := 0; ; i++ {
+		elem, ok := getValue(v, indexKey(i))
+		if !ok {
+			break
+		}
+
+		val = append(val[:len(val):len(val)], elem...)
+	}
+
+	return val, len(val) > 0
+}
+
+// uniqueValues removes the duplicate values in place, keeping the first
+// occurrence of each.
+func uniqueValues(val []string) []string {
+	seen := make(map[string]struct{}, len(val))
+	out := val[:0]
+
+	for _, s := range val {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+
+	return out
+}
+
+func containsFold(values []string, s string) bool {
+	for _, val := range values {
+		if strings.EqualFold(val, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unescapeValues(val []string) ([]string, error) {
+	out := make([]string, len(val))
+
+	for i, s := range val {
+		var err error
+		if out[i], err = url.QueryUnescape(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func transformValues(val []string, key string, fn func(key, value string) (string, error)) ([]string, error) {
+	out := make([]string, len(val))
+
+	for i, s := range val {
+		var err error
+		if out[i], err = fn(key, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// ExpandEnv is a ValueFunc that replaces the ${var} or $var in the values
+// according to the environment variables.
+func ExpandEnv(_, value string) (string, error) {
+	return os.ExpandEnv(value), nil
+}
+
+func splitValues(val []string, sep string) []string {
+	var out []string
+
+	for _, s := range val {
+		if s != "" {
+			out = append(out, strings.Split(s, sep)...)
+		}
+	}
+
+	return out
+}
+
+func (c *fieldUnmarshaler) isNull(val []string) bool {
+	if len(val) != 1 {
+		return false
+	}
+
+	for _, null := range c.nullValues {
+		if val[0] == null {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (u *structUnmarshaler) unmarshalField(
 	ctx unmarshalContext,
 	field fieldUnmarshaler,
 	v map[string][]string,
 	dst reflect.Value,
 ) error {
+	if field.source != "" && ctx.request != nil {
+		var err error
+		if v, err = ctx.request.values(field.source, field.name); err != nil {
+			return err
+		}
+	}
+
 	if !field.nested {
-		var ok bool
-		ctx.value, ok = getValue(v, field.name)
+		if ctx.mask != nil && !ctx.mask.covers(ctx.keyPrefix+field.name) {
+			return nil
+		}
 
-		if !ok {
+		var (
+			ok  bool
+			err error
+		)
+
+		if field.indexKey != nil {
+			ctx.value, ok = getIndexedValue(v, field.name, field.indexKey)
+		} else {
+			ctx.value, ok = getValue(v, field.name)
+		}
+
+		for _, alias := range field.aliases {
+			if ok {
+				break
+			}
+
+			if ctx.value, ok = getValue(v, alias.name); ok && alias.deprecated && ctx.deprecated != nil {
+				ctx.deprecated(field.name, alias.name)
+			}
+		}
+
+		if ok && field.escaped {
+			if ctx.value, err = unescapeValues(ctx.value); err != nil {
+				return field.newError(ctx.value, err)
+			}
+		}
+
+		if ok && ctx.valueCheck != nil {
+			if err := ctx.valueCheck(field.name, ctx.value); err != nil {
+				return field.newError(ctx.value, err)
+			}
+		}
+
+		if !ok && field.defaults != nil {
+			ctx.value, ok = field.defaults, true
+		}
+
+		if ok && ctx.valueFunc != nil {
+			if ctx.value, err = transformValues(ctx.value, field.name, ctx.valueFunc); err != nil {
+				return field.newError(nil, err)
+			}
+		}
+
+		if ctx.trace != nil {
+			vals := ctx.value
+			if field.secret {
+				vals = redactValues(vals)
+			}
+
+			ctx.trace(ctx.keyPrefix+field.name, joinFieldPath(ctx.fieldPath, field.field), vals)
+		}
+
+		// A slice key that is present without any value is set into an empty
+		// slice, so it can be told apart from the missing key.
+		if _, present := v[field.name]; !ok && present && field.slice != nil && !field.required {
+			dst.Field(field.index).Set(reflect.MakeSlice(field.slice, 0, 0))
+
+			return nil
+		}
+
+		if !ok || field.isNull(ctx.value) {
 			if field.required {
 				return fmt.Errorf(`value not found for required key "%s"`, field.name)
 			}
@@ -136,295 +605,1797 @@ func (u *structUnmarshaler) unmarshalField(
 
 			return nil
 		}
+
+		if err := ctx.budget.consume(ctx.value); err != nil {
+			return fmt.Errorf("key %s: %w", field.name, err)
+		}
 	}
 
-	return field.unmarshaler.unmarshal(ctx, v, dst.Field(field.index))
-}
+	if field.nested && ctx.trace != nil {
+		ctx.fieldPath = joinFieldPath(ctx.fieldPath, field.field)
+	}
+
+	if err := field.unmarshaler.unmarshal(ctx, v, dst.Field(field.index)); err != nil {
+		if field.nested {
+			if fe, ok := err.(*FieldError); ok {
+				fe.Field = joinFieldPath(field.field, fe.Field)
+			}
 
-func (u *structUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
-	for _, field := range u.fields {
-		if err := u.unmarshalField(ctx, field, v, dst); err != nil {
 			return err
 		}
+
+		return field.newError(ctx.value, err)
+	}
+
+	for _, val := range field.validators {
+		if err := val.fn(dst.Field(field.index).Interface()); err != nil {
+			fe := field.newError(ctx.value, err).(*FieldError)
+			fe.Validator = val.name
+
+			return fe
+		}
 	}
 
 	return nil
 }
 
-type stringUnmarshaler struct{}
+// newError wraps err into a FieldError, or completes the FieldError of a
+// slice element that is returned by the sliceUnmarshaler.
+func (c *fieldUnmarshaler) newError(val []string, err error) error {
+	fe, ok := err.(*FieldError)
+	if ok && fe.Key == "" {
+		fe.Key = c.name
+		fe.Field = c.field
+	} else {
+		fe = &FieldError{
+			Key:   c.name,
+			Field: c.field,
+			Index: -1,
+			Err:   err,
+		}
 
-func (u *stringUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
-	dst.SetString(ctx.value[0])
+		if len(val) == 1 {
+			fe.Value = val[0]
+		}
+	}
 
-	return nil
+	if c.secret {
+		fe.Value = ""
+		fe.Err = &redactedError{err: fe.Err}
+	}
+
+	return fe
 }
 
-type intUnmarshaler struct {
-	bitSize int
+func joinFieldPath(parent, path string) string {
+	if parent == "" || strings.HasPrefix(path, "[") {
+		return parent + path
+	}
+
+	return parent + "." + path
 }
 
-func (u *intUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
-	val, err := strconv.ParseInt(ctx.value[0], 10, u.bitSize)
-	if err != nil {
-		return err
+func (u *structUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	if len(v) < len(u.direct) && ctx.trace == nil && ctx.mask == nil {
+		if err := u.unmarshalByKeys(ctx, v, dst); err != nil {
+			return err
+		}
+	} else {
+		for _, field := range u.fields {
+			if err := u.unmarshalField(ctx, field, v, dst); err != nil {
+				return err
+			}
+		}
 	}
 
-	dst.SetInt(val)
+	if u.conditional || u.groups != nil {
+		return u.checkConditions(ctx, v)
+	}
+
+	return nil
+}
+
+// checkConditions returns an error for the first missing field that is
+// required by its conditions, or the first group with conflicting fields.
+func (u *structUnmarshaler) checkConditions(ctx unmarshalContext, v map[string][]string) error {
+	for _, field := range u.fields {
+		if field.conditions == nil || field.defaults != nil || field.provided(v) {
+			continue
+		}
+
+		if ctx.mask != nil && !ctx.mask.covers(ctx.keyPrefix+field.name) {
+			continue
+		}
+
+		for _, cond := range field.conditions {
+			if err := cond.check(v); err != nil {
+				return field.newError(nil, err)
+			}
+		}
+	}
+
+	for _, group := range u.groups {
+		if err := group.check(ctx, u.fields, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// provided reports whether the input has a value for the field from its own
+// key or an alias.
+func (c *fieldUnmarshaler) provided(v map[string][]string) bool {
+	if c.indexKey != nil {
+		if _, ok := getIndexedValue(v, c.name, c.indexKey); ok {
+			return true
+		}
+	} else if _, ok := getValue(v, c.name); ok {
+		return true
+	}
+
+	for _, alias := range c.aliases {
+		if _, ok := getValue(v, alias.name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unmarshalByKeys iterates the input keys to find the direct fields instead
+// of looking up every direct field key, which is faster when the input is much
+// smaller than the struct.
+func (u *structUnmarshaler) unmarshalByKeys(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	var found []int
+
+	for key := range v {
+		found = append(found, u.direct[key]...)
+	}
+
+	slices.Sort(found)
+
+	for i, field := range u.fields {
+		if len(found) > 0 && found[0] == i {
+			found = found[1:]
+		} else if field.isDirect() {
+			dst.Field(field.index).SetZero()
+
+			continue
+		}
+
+		if err := u.unmarshalField(ctx, field, v, dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isDirect reports whether the field is only read from its own key, and is
+// set into zero when the key is missing.
+func (c *fieldUnmarshaler) isDirect() bool {
+	return !c.nested && !c.required && c.source == "" && c.aliases == nil &&
+		c.defaults == nil && c.indexKey == nil
+}
+
+type stringUnmarshaler struct{}
+
+func (u *stringUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	dst.SetString(ctx.value[0])
+
+	return nil
+}
+
+type intUnmarshaler struct {
+	bitSize int
+	parse   func(s string, bitSize int) (int64, error)
+}
+
+func (u *intUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	val, err := u.parse(ctx.value[0], u.bitSize)
+	if err != nil {
+		return err
+	}
+
+	if dst.OverflowInt(val) {
+		return fmt.Errorf("value %d overflows %s", val, dst.Type().String())
+	}
+
+	dst.SetInt(val)
+
+	return nil
+}
+
+// countUnmarshaler sets the number of values of the key into an int or uint
+// field, instead of parsing them.
+type countUnmarshaler struct {
+	list bool
+	sep  string
+}
+
+func (u *countUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if u.list {
+		ctx.value = splitList(ctx.value)
+	} else if u.sep != "" {
+		ctx.value = splitValues(ctx.value, u.sep)
+	}
+
+	n := len(ctx.value)
+
+	if dst.CanInt() {
+		if dst.OverflowInt(int64(n)) {
+			return fmt.Errorf("count %d overflows %s", n, dst.Type().String())
+		}
+
+		dst.SetInt(int64(n))
+
+		return nil
+	}
+
+	if dst.OverflowUint(uint64(n)) {
+		return fmt.Errorf("count %d overflows %s", n, dst.Type().String())
+	}
+
+	dst.SetUint(uint64(n))
+
+	return nil
+}
+
+func newCountUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	if getIntSize(typ.Kind()) < 0 && getUintSize(typ.Kind()) < 0 {
+		return nil, errInvalidCount
+	}
+
+	sep := cfg.Separator
+	if sep == "" && cfg.SliceStyle == SliceJoined {
+		sep = cfg.sliceSeparator()
+	}
+
+	return &countUnmarshaler{list: cfg.List, sep: sep}, nil
+}
+
+type uintUnmarshaler struct {
+	bitSize int
+	// parse is the ParseIntFunc from the config, or nil for strconv.ParseUint.
+	parse func(s string, bitSize int) (int64, error)
+}
+
+func (u *uintUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if u.parse == nil {
+		val, err := strconv.ParseUint(ctx.value[0], 10, u.bitSize)
+		if err != nil {
+			return err
+		}
+
+		dst.SetUint(val)
+
+		return nil
+	}
+
+	val, err := u.parse(ctx.value[0], 64)
+	if err != nil {
+		return err
+	}
+
+	if val < 0 || dst.OverflowUint(uint64(val)) {
+		return fmt.Errorf("value %d overflows %s", val, dst.Type().String())
+	}
+
+	dst.SetUint(uint64(val))
+
+	return nil
+}
+
+type boolUnmarshaler struct {
+	trueValues  []string
+	falseValues []string
+}
+
+func (u *boolUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if containsFold(u.trueValues, ctx.value[0]) {
+		dst.SetBool(true)
+
+		return nil
+	}
+
+	if containsFold(u.falseValues, ctx.value[0]) {
+		dst.SetBool(false)
+
+		return nil
+	}
+
+	val, err := strconv.ParseBool(ctx.value[0])
+	if err != nil {
+		return err
+	}
+
+	dst.SetBool(val)
+
+	return nil
+}
+
+type floatUnmarshaler struct {
+	bitSize int
+	parse   func(s string, bitSize int) (float64, error)
+}
+
+func (u *floatUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	val, err := u.parse(ctx.value[0], u.bitSize)
+	if err != nil {
+		return err
+	}
+
+	if dst.OverflowFloat(val) {
+		return fmt.Errorf("value %g overflows %s", val, dst.Type().String())
+	}
+
+	dst.SetFloat(val)
+
+	return nil
+}
+
+type complexUnmarshaler struct {
+	bitSize int
+}
+
+func (u *complexUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	val, err := strconv.ParseComplex(ctx.value[0], u.bitSize)
+	if err != nil {
+		return err
+	}
+
+	dst.SetComplex(val)
+
+	return nil
+}
+
+type runeUnmarshaler struct{}
+
+func (u *runeUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	r, size := utf8.DecodeRuneInString(ctx.value[0])
+
+	if size != len(ctx.value[0]) || r == utf8.RuneError {
+		return fmt.Errorf("%q is not a single character", ctx.value[0])
+	}
+
+	dst.SetInt(int64(r))
+
+	return nil
+}
+
+type byteUnmarshaler struct{}
+
+func (u *byteUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if len(ctx.value[0]) != 1 {
+		return fmt.Errorf("%q is not a single byte", ctx.value[0])
+	}
+
+	dst.SetUint(uint64(ctx.value[0][0]))
+
+	return nil
+}
+
+type methodUnmarshaler struct {
+	newFn       func(dst reflect.Value)
+	ptrReceiver bool
+}
+
+func (u *methodUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if u.newFn != nil {
+		u.newFn(dst)
+	}
+
+	if u.ptrReceiver {
+		// TODO: Make sure that this is guaranteed to be addressable or not.
+		// The non-nil pointer check already in place at the top-level function
+		// so it must always be addressable.
+		dst = dst.Addr()
+	}
+
+	return dst.Interface().(ValueUnmarshaler).UnmarshalValue(ctx.value)
+}
+
+type sliceUnmarshaler struct {
+	typ    reflect.Type
+	elem   unmarshaler
+	maxLen int
+	list   bool
+	sep    string
+	unique bool
+	// minItems and maxItems bound the number of values of the field, where
+	// zero means unbounded.
+	minItems int
+	maxItems int
+}
+
+func (u *sliceUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	if u.list {
+		ctx.value = splitList(ctx.value)
+	} else if u.sep != "" {
+		ctx.value = splitValues(ctx.value, u.sep)
+	}
+
+	if u.unique {
+		ctx.value = uniqueValues(slices.Clone(ctx.value))
+	}
+
+	if u.maxLen > 0 && len(ctx.value) > u.maxLen {
+		return &LimitError{Limit: "MaxSliceLen", Max: u.maxLen}
+	}
+
+	if u.minItems > 0 && len(ctx.value) < u.minItems {
+		return fmt.Errorf("%w: got %d, minimum is %d", errTooFewItems, len(ctx.value), u.minItems)
+	}
+
+	if u.maxItems > 0 && len(ctx.value) > u.maxItems {
+		return fmt.Errorf("%w: got %d, maximum is %d", errTooManyItems, len(ctx.value), u.maxItems)
+	}
+
+	if dst.IsNil() || dst.Cap() < len(ctx.value) {
+		dst.Set(reflect.MakeSlice(u.typ, len(ctx.value), len(ctx.value)))
+	} else if dst.Len() != len(ctx.value) {
+		dst.SetLen(len(ctx.value))
+	}
+
+	values := ctx.value
+
+	for i := 0; i < len(values); i++ {
+		ctx.value = values[i : i+1]
+
+		if err := u.elem.unmarshal(ctx, v, dst.Index(i)); err != nil {
+			return &FieldError{Index: i, Value: values[i], Err: err}
+		}
+	}
+
+	return nil
+}
+
+// indexedSliceUnmarshaler unmarshals a slice of structs where the keys of each
+// element are prefixed with its index, e.g. "items.0.name" or "items[0].name".
+type indexedSliceUnmarshaler struct {
+	typ    reflect.Type
+	prefix string
+	suffix string
+	lookup func(key string) string
+	elem   unmarshaler
+	maxLen int
+}
+
+func (u *indexedSliceUnmarshaler) unmarshal(ctx unmarshalContext, v map[string][]string, dst reflect.Value) error {
+	var groups map[int]map[string][]string
+
+	n := 0
+
+	for key, val := range v {
+		rest, ok := strings.CutPrefix(key, u.prefix)
+		if !ok {
+			continue
+		}
+
+		index, sub, ok := strings.Cut(rest, u.suffix)
+		if !ok {
+			continue
+		}
+
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 {
+			continue
+		}
+
+		if u.maxLen > 0 && i >= u.maxLen {
+			return fmt.Errorf("key %s: %w", key, &LimitError{Limit: "MaxSliceLen", Max: u.maxLen})
+		}
+
+		// Every index needs at least one key, so this bounds the allocation
+		// by the size of the input.
+		if i >= len(v) {
+			return fmt.Errorf("key %s: index %d is out of range", key, i)
+		}
+
+		if groups == nil {
+			groups = make(map[int]map[string][]string)
+		}
+
+		if groups[i] == nil {
+			groups[i] = make(map[string][]string)
+		}
+
+		// The element keys are looked up as a part of the full key, e.g. a
+		// canonical header "Items.0.name" for the element key "Name".
+		if u.lookup != nil {
+			sub = u.lookup(sub)
+		}
+
+		groups[i][sub] = val
+
+		if i >= n {
+			n = i + 1
+		}
+	}
+
+	if n == 0 {
+		dst.SetZero()
+
+		return nil
+	}
+
+	if dst.Cap() < n {
+		dst.Set(reflect.MakeSlice(u.typ, n, n))
+	} else {
+		dst.SetLen(n)
+	}
+
+	for i := 0; i < n; i++ {
+		ctx := ctx
+		if ctx.trace != nil || ctx.mask != nil {
+			ctx.keyPrefix += u.prefix + strconv.Itoa(i) + u.suffix
+			ctx.fieldPath = joinFieldPath(ctx.fieldPath, "["+strconv.Itoa(i)+"]")
+		}
+
+		if err := u.elem.unmarshal(ctx, groups[i], dst.Index(i)); err != nil {
+			// The keys of the element fields are relative to its index.
+			if fe, ok := err.(*FieldError); ok {
+				fe.Key = u.prefix + strconv.Itoa(i) + u.suffix + fe.Key
+				fe.Field = joinFieldPath("["+strconv.Itoa(i)+"]", fe.Field)
+
+				return fe
+			}
+
+			return fmt.Errorf("slice index #%d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func newIndexedSliceUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	prefix := cfg.joinKey(cfg.Prefix)
+
+	prefix = cfg.lookupKey(prefix)
+
+	// The element keys are relative to its index.
+	elemCfg := cfg
+	elemCfg.Prefix = nil
+
+	elemTyp := typ.Elem()
+
+	elem, err := newStructUnmarshaler(elemCfg, indirectType(elemTyp))
+	if err != nil {
+		return nil, err
+	}
+
+	if elemTyp.Kind() == reflect.Pointer {
+		elem = &pointerUnmarshaler{
+			elemTyp: elemTyp.Elem(),
+			elem:    elem,
+		}
+	}
+
+	unm := &indexedSliceUnmarshaler{
+		typ:    typ,
+		prefix: prefix + cfg.delimiter(),
+		suffix: cfg.delimiter(),
+		elem:   elem,
+		maxLen: cfg.MaxSliceLen,
+	}
+
+	if cfg.KeyLookupFunc != nil {
+		unm.lookup = cfg.lookupKey
+	}
+
+	if cfg.BracketIndex {
+		unm.prefix = prefix + "["
+		unm.suffix = "]" + cfg.delimiter()
+	}
+
+	return unm, nil
+}
+
+type timeUnmarshaler struct {
+	loc *time.Location
+	// format is the unix timestamp option, or empty for the time layouts.
+	format string
+}
+
+func (u *timeUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
+	if u.format != "" {
+		n, err := strconv.ParseInt(ctx.value[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as %s time", ctx.value[0], u.format)
+		}
+
+		var val time.Time
+
+		switch u.format {
+		case "unix":
+			val = time.Unix(n, 0)
+		case "unixmilli":
+			val = time.UnixMilli(n)
+		default:
+			val = time.Unix(0, n)
+		}
+
+		dst.Set(reflect.ValueOf(val.In(u.loc)))
+
+		return nil
+	}
+
+	for _, layout := range timeLayouts {
+		if val, err := time.ParseInLocation(layout, ctx.value[0], u.loc); err == nil {
+			dst.Set(reflect.ValueOf(val))
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot parse %q as time", ctx.value[0])
+}
+
+func buildNewFunc(typ reflect.Type) func(dst reflect.Value) {
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return func(dst reflect.Value) {
+			if dst.IsNil() {
+				dst.Set(reflect.New(typ.Elem()))
+			}
+		}
+
+	case reflect.Map:
+		return func(dst reflect.Value) {
+			if dst.IsNil() {
+				dst.Set(reflect.MakeMap(typ))
+			}
+		}
+	}
+
+	return nil
+}
+
+func getIntSize(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int:
+		return strconv.IntSize
+	case reflect.Int64:
+		return 64
+	case reflect.Int32:
+		return 32
+	case reflect.Int16:
+		return 16
+	case reflect.Int8:
+		return 8
+	}
+
+	return -1
+}
+
+func getUintSize(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Uint:
+		return strconv.IntSize
+	case reflect.Uint64:
+		return 64
+	case reflect.Uint32:
+		return 32
+	case reflect.Uint16:
+		return 16
+	case reflect.Uint8:
+		return 8
+	}
+
+	return -1
+}
+
+func getFloatSize(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Float64:
+		return 64
+	case reflect.Float32:
+		return 32
+	}
+
+	return -1
+}
+
+func getComplexSize(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Complex128:
+		return 128
+	case reflect.Complex64:
+		return 64
+	}
+
+	return -1
+}
+
+// newScalarUnmarshaler returns the unmarshaler for types that are decoded from
+// a single value, which can also be used as a slice element.
+func newScalarUnmarshaler(cfg unmarshalConfig, typ reflect.Type) unmarshaler {
+	if codec, ok := findCodec(cfg.Codecs, typ); ok && codec.parse != nil {
+		return newCodecUnmarshaler(codec)
+	}
+
+	if cfg.Char {
+		switch typ.Kind() {
+		case reflect.Int32:
+			return &runeUnmarshaler{}
+		case reflect.Uint8:
+			return &byteUnmarshaler{}
+		}
+
+		return nil
+	}
+
+	if typ == timeReflectType {
+		return &timeUnmarshaler{loc: cfg.location(), format: cfg.TimeFormat}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return &stringUnmarshaler{}
+	case reflect.Bool:
+		return &boolUnmarshaler{
+			trueValues:  cfg.TrueValues,
+			falseValues: cfg.FalseValues,
+		}
+	}
+
+	if bitSize := getIntSize(typ.Kind()); bitSize > 0 {
+		return &intUnmarshaler{
+			bitSize: bitSize,
+			parse:   cfg.parseInt(),
+		}
+	}
+
+	if bitSize := getUintSize(typ.Kind()); bitSize > 0 {
+		return &uintUnmarshaler{
+			bitSize: bitSize,
+			parse:   cfg.ParseIntFunc,
+		}
+	}
+
+	if bitSize := getFloatSize(typ.Kind()); bitSize > 0 {
+		return &floatUnmarshaler{
+			bitSize: bitSize,
+			parse:   cfg.parseFloat(),
+		}
+	}
+
+	if bitSize := getComplexSize(typ.Kind()); bitSize > 0 {
+		return &complexUnmarshaler{bitSize: bitSize}
+	}
+
+	return nil
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Pointer {
+		return typ.Elem()
+	}
+
+	return typ
+}
+
+func isSliceType(typ reflect.Type) bool {
+	return indirectType(typ).Kind() == reflect.Slice
+}
+
+// isStructType reports whether typ is unmarshaled as a nested struct.
+func isStructType(cfg unmarshalConfig, typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct || typ == timeReflectType {
+		return false
+	}
+
+	if _, ok := findCodec(cfg.Codecs, typ); ok {
+		return false
+	}
+
+	if ok, _ := implementsMethod(typ, mapUnmarshalerReflectType); ok {
+		return false
+	}
+
+	return !typ.Implements(valueUnmarshalerReflectType) &&
+		!reflect.PointerTo(typ).Implements(valueUnmarshalerReflectType)
+}
+
+// isStructSliceElem reports whether typ is a struct or a pointer to struct
+// that makes its slice unmarshaled as an indexed slice.
+func isStructSliceElem(cfg unmarshalConfig, typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		if _, ok := findCodec(cfg.Codecs, typ); ok {
+			return false
+		}
+	}
+
+	return isStructType(cfg, indirectType(typ))
+}
+
+func newSliceUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	elem := typ.Elem()
+
+	if unm := newScalarUnmarshaler(cfg, elem); unm != nil {
+		sep := cfg.Separator
+		if sep == "" && cfg.SliceStyle == SliceJoined {
+			sep = cfg.sliceSeparator()
+		}
+
+		return &sliceUnmarshaler{
+			typ:      typ,
+			elem:     unm,
+			maxLen:   cfg.MaxSliceLen,
+			list:     cfg.List,
+			sep:      sep,
+			unique:   cfg.Unique,
+			minItems: cfg.minItems,
+			maxItems: cfg.maxItems,
+		}, nil
+	}
+
+	if cfg.Char {
+		return nil, errInvalidChar
+	}
+
+	return nil, newUnsupportedTypeError(elem, "cannot unmarshal into slice of %s", elem.Kind().String())
+}
+
+func newValueUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unm unmarshaler, nested bool, err error) {
+	if codec, ok := findCodec(cfg.Codecs, typ); ok {
+		if codec.parse == nil {
+			return nil, false, newUnsupportedTypeError(typ, "cannot unmarshal into %s", typ.String())
+		}
+
+		return newCodecUnmarshaler(codec), false, nil
+	}
+
+	if unm, ok := newMapMethodUnmarshaler(cfg, typ); ok {
+		return unm, true, nil
+	}
+
+	var valReceiver bool
+
+	switch {
+	case typ.Implements(valueUnmarshalerReflectType):
+		valReceiver = true
+
+		fallthrough
+
+	case reflect.PointerTo(typ).Implements(valueUnmarshalerReflectType):
+		return &methodUnmarshaler{
+			newFn:       buildNewFunc(typ),
+			ptrReceiver: !valReceiver,
+		}, false, nil
+	}
+
+	if cfg.Structured && typ.Kind() != reflect.Pointer {
+		unm, err := newStructuredUnmarshaler(cfg, typ)
+
+		return unm, false, err
+	}
+
+	if unm := newScalarUnmarshaler(cfg, typ); unm != nil {
+		return unm, false, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		unm, nested, err := newValueUnmarshaler(cfg, typ.Elem())
+		if err != nil {
+			return nil, false, err
+		}
+
+		return &pointerUnmarshaler{
+			elemTyp: typ.Elem(),
+			elem:    unm,
+		}, nested, nil
+
+	case reflect.Struct:
+		if cfg.Char {
+			return nil, false, errInvalidChar
+		}
+
+		if cfg.params != nil {
+			unm, err := newParamsUnmarshaler(cfg, typ)
+
+			return unm, false, err
+		}
+
+		unm, err := newStructUnmarshaler(cfg, typ)
+
+		return unm, true, err
+
+	case reflect.Slice:
+		if cfg.params != nil {
+			unm, err := newParamsUnmarshaler(cfg, typ)
+
+			return unm, false, err
+		}
+
+		if isStructSliceElem(cfg, typ.Elem()) {
+			unm, err := newIndexedSliceUnmarshaler(cfg, typ)
+
+			return unm, true, err
+		}
+
+		unm, err := newSliceUnmarshaler(cfg, typ)
+
+		return unm, false, err
+
+	case reflect.Interface:
+		return newInterfaceUnmarshaler(cfg, typ)
+
+	case reflect.Map:
+		if cfg.PrefixMap {
+			unm, err := newPrefixMapUnmarshaler(cfg, typ)
+
+			return unm, true, err
+		}
+
+		if typ.Key().Kind() == reflect.String && isStructSliceElem(cfg, typ.Elem()) {
+			unm, err := newStructMapUnmarshaler(cfg, typ)
+
+			return unm, true, err
+		}
+	}
+
+	if cfg.Char {
+		return nil, false, errInvalidChar
+	}
+
+	return nil, false, newUnsupportedTypeError(typ, "cannot unmarshal into %s", typ.Kind().String())
+}
+
+func newFieldUnmarshaler(cfg unmarshalConfig, structFld reflect.StructField) (fieldUnmarshaler, error) {
+	name, opts := ParseTag(structFld.Tag.Get(cfg.tagName()))
+
+	// Follow the encoding/json standard where a field can still be named "-"
+	// by using a comma suffix.
+	if name == "-" && len(opts) == 0 {
+		return fieldUnmarshaler{}, errSkipField
+	}
+
+	prefix := cfg.Prefix
+	if name != "" {
+		prefix = append(prefix, name)
+	} else if !structFld.Anonymous {
+		prefix = append(prefix, cfg.fieldName(structFld))
+	}
+
+	fieldCfg := unmarshalConfig{
+		UnmarshalConfig: cfg.UnmarshalConfig,
+		Prefix:          prefix,
+		Source:          cfg.Source,
+		depth:           cfg.depth,
+		fields:          cfg.fields,
+	}
+
+	for _, opt := range opts {
+		if err := fieldCfg.applyOption(opt, structFld.Type); err != nil {
+			return fieldUnmarshaler{}, err
+		}
+	}
+
+	if fieldCfg.styled {
+		if err := fieldCfg.applyStyle(); err != nil {
+			return fieldUnmarshaler{}, err
+		}
+	}
+
+	if fieldCfg.PrefixMap && indirectType(structFld.Type).Kind() != reflect.Map {
+		return fieldUnmarshaler{}, errInvalidPrefix
+	}
+
+	if fieldCfg.List && !isSliceType(structFld.Type) {
+		return fieldUnmarshaler{}, errInvalidList
+	}
+
+	if fieldCfg.Unique && !isSliceType(structFld.Type) {
+		return fieldUnmarshaler{}, errInvalidUnique
+	}
+
+	if (fieldCfg.minItems > 0 || fieldCfg.maxItems > 0) && !isSliceType(structFld.Type) {
+		return fieldUnmarshaler{}, errInvalidItems
+	}
+
+	if fieldCfg.maxItems > 0 && fieldCfg.minItems > fieldCfg.maxItems {
+		return fieldUnmarshaler{}, errors.New("minitems option cannot be greater than maxitems")
+	}
+
+	if fieldCfg.params != nil && !fieldCfg.params.validType(structFld.Type) {
+		return fieldUnmarshaler{}, fieldCfg.params.invalid
+	}
+
+	if fieldCfg.MimeValue && !cfg.mimeStruct {
+		return fieldUnmarshaler{}, errInvalidValue
+	}
+
+	if fieldCfg.Token68 && !cfg.authStruct {
+		return fieldUnmarshaler{}, errInvalidToken68
+	}
+
+	field := fieldUnmarshaler{
+		required: fieldCfg.Required,
+		index:    structFld.Index[len(structFld.Index)-1],
+		defaults: fieldCfg.Defaults,
+		escaped:  fieldCfg.Escape,
+		secret:   fieldCfg.Secret,
+		source:   fieldCfg.Source,
+		field:    structFld.Name,
+	}
+
+	var err error
+	if fieldCfg.Count {
+		if field.unmarshaler, err = newCountUnmarshaler(fieldCfg, structFld.Type); err != nil {
+			return fieldUnmarshaler{}, err
+		}
+	} else if field.unmarshaler, field.nested, err = newValueUnmarshaler(fieldCfg, structFld.Type); err != nil {
+		if skipUnsupported(cfg.SkipUnsupported, err) {
+			return fieldUnmarshaler{}, errSkipField
+		}
+
+		setUnsupportedField(err, structFld.Name)
+
+		return fieldUnmarshaler{}, fmt.Errorf("struct field %s: %w", structFld.Name, err)
+	}
+
+	if field.nested {
+		// The prefix map checks the required option by itself, as it has no
+		// single key to look for.
+		if _, ok := field.unmarshaler.(*prefixMapUnmarshaler); fieldCfg.Required && !ok {
+			return fieldUnmarshaler{}, errors.New("cannot set required option for struct")
+		}
+
+		if fieldCfg.Defaults != nil {
+			return fieldUnmarshaler{}, errors.New("cannot set default option for struct")
+		}
+
+		if fieldCfg.Source == sourcePath {
+			return fieldUnmarshaler{}, errors.New("cannot set path option for struct")
+		}
+
+		if fieldCfg.Escape {
+			return fieldUnmarshaler{}, errors.New("cannot set escape option for struct")
+		}
+
+		if fieldCfg.aliases != nil {
+			return fieldUnmarshaler{}, errors.New("cannot set alias option for struct")
+		}
+
+		if fieldCfg.conditions != nil {
+			return fieldUnmarshaler{}, errors.New("cannot set conditional required option for struct")
+		}
+
+		if fieldCfg.groups != nil {
+			return fieldUnmarshaler{}, errors.New("cannot set group option for struct")
+		}
+
+		if fieldCfg.validators != nil {
+			return fieldUnmarshaler{}, errors.New("cannot set validate option for struct")
+		}
+
+		if fieldCfg.minItems > 0 || fieldCfg.maxItems > 0 {
+			return fieldUnmarshaler{}, errors.New("cannot set items option for struct")
+		}
+
+		return field, nil
+	}
+
+	// Make sure that the default values are valid before they are used. They
+	// can only be checked on use when they are transformed by the ValueFunc.
+	if field.defaults != nil && cfg.ValueFunc == nil {
+		scratch := reflect.New(structFld.Type).Elem()
+
+		if err := field.unmarshaler.unmarshal(unmarshalContext{value: field.defaults}, nil, scratch); err != nil {
+			return fieldUnmarshaler{}, fmt.Errorf("struct field %s: invalid default value: %w", structFld.Name, err)
+		}
+	}
+
+	if structFld.Anonymous && name == "" {
+		prefix = append(prefix, cfg.fieldName(structFld))
+	}
+
+	field.name = fieldCfg.lookupKey(fieldCfg.joinKey(prefix))
+
+	for _, alias := range fieldCfg.aliases {
+		alias.name = fieldCfg.lookupKey(fieldCfg.joinKey(append(cfg.Prefix[:len(cfg.Prefix):len(cfg.Prefix)], alias.name)))
+		if field.source == sourceHeader {
+			alias.name = http.CanonicalHeaderKey(alias.name)
+		}
+
+		field.aliases = append(field.aliases, alias)
+	}
+
+	if fieldCfg.conditions != nil && (fieldCfg.Source != "" || fieldCfg.Required) {
+		return fieldUnmarshaler{}, errors.New("conditional required option cannot be combined with the required or source options")
+	}
+
+	if fieldCfg.groups != nil && fieldCfg.Source != "" {
+		return fieldUnmarshaler{}, errors.New("group option cannot be combined with the source options")
+	}
+
+	field.groups = fieldCfg.groups
+
+	for _, name := range fieldCfg.validators {
+		fn, ok := cfg.Validators[name]
+		if !ok {
+			return fieldUnmarshaler{}, fmt.Errorf("unknown validator %s", name)
+		}
+
+		field.validators = append(field.validators, fieldValidator{name: name, fn: fn})
+	}
+
+	for _, cond := range fieldCfg.conditions {
+		cond.key = fieldCfg.lookupKey(fieldCfg.joinKey(append(cfg.Prefix[:len(cfg.Prefix):len(cfg.Prefix)], cond.key)))
+		field.conditions = append(field.conditions, cond)
+	}
+	field.nullValues = cfg.NullValues
+
+	if field.source == sourceHeader {
+		field.name = http.CanonicalHeaderKey(field.name)
+	}
+
+	if fieldCfg.Brackets && isSliceType(structFld.Type) && !field.nested {
+		field.name += "[]"
+	}
+
+	if structFld.Type.Kind() == reflect.Slice {
+		field.slice = structFld.Type
+	}
+
+	if isSliceType(structFld.Type) {
+		name := field.name
+
+		switch {
+		case fieldCfg.Numbered:
+			field.indexKey = func(i int) string {
+				return name + strconv.Itoa(i)
+			}
+
+		case cfg.BracketIndex:
+			field.indexKey = func(i int) string {
+				return name + "[" + strconv.Itoa(i) + "]"
+			}
+
+		case fieldCfg.SliceStyle == SliceIndexed:
+			delim := cfg.delimiter()
+
+			field.indexKey = func(i int) string {
+				return name + delim + strconv.Itoa(i)
+			}
+		}
+	}
+
+	if fieldCfg.MimeValue {
+		field.name = mimeValueKey
+	}
+
+	if fieldCfg.Token68 {
+		field.name = authToken68Key
+	}
+
+	return field, nil
+}
+
+func newStructUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	var (
+		fields      []fieldUnmarshaler
+		groups      []fieldGroup
+		conditional bool
+	)
+
+	cfg.depth++
+	if cfg.MaxDepth > 0 && cfg.depth > cfg.MaxDepth {
+		return nil, &LimitError{Limit: "MaxDepth", Max: cfg.MaxDepth}
+	}
+
+	n := typ.NumField()
+	for i := 0; i < n; i++ {
+		field, err := newFieldUnmarshaler(cfg, typ.Field(i))
+
+		if errors.Is(err, errSkipField) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cfg.countField(); err != nil {
+			return nil, err
+		}
+
+		if field.conditions != nil {
+			conditional = true
+		}
+
+		for _, opt := range field.groups {
+			i := slices.IndexFunc(groups, func(g fieldGroup) bool { return g.fieldGroupOption == opt })
+			if i < 0 {
+				i = len(groups)
+				groups = append(groups, fieldGroup{fieldGroupOption: opt})
+			}
+
+			groups[i].fields = append(groups[i].fields, len(fields))
+		}
+
+		fields = append(fields, field)
+	}
+
+	direct := make(map[string][]int)
+
+	for i, field := range fields {
+		if field.isDirect() {
+			direct[field.name] = append(direct[field.name], i)
+		}
+	}
+
+	return &structUnmarshaler{
+		fields:      fields,
+		direct:      direct,
+		conditional: conditional,
+		groups:      groups,
+	}, nil
+}
+
+// KeyFold selects how the keys are normalized before they are matched, so
+// the spelling variations of a key bind into the same field. The flags can be
+// combined.
+type KeyFold uint
+
+const (
+	// KeyFoldSeparators treats '-' and '_' as the same character, so
+	// "created_after" and "created-after" are the same key.
+	KeyFoldSeparators KeyFold = 1 << iota
+
+	// KeyFoldStripSeparators removes '-' and '_' entirely, so "createdafter"
+	// is also the same key.
+	KeyFoldStripSeparators
+
+	// KeyFoldCase matches the keys using the Unicode case folding, with the
+	// same semantics as strings.EqualFold.
+	KeyFoldCase
+)
+
+var separatorStripper = strings.NewReplacer("-", "", "_", "")
+
+func (f KeyFold) fold(s string) string {
+	if f&KeyFoldStripSeparators != 0 {
+		s = separatorStripper.Replace(s)
+	} else if f&KeyFoldSeparators != 0 {
+		s = strings.ReplaceAll(s, "_", "-")
+	}
+
+	if f&KeyFoldCase != 0 {
+		s = strings.Map(foldRune, s)
+	}
+
+	return s
+}
+
+// foldRune returns the smallest rune in the case folding orbit of r, so all
+// runes that are equal under strings.EqualFold map into the same rune.
+func foldRune(r rune) rune {
+	folded := r
+
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded {
+			folded = f
+		}
+	}
+
+	return folded
+}
+
+// foldKeys returns v with its keys folded. The values of the keys that fold
+// into the same key are concatenated in the order of the original keys, so
+// the same input always binds the same values.
+func (f KeyFold) foldKeys(v map[string][]string) map[string][]string {
+	if f == 0 || v == nil {
+		return v
+	}
+
+	folded := make(map[string][]string, len(v))
+
+	for _, key := range slices.Sorted(maps.Keys(v)) {
+		foldedKey := f.fold(key)
+		folded[foldedKey] = append(folded[foldedKey], v[key]...)
+	}
+
+	return folded
+}
+
+type UnmarshalConfig struct {
+	Delimiter     string
+	KeyLookupFunc func(s string) string
+
+	// TagName is the struct tag to read the field options from. Defaults to
+	// "map".
+	TagName string
+
+	// JoinKeyFunc builds the key of a nested field from the name of each
+	// level, which overrides the Delimiter.
+	JoinKeyFunc func(names []string) string
+
+	// BracketIndex uses "key[0]" instead of "key.0" for the indexed slice
+	// elements. The values of a non-struct slice can then also be given as
+	// "key[0]", "key[1]" and so on, in addition to the repeated key.
+	BracketIndex bool
+
+	// NullValues lists the sentinel values (e.g. "null" or "-") that are treated
+	// as if the key is absent, which resets the field into its zero value.
+	NullValues []string
+
+	// MaxSliceLen limits the number of values that can be unmarshaled into a
+	// slice field. Zero means no limit.
+	MaxSliceLen int
+
+	// MaxValueLen limits the byte length of each value. Zero means no limit.
+	MaxValueLen int
+
+	// MaxTotalLen limits the total byte length of all values consumed in a
+	// single Unmarshal call. Zero means no limit.
+	MaxTotalLen int
+
+	// MaxDepth limits the struct nesting depth of the target type, where the
+	// top-level struct counts as one. Zero means no limit.
+	MaxDepth int
+
+	// MaxFields limits the total number of fields compiled for the target type,
+	// including the fields of nested structs. Zero means no limit.
+	MaxFields int
+
+	// Location is used to parse time values without zone information. Defaults
+	// to UTC.
+	Location *time.Location
+
+	// ParseIntFunc and ParseFloatFunc override the default strconv parsing for
+	// integer and floating-point values, e.g. to accept thousand separators
+	// from localized input. ParseIntFunc also parses the unsigned integers,
+	// which are then limited to the int64 range.
+	ParseIntFunc   func(s string, bitSize int) (int64, error)
+	ParseFloatFunc func(s string, bitSize int) (float64, error)
+
+	// TrueValues and FalseValues are the extra literals that are accepted as
+	// booleans in addition to the strconv.ParseBool ones, matched without
+	// case, e.g. "on" and "off" from the HTML checkboxes.
+	TrueValues  []string
+	FalseValues []string
+
+	// Interfaces registers the candidate concrete types for interface-typed
+	// fields.
+	Interfaces []Interface
+
+	// Codecs registers the conversion for types that are not supported
+	// natively. When there are multiple codecs for the same type, the last one
+	// is used.
+	Codecs []Codec
+
+	// Options registers the custom tag options.
+	Options []Option
+
+	// Style and Explode select an OpenAPI 3 parameter serialization style
+	// for all fields, which sets the SliceStyle, SliceSeparator and, for the
+	// deepObject style, the JoinKeyFunc.
+	Style   ParamStyle
+	Explode Explode
+
+	// NameFunc derives the key of the fields without a name in their tag from
+	// their Go name, e.g. SnakeCase or LowerCamel. Defaults to the Go name
+	// as-is.
+	NameFunc func(fieldName string) string
+
+	// KeyFuncs registers the key transforms that can be selected per field
+	// using the "keyfunc" option, in addition to the built-in "canonical",
+	// "lower", "upper" and "none". The selected one replaces KeyLookupFunc for
+	// the field.
+	KeyFuncs map[string]func(s string) string
+
+	// SkipUnsupported skips the fields whose type cannot be unmarshaled (e.g.
+	// funcs or channels) instead of failing the whole struct.
+	SkipUnsupported bool
+
+	// SliceStyle is the default layout of slice fields, which can be
+	// overridden using the "repeated", "indexed" or "joined" options.
+	SliceStyle SliceStyle
+
+	// SliceSeparator separates the joined slice values. Defaults to ",".
+	SliceSeparator string
+
+	// KeyFold normalizes both the input keys and the field keys before they
+	// are matched, which is useful for forgiving public APIs. The request
+	// headers are never folded, since they are already canonicalized.
+	KeyFold KeyFold
+
+	// TraceFunc is called with the key, the Go field path and the values
+	// found for every unmarshaled field, including the missing ones, which
+	// helps to debug how a confusing input is bound.
+	TraceFunc func(key, fieldPath string, values []string)
+
+	// ValueFunc transforms each value of a non-nested field before it is
+	// parsed, including the default values, e.g. ExpandEnv to substitute the
+	// environment variables in config-style sources.
+	ValueFunc func(key, value string) (string, error)
+
+	// ValueCheckFunc is called with the input values of every non-nested
+	// field before they are parsed, e.g. to limit their length or reject the
+	// forbidden characters for all fields at once. The default values are not
+	// checked.
+	ValueCheckFunc func(key string, values []string) error
+
+	// Validators registers the validators that can be selected per field using
+	// the "validate" option, see RegisterValidator.
+	Validators map[string]ValidatorFunc
+
+	// KeyRewrites renames the input keys before they are matched, e.g. to
+	// translate the legacy parameter names of an upstream service. A renamed
+	// key does not override the values given under the new name, and the keys
+	// renamed into the same key are taken in their sorted order. The input map
+	// itself is never modified.
+	KeyRewrites map[string]string
+
+	// DeprecatedFunc is called when a field is read from one of its aliases
+	// that is marked with the "deprecated" option, e.g. to count the clients
+	// that still use the old key before it is retired.
+	DeprecatedFunc func(key, alias string)
+}
+
+func (cfg UnmarshalConfig) sliceSeparator() string {
+	if cfg.SliceSeparator != "" {
+		return cfg.SliceSeparator
+	}
+
+	return ","
+}
+
+func (cfg UnmarshalConfig) parseInt() func(s string, bitSize int) (int64, error) {
+	if cfg.ParseIntFunc != nil {
+		return cfg.ParseIntFunc
+	}
+
+	return func(s string, bitSize int) (int64, error) {
+		return strconv.ParseInt(s, 10, bitSize)
+	}
+}
+
+func (cfg UnmarshalConfig) parseFloat() func(s string, bitSize int) (float64, error) {
+	if cfg.ParseFloatFunc != nil {
+		return cfg.ParseFloatFunc
+	}
+
+	return strconv.ParseFloat
+}
+
+func (cfg UnmarshalConfig) fieldName(structFld reflect.StructField) string {
+	if cfg.NameFunc != nil {
+		return cfg.NameFunc(structFld.Name)
+	}
+
+	return structFld.Name
+}
+
+func (cfg UnmarshalConfig) tagName() string {
+	if cfg.TagName != "" {
+		return cfg.TagName
+	}
 
-	return nil
+	return "map"
 }
 
-type methodUnmarshaler struct {
-	newFn       func(dst reflect.Value)
-	ptrReceiver bool
+func (cfg UnmarshalConfig) location() *time.Location {
+	if cfg.Location != nil {
+		return cfg.Location
+	}
+
+	return time.UTC
 }
 
-func (u *methodUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
-	if u.newFn != nil {
-		u.newFn(dst)
+func (cfg UnmarshalConfig) newContext() unmarshalContext {
+	ctx := unmarshalContext{
+		trace:      cfg.TraceFunc,
+		deprecated: cfg.DeprecatedFunc,
+		valueFunc:  cfg.ValueFunc,
+		valueCheck: cfg.ValueCheckFunc,
 	}
 
-	if u.ptrReceiver {
-		// TODO: Make sure that this is guaranteed to be addressable or not.
-		// The non-nil pointer check already in place at the top-level function
-		// so it must always be addressable.
-		dst = dst.Addr()
+	if cfg.MaxValueLen > 0 || cfg.MaxTotalLen > 0 {
+		ctx.budget = &valueBudget{
+			maxValueLen: cfg.MaxValueLen,
+			maxTotalLen: cfg.MaxTotalLen,
+		}
 	}
 
-	return dst.Interface().(ValueUnmarshaler).UnmarshalValue(ctx.value)
-}
-
-type sliceUnmarshaler struct {
-	typ     reflect.Type
-	bitSize int
+	return ctx
 }
 
-func (u *sliceUnmarshaler) unmarshal(ctx unmarshalContext, _ map[string][]string, dst reflect.Value) error {
-	if dst.Cap() < len(ctx.value) {
-		dst.Set(reflect.MakeSlice(u.typ, len(ctx.value), len(ctx.value)))
-	} else if dst.Len() != len(ctx.value) {
-		dst.SetLen(len(ctx.value))
+func (cfg UnmarshalConfig) delimiter() string {
+	if cfg.Delimiter != "" {
+		return cfg.Delimiter
 	}
 
-	for i := 0; i < len(ctx.value); i++ {
-		if u.bitSize > 0 {
-			val, err := strconv.ParseInt(ctx.value[i], 10, u.bitSize)
-			if err != nil {
-				return fmt.Errorf("int slice index #%d: %w", i, err)
-			}
+	return "."
+}
 
-			dst.Index(i).SetInt(val)
-		} else {
-			dst.Index(i).SetString(ctx.value[i])
-		}
+func (cfg UnmarshalConfig) joinKey(names []string) string {
+	if cfg.JoinKeyFunc != nil {
+		return cfg.JoinKeyFunc(names)
 	}
 
-	return nil
+	return strings.Join(names, cfg.delimiter())
 }
 
-func buildNewFunc(typ reflect.Type) func(dst reflect.Value) {
-	switch typ.Kind() {
-	case reflect.Pointer:
-		return func(dst reflect.Value) {
-			if dst.IsNil() {
-				dst.Set(reflect.New(typ.Elem()))
+// inputKeys applies the KeyRewrites and then the KeyFold into the keys of v,
+// returning v as-is when there is nothing to apply.
+func (cfg UnmarshalConfig) inputKeys(v map[string][]string) map[string][]string {
+	if len(cfg.KeyRewrites) > 0 && v != nil {
+		rewritten := make(map[string][]string, len(v))
+
+		for key, vals := range v {
+			if _, ok := cfg.KeyRewrites[key]; !ok {
+				rewritten[key] = vals
 			}
 		}
 
-	case reflect.Map:
-		return func(dst reflect.Value) {
-			if dst.IsNil() {
-				dst.Set(reflect.MakeMap(typ))
+		// Rewrite in order, so the first of the keys renamed into the same
+		// key always wins.
+		for _, key := range slices.Sorted(maps.Keys(cfg.KeyRewrites)) {
+			vals, ok := v[key]
+			if !ok {
+				continue
+			}
+
+			to := cfg.KeyRewrites[key]
+			if _, exists := rewritten[to]; !exists {
+				rewritten[to] = vals
 			}
 		}
+
+		v = rewritten
 	}
 
-	return nil
+	return cfg.KeyFold.foldKeys(v)
 }
 
-func getIntSize(kind reflect.Kind) int {
-	switch kind {
-	case reflect.Int:
-		return strconv.IntSize
-	case reflect.Int64:
-		return 64
-	case reflect.Int32:
-		return 32
-	case reflect.Int16:
-		return 16
-	case reflect.Int8:
-		return 8
-	}
-
-	return -1
+type unmarshalConfig struct {
+	UnmarshalConfig
+	Prefix     []string
+	Required   bool
+	Char       bool
+	Defaults   []string
+	Source     string
+	PrefixMap  bool
+	Escape     bool
+	List       bool
+	Separator  string
+	Brackets   bool
+	Numbered   bool
+	TimeFormat string
+	Structured bool
+	MimeValue  bool
+	Token68    bool
+	mimeStruct bool
+	authStruct bool
+	params     *paramsFormat
+	Secret     bool
+	Unique     bool
+	Count      bool
+	styled     bool
+	aliases    []fieldAlias
+	conditions []fieldCondition
+	groups     []fieldGroupOption
+	validators []string
+	minItems   int
+	maxItems   int
+	depth      int
+	fields     *int
 }
 
-func newSliceUnmarshaler(typ reflect.Type) (unmarshaler, error) {
-	elem := typ.Elem()
+func (cfg *unmarshalConfig) applyOption(opt TagOption, typ reflect.Type) error {
+	// Follow the gorilla/schema convention where multiple default values are
+	// separated by a pipe.
+	if opt.Name == "default" {
+		cfg.Defaults = strings.Split(opt.Value, "|")
 
-	if elem.Kind() == reflect.String {
-		return &sliceUnmarshaler{typ: typ}, nil
+		return nil
 	}
 
-	if bitSize := getIntSize(elem.Kind()); bitSize > 0 {
-		return &sliceUnmarshaler{
-			typ:     typ,
-			bitSize: bitSize,
-		}, nil
-	}
+	switch opt.Name {
+	case "alias", "deprecated":
+		if opt.Value == "" {
+			return fmt.Errorf("option %s requires a key", opt.Name)
+		}
 
-	return nil, fmt.Errorf("cannot unmarshal into slice of %s", elem.Kind().String())
-}
+		cfg.aliases = append(cfg.aliases, fieldAlias{name: opt.Value, deprecated: opt.Name == "deprecated"})
 
-func newValueUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unm unmarshaler, nested bool, err error) {
-	var valReceiver bool
+		return nil
 
-	switch {
-	case typ.Implements(valueUnmarshalerReflectType):
-		valReceiver = true
+	case "required_with", "required_without", "required_if":
+		cond := fieldCondition{option: opt.Name, key: opt.Value}
+		if opt.Name == "required_if" {
+			var ok bool
+			if cond.key, cond.value, ok = strings.Cut(opt.Value, " "); !ok {
+				return errors.New("option required_if requires a key and a value separated by a space")
+			}
+		}
 
-		fallthrough
+		if cond.key == "" {
+			return fmt.Errorf("option %s requires a key", opt.Name)
+		}
 
-	case reflect.PointerTo(typ).Implements(valueUnmarshalerReflectType):
-		return &methodUnmarshaler{
-			newFn:       buildNewFunc(typ),
-			ptrReceiver: !valReceiver,
-		}, false, nil
-	}
+		cfg.conditions = append(cfg.conditions, cond)
 
-	switch typ.Kind() {
-	case reflect.Pointer:
-		unm, nested, err := newValueUnmarshaler(cfg, typ.Elem())
-		if err != nil {
-			return nil, false, err
+		return nil
+
+	case "validate":
+		if opt.Value == "" {
+			return errors.New("option validate requires a validator name")
 		}
 
-		return &pointerUnmarshaler{
-			elemTyp: typ.Elem(),
-			elem:    unm,
-		}, nested, nil
+		cfg.validators = append(cfg.validators, opt.Value)
 
-	case reflect.Struct:
-		unm, err := newStructUnmarshaler(cfg, typ)
+		return nil
 
-		return unm, true, err
+	case "xor", "anyof":
+		if opt.Value == "" {
+			return fmt.Errorf("option %s requires a group name", opt.Name)
+		}
 
-	case reflect.String:
-		return &stringUnmarshaler{}, false, nil
+		cfg.groups = append(cfg.groups, fieldGroupOption{option: opt.Name, name: opt.Value})
 
-	case reflect.Slice:
-		unm, err := newSliceUnmarshaler(typ)
+		return nil
 
-		return unm, false, err
-	}
+	case "minitems", "maxitems":
+		n, err := strconv.Atoi(opt.Value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("option %s requires a positive number", opt.Name)
+		}
 
-	if intSize := getIntSize(typ.Kind()); intSize > 0 {
-		return &intUnmarshaler{
-			bitSize: intSize,
-		}, false, nil
-	}
+		if opt.Name == "minitems" {
+			cfg.minItems = n
+		} else {
+			cfg.maxItems = n
+		}
 
-	return nil, false, fmt.Errorf("cannot unmarshal into %s", typ.Kind().String())
-}
+		return nil
 
-func newFieldUnmarshaler(cfg unmarshalConfig, structFld reflect.StructField) (fieldUnmarshaler, error) {
-	tag := strings.Split(structFld.Tag.Get("map"), ",")
-	name := tag[0]
+	case "style":
+		cfg.Style, cfg.styled = ParamStyle(opt.Value), true
 
-	// Follow the encoding/json standard where a field can still be named "-"
-	// by using a comma suffix.
-	if name == "-" && len(tag) == 1 {
-		return fieldUnmarshaler{}, errSkipField
-	}
+		return nil
 
-	field := fieldUnmarshaler{
-		index: structFld.Index[len(structFld.Index)-1],
+	case "explode":
+		explode, err := parseExplode(opt.Value)
+		if err != nil {
+			return err
+		}
+
+		cfg.Explode, cfg.styled = explode, true
+
+		return nil
 	}
 
-	for i := 1; i < len(tag); i++ {
-		if err := field.applyOption(tag[i]); err != nil {
-			return fieldUnmarshaler{}, err
+	if opt.Name == "keyfunc" {
+		fn, err := findKeyFunc(cfg.KeyFuncs, opt.Value)
+		if err != nil {
+			return err
 		}
-	}
 
-	prefix := cfg.Prefix
-	if name != "" {
-		prefix = append(prefix, name)
-	} else if !structFld.Anonymous {
-		prefix = append(prefix, structFld.Name)
-	}
+		cfg.KeyLookupFunc = fn
 
-	var err error
-	if field.unmarshaler, field.nested, err = newValueUnmarshaler(unmarshalConfig{
-		UnmarshalConfig: cfg.UnmarshalConfig,
-		Prefix:          prefix,
-	}, structFld.Type); err != nil {
-		return fieldUnmarshaler{}, fmt.Errorf("struct field %s: %w", structFld.Name, err)
+		return nil
 	}
 
-	if field.nested {
-		if field.required {
-			return fieldUnmarshaler{}, errors.New("cannot set required option for struct")
+	if _, ok := findOption(cfg.Options, opt.Name); ok {
+		codecs, err := applyCustomOption(cfg.Options, cfg.Codecs, opt, typ)
+		if err != nil {
+			return err
 		}
 
-		return field, nil
-	}
+		cfg.Codecs = codecs
 
-	if structFld.Anonymous && name == "" {
-		prefix = append(prefix, structFld.Name)
+		return nil
 	}
 
-	field.name = strings.Join(prefix, cfg.delimiter())
+	switch opt.Name {
+	case "required":
+		cfg.Required = true
+	case "char":
+		cfg.Char = true
+	case sourceQuery, sourceHeader, sourcePath, sourceForm, sourceCookie, sourceFragment:
+		cfg.Source = opt.Name
+	case "prefix":
+		cfg.PrefixMap = true
+	case "escape":
+		cfg.Escape = true
+	case "secret":
+		cfg.Secret = true
+	case "unique":
+		cfg.Unique = true
+	case "count":
+		cfg.Count = true
+	case "list":
+		cfg.List = true
+	case "comma":
+		cfg.Separator = ","
+	case "space":
+		cfg.Separator = " "
+	case "semicolon":
+		cfg.Separator = ";"
+	case "brackets":
+		cfg.Brackets = true
+	case "numbered":
+		cfg.Numbered = true
+	case "unix", "unixmilli", "unixnano":
+		cfg.TimeFormat = opt.Name
+	case "int":
+		// The bools already accept "1" and "0".
+	case "repeated":
+		cfg.SliceStyle = SliceRepeated
+	case "indexed":
+		cfg.SliceStyle = SliceIndexed
+	case "joined":
+		cfg.SliceStyle = SliceJoined
+	case "mime", "auth", "link", "forwarded", "directives":
+		return cfg.setParams(paramsFormats[opt.Name])
+	case "structured":
+		cfg.Structured = true
+	case "value":
+		cfg.MimeValue = true
+	case "token68":
+		cfg.Token68 = true
+	case "omitempty", "keepzero", "sorted":
+		// This option is only valid for marhsaler so it will be ignored.
+	case "":
+		// Allow empty option.
+	default:
+		return fmt.Errorf("unknown option %s", opt.Name)
+	}
 
-	if cfg.KeyLookupFunc != nil {
-		field.name = cfg.KeyLookupFunc(field.name)
+	if opt.Value != "" {
+		return fmt.Errorf("option %s does not take a value", opt.Name)
 	}
 
-	return field, nil
+	return nil
 }
 
-func newStructUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
-	var fields []fieldUnmarshaler
-
-	n := typ.NumField()
-	for i := 0; i < n; i++ {
-		field, err := newFieldUnmarshaler(cfg, typ.Field(i))
-
-		if errors.Is(err, errSkipField) {
-			continue
-		}
+func newUnmarshalConfig(cfg UnmarshalConfig) unmarshalConfig {
+	return unmarshalConfig{
+		UnmarshalConfig: cfg,
+		fields:          new(int),
+	}
+}
 
-		if err != nil {
-			return nil, err
-		}
+// lookupKey applies the KeyLookupFunc and then the KeyFold into key. The
+// header and path keys are not folded since they are matched as-is.
+func (cfg unmarshalConfig) lookupKey(key string) string {
+	if cfg.KeyLookupFunc != nil {
+		key = cfg.KeyLookupFunc(key)
+	}
 
-		fields = append(fields, field)
+	if cfg.Source != sourceHeader && cfg.Source != sourcePath {
+		key = cfg.KeyFold.fold(key)
 	}
 
-	return &structUnmarshaler{
-		fields: fields,
-	}, nil
+	return key
 }
 
-type UnmarshalConfig struct {
-	Delimiter     string
-	KeyLookupFunc func(s string) string
-}
+func (cfg *unmarshalConfig) countField() error {
+	*cfg.fields++
 
-func (cfg UnmarshalConfig) delimiter() string {
-	if cfg.Delimiter != "" {
-		return cfg.Delimiter
+	if cfg.MaxFields > 0 && *cfg.fields > cfg.MaxFields {
+		return &LimitError{Limit: "MaxFields", Max: cfg.MaxFields}
 	}
 
-	return "."
-}
-
-type unmarshalConfig struct {
-	UnmarshalConfig
-	Prefix []string
+	return nil
 }
 
 func newUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error) {
+	if err := cfg.applyStyle(); err != nil {
+		return nil, err
+	}
+
+	if unm, ok := newMapMethodUnmarshaler(cfg, typ); ok {
+		return unm, nil
+	}
+
 	switch typ.Kind() {
 	case reflect.Struct:
 		return newStructUnmarshaler(cfg, typ)
@@ -441,7 +2412,7 @@ func newUnmarshaler(cfg unmarshalConfig, typ reflect.Type) (unmarshaler, error)
 		}, nil
 	}
 
-	return nil, fmt.Errorf("cannot unmarshal into %s", typ.Kind().String())
+	return nil, newUnsupportedTypeError(typ, "cannot unmarshal into %s", typ.Kind().String())
 }
 
 type Unmarshaler struct {
@@ -455,29 +2426,186 @@ func NewUnmarshaler(cfg UnmarshalConfig) *Unmarshaler {
 	}
 }
 
-func (u *Unmarshaler) Unmarshal(v map[string][]string, dst any) error {
+// ClearCache releases all the compiled types, e.g. the types from a plugin
+// that is no longer used. They are compiled again on the next use.
+func (u *Unmarshaler) ClearCache() {
+	u.cache.Clear()
+}
+
+// RegisterValidator adds the validator that is selected by the
+// "validate=name" option, replacing the one with the same name. It clears the
+// cache so the fields are compiled again with the validator, and must not be
+// called concurrently with the other methods.
+func (u *Unmarshaler) RegisterValidator(name string, fn ValidatorFunc) {
+	validators := maps.Clone(u.config.Validators)
+	if validators == nil {
+		validators = make(map[string]ValidatorFunc)
+	}
+
+	validators[name] = fn
+	u.config.Validators = validators
+
+	u.ClearCache()
+}
+
+func (u *Unmarshaler) compile(typ reflect.Type) (unmarshaler, error) {
+	return u.cache.Get(typ, func(key reflect.Type) (unmarshaler, error) {
+		return newUnmarshaler(newUnmarshalConfig(u.config), key)
+	})
+}
+
+func (u *Unmarshaler) getUnmarshaler(dst any) (unmarshaler, reflect.Value, error) {
 	val := reflect.ValueOf(dst)
 
 	if val.Kind() != reflect.Pointer || val.IsNil() {
-		return errors.New("can only unmarshal into a non-nil pointer")
+		return nil, reflect.Value{}, errors.New("can only unmarshal into a non-nil pointer")
 	}
 
 	elem := val.Elem()
 
-	vu, err := u.cache.Get(elem.Type(), func(key reflect.Type) (unmarshaler, error) {
-		return newUnmarshaler(unmarshalConfig{UnmarshalConfig: u.config}, key)
-	})
+	vu, err := u.compile(elem.Type())
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+
+	return vu, elem, nil
+}
+
+func (u *Unmarshaler) Unmarshal(v map[string][]string, dst any) error {
+	vu, elem, err := u.getUnmarshaler(dst)
+	if err != nil {
+		return err
+	}
+
+	return vu.unmarshal(u.config.newContext(), u.config.inputKeys(v), elem)
+}
+
+// UnmarshalFrom unmarshals the sources into dst, where they are given from the
+// lowest to the highest priority (e.g. defaults, config file, environment and
+// then query). A key from a source overrides the same key from all the
+// sources before it, so a field is only overwritten when a higher priority
+// source actually provides it. The keys are compared after the KeyRewrites and
+// the KeyFold, and a field key also overrides the aliases of the same field.
+func (u *Unmarshaler) UnmarshalFrom(dst any, sources ...map[string][]string) error {
+	vu, elem, err := u.getUnmarshaler(dst)
 	if err != nil {
 		return err
 	}
 
-	return vu.unmarshal(unmarshalContext{}, v, elem)
+	aliases := make(map[string][]string)
+	aliasKeys(vu, aliases)
+
+	merged := make(map[string][]string)
+
+	for _, src := range sources {
+		src = u.config.inputKeys(src)
+
+		// Drop the other keys of the same fields first, so the keys of this
+		// source do not remove each other.
+		for key, val := range src {
+			if len(val) == 0 {
+				continue
+			}
+
+			for _, alias := range aliases[key] {
+				delete(merged, alias)
+			}
+		}
+
+		for key, val := range src {
+			if len(val) > 0 {
+				merged[key] = val
+			}
+		}
+	}
+
+	return vu.unmarshal(u.config.newContext(), merged, elem)
+}
+
+// aliasKeys collects the other keys of every field of the struct and its
+// nested structs that has any alias, keyed by each of its keys.
+func aliasKeys(u unmarshaler, keys map[string][]string) {
+	switch u := u.(type) {
+	case *pointerUnmarshaler:
+		aliasKeys(u.elem, keys)
+
+	case *structUnmarshaler:
+		for i := range u.fields {
+			field := &u.fields[i]
+			if field.nested {
+				aliasKeys(field.unmarshaler, keys)
+
+				continue
+			}
+
+			if len(field.aliases) == 0 {
+				continue
+			}
+
+			names := []string{field.name}
+			for _, alias := range field.aliases {
+				names = append(names, alias.name)
+			}
+
+			for _, name := range names {
+				keys[name] = names
+			}
+		}
+	}
+}
+
+func UnmarshalFrom(dst any, sources ...map[string][]string) error {
+	return DefaultUnmarshaler.UnmarshalFrom(dst, sources...)
+}
+
+// UnmarshalSingle unmarshals a single-valued map (e.g. from env files,
+// annotations or hashes) into dst.
+func (u *Unmarshaler) UnmarshalSingle(v map[string]string, dst any) error {
+	multi := make(map[string][]string, len(v))
+	vals := make([]string, 0, len(v))
+
+	for key, val := range v {
+		vals = append(vals, val)
+		multi[key] = vals[len(vals)-1 : len(vals) : len(vals)]
+	}
+
+	return u.Unmarshal(multi, dst)
+}
+
+func UnmarshalSingle(v map[string]string, dst any) error {
+	return DefaultUnmarshaler.UnmarshalSingle(v, dst)
+}
+
+// UnmarshalAs unmarshals v into a new value of the type T using the default
+// unmarshaler.
+func UnmarshalAs[T any](v map[string][]string) (T, error) {
+	var dst T
+
+	err := DefaultUnmarshaler.Unmarshal(v, &dst)
+
+	return dst, err
 }
 
 func Unmarshal(v map[string][]string, dst any) error {
 	return DefaultUnmarshaler.Unmarshal(v, dst)
 }
 
+// MustUnmarshal is like Unmarshal but panics on error. It is intended for
+// tests and fixtures, where an error is always a programming mistake.
+func (u *Unmarshaler) MustUnmarshal(v map[string][]string, dst any) {
+	if err := u.Unmarshal(v, dst); err != nil {
+		panic(err)
+	}
+}
+
+func MustUnmarshal(v map[string][]string, dst any) {
+	DefaultUnmarshaler.MustUnmarshal(v, dst)
+}
+
 func UnmarshalHeader(v http.Header, dst any) error {
 	return HeaderUnmarshaler.Unmarshal(v, dst)
 }
+
+func UnmarshalMetadata(v map[string][]string, dst any) error {
+	return MetadataUnmarshaler.Unmarshal(v, dst)
+}
@@ -17,8 +17,15 @@ limitations under the License.
 package structmap_test
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/adzil/structmap"
 	"github.com/stretchr/testify/assert"
@@ -64,24 +71,155 @@ func TestUnmarshal(t *testing.T) {
 
 	t.Run("WithUnknownType", func(t *testing.T) {
 		type emptyStruct struct {
-			Float64 float64
+			Chan chan int
 		}
 
 		var empty *emptyStruct
 
 		err := structmap.Unmarshal(nil, &empty)
-		assert.ErrorContains(t, err, "cannot unmarshal into float64")
+		assert.ErrorContains(t, err, "cannot unmarshal into chan")
 	})
 
 	t.Run("WithUnknownSliceType", func(t *testing.T) {
 		type emptyStruct struct {
-			Float64 []float64
+			Chan []chan int
 		}
 
 		var empty emptyStruct
 
 		err := structmap.Unmarshal(nil, &empty)
-		assert.ErrorContains(t, err, "cannot unmarshal into slice of float64")
+		assert.ErrorContains(t, err, "cannot unmarshal into slice of chan")
+	})
+
+	t.Run("WithSkipUnsupported", func(t *testing.T) {
+		type nestedStruct struct {
+			Callback func() `map:"callback"`
+			Value    string `map:"value"`
+		}
+
+		type testStruct struct {
+			Name   string         `map:"name"`
+			Done   chan struct{}  `map:"done"`
+			Extra  map[string]int `map:"extra"`
+			Nested nestedStruct   `map:"nested"`
+		}
+
+		input := map[string][]string{
+			"name":         {"alice"},
+			"done":         {"true"},
+			"nested.value": {"bob"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		assert.ErrorContains(t, err, "cannot unmarshal into chan")
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{SkipUnsupported: true})
+
+		err = u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Name: "alice", Nested: nestedStruct{Value: "bob"}}, actual)
+	})
+
+	t.Run("WithSliceStyle", func(t *testing.T) {
+		type testStruct struct {
+			Tags   []string `map:"tags"`
+			IDs    []int    `map:"ids,repeated"`
+			Sizes  []int    `map:"sizes,joined"`
+			Colors []string `map:"colors,semicolon"`
+		}
+
+		expected := testStruct{
+			Tags:   []string{"a", "b"},
+			IDs:    []int{1, 2},
+			Sizes:  []int{3, 4, 5},
+			Colors: []string{"red", "blue"},
+		}
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{SliceStyle: structmap.SliceIndexed})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{
+			"tags.0": {"a"},
+			"tags.1": {"b"},
+			"tags.3": {"skipped"},
+			"ids":    {"1", "2"},
+			"sizes":  {"3,4", "5"},
+			"colors": {"red;blue"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		u = structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			SliceStyle:     structmap.SliceJoined,
+			SliceSeparator: "|",
+		})
+
+		err = u.Unmarshal(map[string][]string{
+			"tags":   {"a|b"},
+			"ids":    {"1", "2"},
+			"sizes":  {"3|4|5"},
+			"colors": {"red;blue"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithNilAndEmptySlice", func(t *testing.T) {
+		type testStruct struct {
+			Tags   []string `map:"tags"`
+			Sizes  []int    `map:"sizes,comma"`
+			Colors []string `map:"colors"`
+			IDs    []int    `map:"ids,required"`
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(map[string][]string{
+			"tags":  {},
+			"sizes": {""},
+			"ids":   {"1"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Tags: []string{}, Sizes: []int{}, IDs: []int{1}}, actual)
+		assert.NotNil(t, actual.Tags)
+		assert.Nil(t, actual.Colors)
+
+		err = structmap.Unmarshal(map[string][]string{"ids": {}}, &actual)
+		assert.ErrorContains(t, err, "ids")
+	})
+
+	t.Run("WithSliceCompositions", func(t *testing.T) {
+		type item struct {
+			Name string `map:"name"`
+		}
+
+		type testStruct struct {
+			Tags    *[]string `map:"tags"`
+			Sizes   *[]int    `map:"sizes"`
+			Options []*item   `map:"options"`
+			Groups  *[]item   `map:"groups"`
+		}
+
+		tags := []string{"a", "b"}
+		expected := testStruct{
+			Tags:    &tags,
+			Options: []*item{{Name: "x"}, {Name: "y"}},
+			Groups:  &[]item{{Name: "z"}},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(map[string][]string{
+			"tags":           {"a", "b"},
+			"options.0.name": {"x"},
+			"options.1.name": {"y"},
+			"groups.0.name":  {"z"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
 	})
 
 	t.Run("WithNestedPointer", func(t *testing.T) {
@@ -124,26 +262,1354 @@ func TestUnmarshal(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, expected, actual)
 	})
-}
 
-func TestUnmarshalHeader(t *testing.T) {
-	type testHeader struct {
-		ContentType string `map:"content-type"`
-		Accept      string `map:"accept"`
-	}
+	t.Run("WithNullValues", func(t *testing.T) {
+		type testStruct struct {
+			Pointer *int
+			Number  int
+			Strings []string
+			Message string `map:",required"`
+		}
 
-	expected := testHeader{
-		ContentType: "application/json",
-		Accept:      "application/xml",
-	}
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			NullValues: []string{"null", "-"},
+		})
 
-	data := make(http.Header)
-	data.Set("Accept", expected.Accept)
-	data.Set("Content-Type", expected.ContentType)
+		input := map[string][]string{
+			"Pointer": {"null"},
+			"Number":  {"-"},
+			"Strings": {"null"},
+			"Message": {"hello"},
+		}
 
-	var actual testHeader
+		one := 1
+		actual := testStruct{
+			Pointer: &one,
+			Number:  1,
+			Strings: []string{"a"},
+		}
 
-	err := structmap.UnmarshalHeader(data, &actual)
-	require.NoError(t, err)
-	assert.Equal(t, expected, actual)
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Message: "hello"}, actual)
+
+		input["Message"] = []string{"null"}
+
+		err = u.Unmarshal(input, &actual)
+		assert.ErrorContains(t, err, "required")
+	})
+
+	t.Run("WithMaxSliceLen", func(t *testing.T) {
+		type testStruct struct {
+			Numbers []int `map:"n"`
+		}
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			MaxSliceLen: 2,
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{"n": {"1", "2"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, actual.Numbers)
+
+		err = u.Unmarshal(map[string][]string{"n": {"1", "2", "3"}}, &actual)
+
+		var limitErr *structmap.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxSliceLen", limitErr.Limit)
+		assert.ErrorContains(t, err, "key n")
+	})
+
+	t.Run("WithMaxValueLen", func(t *testing.T) {
+		type testStruct struct {
+			First  string   `map:"first"`
+			Second []string `map:"second"`
+		}
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			MaxValueLen: 4,
+			MaxTotalLen: 10,
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{
+			"first":  {"abcd"},
+			"second": {"efgh", "ij"},
+		}, &actual)
+		require.NoError(t, err)
+
+		var limitErr *structmap.LimitError
+
+		err = u.Unmarshal(map[string][]string{"first": {"abcde"}}, &actual)
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxValueLen", limitErr.Limit)
+
+		err = u.Unmarshal(map[string][]string{
+			"first":  {"abcd"},
+			"second": {"efgh", "ijk"},
+		}, &actual)
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxTotalLen", limitErr.Limit)
+		assert.ErrorContains(t, err, "key second")
+	})
+
+	t.Run("WithCompileLimits", func(t *testing.T) {
+		type node struct {
+			Value string
+			Next  *node
+		}
+
+		type wideStruct struct {
+			A, B, C string
+		}
+
+		var limitErr *structmap.LimitError
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{MaxDepth: 3})
+
+		err := u.Unmarshal(nil, &node{})
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxDepth", limitErr.Limit)
+
+		u = structmap.NewUnmarshaler(structmap.UnmarshalConfig{MaxFields: 2})
+
+		err = u.Unmarshal(nil, &wideStruct{})
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, "MaxFields", limitErr.Limit)
+	})
+
+	t.Run("WithTimeLocation", func(t *testing.T) {
+		type testStruct struct {
+			Date    time.Time  `map:"date"`
+			Instant *time.Time `map:"instant"`
+		}
+
+		loc := time.FixedZone("UTC+7", 7*60*60)
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			Location: loc,
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{
+			"date":    {"2023-08-17"},
+			"instant": {"2023-08-17T10:00:00Z"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.True(t, time.Date(2023, 8, 17, 0, 0, 0, 0, loc).Equal(actual.Date))
+		assert.Equal(t, loc, actual.Date.Location())
+		require.NotNil(t, actual.Instant)
+		assert.True(t, time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC).Equal(*actual.Instant))
+
+		err = structmap.Unmarshal(map[string][]string{"date": {"2023-08-17"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, time.UTC, actual.Date.Location())
+
+		err = u.Unmarshal(map[string][]string{"date": {"yesterday"}}, &actual)
+		assert.ErrorContains(t, err, "key date")
+	})
+
+	t.Run("WithParseFuncs", func(t *testing.T) {
+		type testStruct struct {
+			Count  int       `map:"count"`
+			Size   uint16    `map:"size"`
+			Amount float64   `map:"amount"`
+			Rates  []float32 `map:"rates"`
+		}
+
+		input := map[string][]string{
+			"count":  {"1.234"},
+			"size":   {"4.321"},
+			"amount": {"1.234,56"},
+			"rates":  {"0,5", "1,25"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		assert.ErrorContains(t, err, "key count")
+
+		localized := strings.NewReplacer(".", "", ",", ".")
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			ParseIntFunc: func(s string, bitSize int) (int64, error) {
+				return strconv.ParseInt(localized.Replace(s), 10, bitSize)
+			},
+			ParseFloatFunc: func(s string, bitSize int) (float64, error) {
+				return strconv.ParseFloat(localized.Replace(s), bitSize)
+			},
+		})
+
+		err = u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{
+			Count:  1234,
+			Size:   4321,
+			Amount: 1234.56,
+			Rates:  []float32{0.5, 1.25},
+		}, actual)
+
+		for _, val := range []string{"-1", "70.000"} {
+			input["size"] = []string{val}
+
+			err = u.Unmarshal(input, &actual)
+			assert.ErrorContains(t, err, "key size: value", val)
+		}
+	})
+
+	t.Run("WithCharOption", func(t *testing.T) {
+		type testStruct struct {
+			Symbol rune   `map:"symbol,char"`
+			Flag   byte   `map:"flag,char"`
+			Marks  []rune `map:"marks,char"`
+			Code   rune   `map:"code"`
+		}
+
+		expected := testStruct{
+			Symbol: '€',
+			Flag:   'x',
+			Marks:  []rune{'✓', '✗'},
+			Code:   8364,
+		}
+
+		input := map[string][]string{
+			"symbol": {"€"},
+			"flag":   {"x"},
+			"marks":  {"✓", "✗"},
+			"code":   {"8364"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		input["symbol"] = []string{"ab"}
+
+		err = structmap.Unmarshal(input, &actual)
+		assert.ErrorContains(t, err, "key symbol")
+
+		var invalid struct {
+			Field string `map:",char"`
+		}
+
+		err = structmap.Unmarshal(nil, &invalid)
+		assert.ErrorContains(t, err, "char option")
+	})
+
+	t.Run("WithComplex", func(t *testing.T) {
+		type testStruct struct {
+			Impedance complex128  `map:"z"`
+			Roots     []complex64 `map:"roots"`
+		}
+
+		expected := testStruct{
+			Impedance: complex(50, -25.5),
+			Roots:     []complex64{complex(1, 2), complex(0, -1)},
+		}
+
+		input := map[string][]string{
+			"z":     {"50-25.5i"},
+			"roots": {"1+2i", "(0-1i)"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("WithBoolAndUint", func(t *testing.T) {
+		type testStruct struct {
+			Enabled bool     `map:"enabled"`
+			Count   uint16   `map:"count"`
+			Flags   []bool   `map:"flags"`
+			Sizes   []uint64 `map:"sizes"`
+		}
+
+		expected := testStruct{
+			Enabled: true,
+			Count:   42,
+			Flags:   []bool{true, false},
+			Sizes:   []uint64{1, 18446744073709551615},
+		}
+
+		input := map[string][]string{
+			"enabled": {"true"},
+			"count":   {"42"},
+			"flags":   {"1", "false"},
+			"sizes":   {"1", "18446744073709551615"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		input["count"] = []string{"-1"}
+
+		err = structmap.Unmarshal(input, &actual)
+		assert.ErrorContains(t, err, "key count")
+	})
+
+	t.Run("WithEscapeOption", func(t *testing.T) {
+		type testStruct struct {
+			Redirect string   `map:"redirect,escape"`
+			Filters  []string `map:"filters,escape"`
+		}
+
+		input := map[string][]string{
+			"redirect": {"https%3A%2F%2Fexample.com%2F%3Fa%3D1%26b%3D2"},
+			"filters":  {"a%2Cb", "c+d"},
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{
+			Redirect: "https://example.com/?a=1&b=2",
+			Filters:  []string{"a,b", "c d"},
+		}, actual)
+
+		input["redirect"] = []string{"%zz"}
+
+		err = structmap.Unmarshal(input, &actual)
+		assert.ErrorContains(t, err, "key redirect")
+
+		var nested struct {
+			Nested struct{} `map:"nested,escape"`
+		}
+
+		err = structmap.Unmarshal(nil, &nested)
+		assert.ErrorContains(t, err, "escape option")
+	})
+
+	t.Run("WithKeyFold", func(t *testing.T) {
+		type Filter struct {
+			CreatedAfter int `map:"created_after"`
+		}
+
+		type testStruct struct {
+			Filter   `map:"filter"`
+			PageSize int      `map:"page-size,required"`
+			SortBy   []string `map:"sort_by"`
+		}
+
+		input := map[string][]string{
+			"filter.created-after": {"10"},
+			"page_size":            {"20"},
+			"sort-by":              {"name"},
+			"sort_by":              {"age"},
+		}
+
+		var actual testStruct
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{KeyFold: structmap.KeyFoldSeparators})
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, 10, actual.CreatedAfter)
+		assert.Equal(t, 20, actual.PageSize)
+		assert.Equal(t, []string{"name", "age"}, actual.SortBy)
+
+		err = u.Unmarshal(map[string][]string{"pagesize": {"20"}}, &actual)
+		assert.ErrorContains(t, err, "page-size")
+
+		u = structmap.NewUnmarshaler(structmap.UnmarshalConfig{KeyFold: structmap.KeyFoldStripSeparators})
+		actual = testStruct{}
+		err = u.Unmarshal(map[string][]string{
+			"filter.createdafter": {"10"},
+			"pagesize":            {"20"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Filter: Filter{CreatedAfter: 10}, PageSize: 20}, actual)
+	})
+
+	t.Run("WithKeyFoldCase", func(t *testing.T) {
+		type testStruct struct {
+			Title string `map:"straße"`
+			Kind  string `map:"ΣΊΣΥΦΟΣ"`
+		}
+
+		input := map[string][]string{
+			"STRAßE":  {"main"},
+			"σίσυφος": {"stone"},
+		}
+
+		var actual testStruct
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{KeyFold: structmap.KeyFoldCase})
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Title: "main", Kind: "stone"}, actual)
+	})
+
+	t.Run("WithValueFunc", func(t *testing.T) {
+		t.Setenv("STRUCTMAP_TEST_HOST", "db.local")
+		t.Setenv("STRUCTMAP_TEST_PORT", "5432")
+
+		type testStruct struct {
+			Host string   `map:"host"`
+			Port int      `map:"port,default:${STRUCTMAP_TEST_PORT}"`
+			Tags []string `map:"tags"`
+		}
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{ValueFunc: structmap.ExpandEnv})
+
+		input := map[string][]string{
+			"host": {"${STRUCTMAP_TEST_HOST}"},
+			"tags": {"a", "$STRUCTMAP_TEST_HOST"},
+		}
+
+		var actual testStruct
+
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Host: "db.local", Port: 5432, Tags: []string{"a", "db.local"}}, actual)
+		assert.Equal(t, []string{"${STRUCTMAP_TEST_HOST}"}, input["host"])
+	})
+
+	t.Run("WithKeyRewrites", func(t *testing.T) {
+		type testStruct struct {
+			Query string `map:"q"`
+			Limit int    `map:"limit"`
+		}
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			KeyRewrites: map[string]string{
+				"search":   "q",
+				"per_page": "limit",
+			},
+		})
+
+		input := map[string][]string{
+			"search":   {"go"},
+			"per_page": {"10"},
+			"limit":    {"20"},
+		}
+
+		var actual testStruct
+
+		err := u.Unmarshal(input, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Query: "go", Limit: 20}, actual)
+		assert.Equal(t, map[string][]string{
+			"search":   {"go"},
+			"per_page": {"10"},
+			"limit":    {"20"},
+		}, input)
+
+		// The first of the sorted keys wins when they rename into the same key.
+		u = structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			KeyRewrites: map[string]string{"query": "q", "search": "q", "term": "q"},
+		})
+
+		for i := 0; i < 10; i++ {
+			err = u.Unmarshal(map[string][]string{"search": {"b"}, "term": {"c"}, "query": {"a"}}, &actual)
+			require.NoError(t, err)
+			assert.Equal(t, "a", actual.Query)
+		}
+	})
+
+	t.Run("WithAliasOption", func(t *testing.T) {
+		type Filter struct {
+			CreatedAfter string `map:"created_after,alias=since,deprecated=from"`
+		}
+
+		type testStruct struct {
+			Filter Filter `map:"filter"`
+			Limit  int    `map:"limit,deprecated=per_page"`
+		}
+
+		var used []string
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			DeprecatedFunc: func(key, alias string) {
+				used = append(used, alias+" -> "+key)
+			},
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{
+			"filter.since": {"2023"},
+			"filter.from":  {"2020"},
+			"per_page":     {"10"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Filter: Filter{CreatedAfter: "2023"}, Limit: 10}, actual)
+		assert.Equal(t, []string{"per_page -> limit"}, used)
+
+		used = nil
+
+		err = u.Unmarshal(map[string][]string{
+			"filter.created_after": {"2024"},
+			"filter.from":          {"2020"},
+			"limit":                {"5"},
+		}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Filter: Filter{CreatedAfter: "2024"}, Limit: 5}, actual)
+		assert.Empty(t, used)
+
+		err = u.Unmarshal(map[string][]string{"filter.from": {"2020"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, "2020", actual.Filter.CreatedAfter)
+		assert.Equal(t, []string{"filter.from -> filter.created_after"}, used)
+	})
+
+	t.Run("WithTraceFunc", func(t *testing.T) {
+		type Item struct {
+			Name string `map:"name"`
+		}
+
+		type testStruct struct {
+			Page  int    `map:"page,default:1"`
+			Sort  string `map:"sort"`
+			Items []Item `map:"items"`
+		}
+
+		var traces []string
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+			TraceFunc: func(key, fieldPath string, values []string) {
+				traces = append(traces, fmt.Sprintf("%s %s %v", key, fieldPath, values))
+			},
+		})
+
+		var actual testStruct
+
+		err := u.Unmarshal(map[string][]string{"items.0.name": {"x"}}, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"page Page [1]",
+			"sort Sort []",
+			"items.0.name Items[0].Name [x]",
+		}, traces)
+	})
+}
+
+func TestUnmarshalFrom(t *testing.T) {
+	type testStruct struct {
+		Host  string   `map:"host"`
+		Port  int      `map:"port,required"`
+		Debug bool     `map:"debug"`
+		Tags  []string `map:"tags"`
+	}
+
+	defaults := map[string][]string{
+		"host": {"localhost"},
+		"port": {"8080"},
+		"tags": {"default"},
+	}
+
+	config := map[string][]string{
+		"port": {"9090"},
+		"tags": {},
+	}
+
+	env := map[string][]string{
+		"debug": {"true"},
+		"tags":  {"a", "b"},
+	}
+
+	var actual testStruct
+
+	err := structmap.UnmarshalFrom(&actual, defaults, config, env)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{
+		Host:  "localhost",
+		Port:  9090,
+		Debug: true,
+		Tags:  []string{"a", "b"},
+	}, actual)
+
+	err = structmap.UnmarshalFrom(&actual, env)
+	assert.ErrorContains(t, err, "port")
+
+	t.Run("WithAliases", func(t *testing.T) {
+		type testStruct struct {
+			Host    string `map:"host,alias=hostname"`
+			Timeout int    `map:"request_timeout"`
+		}
+
+		u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{KeyFold: structmap.KeyFoldSeparators})
+
+		var actual testStruct
+
+		err := u.UnmarshalFrom(&actual,
+			map[string][]string{"host": {"localhost"}, "request_timeout": {"10"}},
+			map[string][]string{"hostname": {"example.com"}, "request-timeout": {"30"}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, testStruct{Host: "example.com", Timeout: 30}, actual)
+
+		err = u.UnmarshalFrom(&actual,
+			map[string][]string{"hostname": {"example.com"}},
+			map[string][]string{"host": {"localhost"}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", actual.Host)
+	})
+}
+
+func TestUnmarshalSingle(t *testing.T) {
+	type testStruct struct {
+		Name    string   `map:"name"`
+		Port    int      `map:"port"`
+		Tags    []string `map:"tags,comma"`
+		Missing string   `map:"missing"`
+	}
+
+	var actual testStruct
+
+	err := structmap.UnmarshalSingle(map[string]string{
+		"name": "alice",
+		"port": "8080",
+		"tags": "a,b",
+	}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "alice", Port: 8080, Tags: []string{"a", "b"}}, actual)
+
+	err = structmap.UnmarshalSingle(map[string]string{"port": "http"}, &actual)
+	assert.ErrorContains(t, err, "key port")
+}
+
+func TestUnmarshalAs(t *testing.T) {
+	type listQuery struct {
+		Page  int    `map:"page"`
+		Query string `map:"q,required"`
+	}
+
+	actual, err := structmap.UnmarshalAs[listQuery](url.Values{"page": {"2"}, "q": {"go"}})
+	require.NoError(t, err)
+	assert.Equal(t, listQuery{Page: 2, Query: "go"}, actual)
+
+	_, err = structmap.UnmarshalAs[listQuery](nil)
+	assert.ErrorContains(t, err, "q")
+}
+
+func TestMustUnmarshal(t *testing.T) {
+	type testStruct struct {
+		Name string `map:"name,required"`
+	}
+
+	var actual testStruct
+
+	structmap.MustUnmarshal(map[string][]string{"name": {"alice"}}, &actual)
+	assert.Equal(t, testStruct{Name: "alice"}, actual)
+
+	assert.Panics(t, func() {
+		structmap.MustUnmarshal(nil, &actual)
+	})
+}
+
+func TestSecretOption(t *testing.T) {
+	type testStruct struct {
+		APIKey int               `map:"api_key,secret"`
+		Tokens map[string]int    `map:"token.,prefix,secret"`
+		Name   string            `map:"name"`
+		Extra  map[string]string `map:"x.,prefix"`
+	}
+
+	var traces []string
+
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+		TraceFunc: func(key, _ string, values []string) {
+			traces = append(traces, fmt.Sprintf("%s=%v", key, values))
+		},
+	})
+
+	var actual testStruct
+
+	err := u.Unmarshal(map[string][]string{"api_key": {"s3cr3t"}, "name": {"a"}}, &actual)
+
+	var fieldErr *structmap.FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Empty(t, fieldErr.Value)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+	assert.ErrorContains(t, err, "key api_key")
+	assert.Equal(t, []string{"api_key=[[redacted]]"}, traces)
+
+	err = u.Unmarshal(map[string][]string{"token.a": {"s3cr3t"}}, &actual)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+
+	var traced []string
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{
+		TraceFunc: func(key, _ string, values []string) {
+			traced = append(traced, fmt.Sprintf("%s=%v", key, values))
+		},
+	})
+
+	out := make(map[string][]string)
+
+	err = m.Marshal(testStruct{APIKey: 42, Name: "a"}, out)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"42"}, out["api_key"])
+	assert.Equal(t, []string{"api_key=[[redacted]]", "token.=[]", "name=[a]", "x.=[]"}, traced)
+}
+
+func TestReset(t *testing.T) {
+	type testStruct struct {
+		Name string `map:"name"`
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(map[string][]string{"name": {"a"}}, &actual)
+	require.NoError(t, err)
+
+	structmap.Reset()
+
+	err = structmap.Unmarshal(map[string][]string{"name": {"b"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "b"}, actual)
+
+	out := make(map[string][]string)
+
+	structmap.DefaultMarshaler.ClearCache()
+
+	err = structmap.Marshal(actual, out)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"b"}}, out)
+}
+
+func TestFieldError(t *testing.T) {
+	type Item struct {
+		Counts []int `map:"counts"`
+	}
+
+	type Filter struct {
+		Items  []Item            `map:"items"`
+		Labels map[string]string `map:"label,prefix"`
+		Limit  int               `map:"limit"`
+	}
+
+	type testStruct struct {
+		Filter Filter `map:"filter"`
+	}
+
+	tests := []struct {
+		name     string
+		input    map[string][]string
+		expected structmap.FieldError
+	}{
+		{
+			name:     "Scalar",
+			input:    map[string][]string{"filter.limit": {"ten"}},
+			expected: structmap.FieldError{Key: "filter.limit", Field: "Filter.Limit", Index: -1, Value: "ten"},
+		},
+		{
+			name:     "IndexedSlice",
+			input:    map[string][]string{"filter.items.0.counts": {"1", "x"}},
+			expected: structmap.FieldError{Key: "filter.items.0.counts", Field: "Filter.Items[0].Counts", Index: 1, Value: "x"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual testStruct
+
+			err := structmap.Unmarshal(tc.input, &actual)
+
+			var fieldErr *structmap.FieldError
+			require.ErrorAs(t, err, &fieldErr)
+			assert.Equal(t, tc.expected.Key, fieldErr.Key)
+			assert.Equal(t, tc.expected.Field, fieldErr.Field)
+			assert.Equal(t, tc.expected.Index, fieldErr.Index)
+			assert.Equal(t, tc.expected.Value, fieldErr.Value)
+			assert.ErrorContains(t, err, "key "+tc.expected.Key+": ")
+
+			var numErr *strconv.NumError
+			assert.ErrorAs(t, err, &numErr)
+		})
+	}
+}
+
+func TestUnmarshalHeader(t *testing.T) {
+	type testHeader struct {
+		ContentType string `map:"content-type"`
+		Accept      string `map:"accept"`
+	}
+
+	expected := testHeader{
+		ContentType: "application/json",
+		Accept:      "application/xml",
+	}
+
+	data := make(http.Header)
+	data.Set("Accept", expected.Accept)
+	data.Set("Content-Type", expected.ContentType)
+
+	var actual testHeader
+
+	err := structmap.UnmarshalHeader(data, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestSchemaUnmarshaler(t *testing.T) {
+	type phone struct {
+		Label  string `schema:"label"`
+		Number string `schema:"number,required"`
+	}
+
+	type person struct {
+		Name   string   `schema:"name,required"`
+		Role   string   `schema:"role,default:member"`
+		Tags   []string `schema:"tags,default:a|b"`
+		Phones []phone  `schema:"phones"`
+		Secret string   `schema:"-"`
+	}
+
+	expected := person{
+		Name: "John",
+		Role: "member",
+		Tags: []string{"a", "b"},
+		Phones: []phone{
+			{Label: "home", Number: "123"},
+			{Number: "456"},
+		},
+	}
+
+	input := map[string][]string{
+		"name":            {"John"},
+		"phones.0.label":  {"home"},
+		"phones.0.number": {"123"},
+		"phones.1.number": {"456"},
+		"Secret":          {"ignored"},
+	}
+
+	var actual person
+
+	err := structmap.SchemaUnmarshaler.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	input["phones.5.number"] = []string{"789"}
+
+	err = structmap.SchemaUnmarshaler.Unmarshal(input, &actual)
+	assert.ErrorContains(t, err, "slice index #2")
+
+	input["phones.100.number"] = []string{"789"}
+
+	err = structmap.SchemaUnmarshaler.Unmarshal(input, &actual)
+	assert.ErrorContains(t, err, "out of range")
+
+	var invalid struct {
+		Count int `schema:"count,default:many"`
+	}
+
+	err = structmap.SchemaUnmarshaler.Unmarshal(nil, &invalid)
+	assert.ErrorContains(t, err, "invalid default value")
+}
+
+func TestFormUnmarshaler(t *testing.T) {
+	type user struct {
+		Name string `form:"name"`
+		Age  int    `form:"age"`
+	}
+
+	type request struct {
+		Names   []string  `form:"names"`
+		Scores  []int     `form:"scores"`
+		Users   []user    `form:"users"`
+		Since   time.Time `form:"since"`
+		Ignored string    `form:"-"`
+	}
+
+	expected := request{
+		Names:  []string{"a", "b"},
+		Scores: []int{1, 2, 3},
+		Users:  []user{{Name: "x", Age: 1}, {Name: "y"}},
+		Since:  time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC),
+	}
+
+	input := map[string][]string{
+		"names[0]":      {"a"},
+		"names[1]":      {"b"},
+		"scores":        {"1"},
+		"scores[0]":     {"2"},
+		"scores[1]":     {"3"},
+		"users[0].name": {"x"},
+		"users[0].age":  {"1"},
+		"users[1].name": {"y"},
+		"since":         {"2023-08-17T10:00:00Z"},
+	}
+
+	var actual request
+
+	err := structmap.FormUnmarshaler.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestUnmarshalQueryStringOptions(t *testing.T) {
+	type listOptions struct {
+		Archived bool      `url:"archived,int"`
+		IDs      []uint    `url:"id,brackets"`
+		Sort     []string  `url:"sort,numbered"`
+		Since    time.Time `url:"since,unix"`
+		Until    time.Time `url:"until,unixmilli"`
+		Before   time.Time `url:"before,unixnano"`
+	}
+
+	expected := listOptions{
+		Archived: true,
+		IDs:      []uint{1, 2},
+		Sort:     []string{"name", "date"},
+		Since:    time.Unix(1692230400, 0).UTC(),
+		Until:    time.UnixMilli(1692230400123).UTC(),
+		Before:   time.Unix(0, 1692230400123456789).UTC(),
+	}
+
+	v := make(url.Values)
+
+	err := structmap.MarshalQueryString(expected, v)
+	require.NoError(t, err)
+
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{TagName: "url"})
+
+	var actual listOptions
+
+	err = u.Unmarshal(v, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	err = u.Unmarshal(map[string][]string{"since": {"yesterday"}}, &actual)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalSmallInput(t *testing.T) {
+	type testStruct struct {
+		A string   `map:"a"`
+		B int      `map:"b"`
+		C []string `map:"c"`
+		D string   `map:"d,default:x"`
+		E bool     `map:"e"`
+		F int      `map:"f"`
+		G string   `map:"g"`
+	}
+
+	actual := testStruct{A: "old", B: 1, E: true, G: "old"}
+
+	err := structmap.Unmarshal(map[string][]string{"g": {"new"}, "c": {}, "b": {"2"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{B: 2, C: []string{}, D: "x", G: "new"}, actual)
+
+	err = structmap.Unmarshal(map[string][]string{"f": {"x"}, "b": {"y"}}, &actual)
+
+	var fe *structmap.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "b", fe.Key)
+}
+
+func TestUnmarshalConditionalRequired(t *testing.T) {
+	type testFilter struct {
+		Start  int    `map:"start"`
+		End    int    `map:"end,required_with=start"`
+		Query  string `map:"q,required_without=id"`
+		ID     string `map:"id"`
+		Kind   string `map:"kind"`
+		Radius int    `map:"radius,required_if=kind circle"`
+	}
+
+	type testStruct struct {
+		Filter testFilter `map:"filter"`
+	}
+
+	tests := []struct {
+		name  string
+		input map[string][]string
+		err   string
+	}{
+		{
+			name:  "Satisfied",
+			input: map[string][]string{"filter.start": {"1"}, "filter.end": {"2"}, "filter.id": {"x"}},
+		},
+		{
+			name:  "RequiredWith",
+			input: map[string][]string{"filter.start": {"1"}, "filter.id": {"x"}},
+			err:   `key filter.end: value is required with key "filter.start"`,
+		},
+		{
+			name:  "RequiredWithout",
+			input: map[string][]string{},
+			err:   `key filter.q: value is required without key "filter.id"`,
+		},
+		{
+			name:  "RequiredIf",
+			input: map[string][]string{"filter.q": {"x"}, "filter.kind": {"circle"}},
+			err:   `key filter.radius: value is required when key "filter.kind" is "circle"`,
+		},
+		{
+			name:  "RequiredIfOtherValue",
+			input: map[string][]string{"filter.q": {"x"}, "filter.kind": {"square"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual testStruct
+
+			err := structmap.Unmarshal(tt.input, &actual)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("InvalidOption", func(t *testing.T) {
+		type testStruct struct {
+			A int `map:"a,required_if=b"`
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.Error(t, err)
+	})
+}
+
+func TestUnmarshalExclusiveGroup(t *testing.T) {
+	type testStruct struct {
+		Email string `map:"email,xor=contact"`
+		Phone string `map:"phone,xor=contact"`
+		Fax   string `map:"fax,xor=contact"`
+		Name  string `map:"name"`
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(map[string][]string{"phone": {"123"}, "name": {"x"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Phone: "123", Name: "x"}, actual)
+
+	err = structmap.Unmarshal(map[string][]string{"email": {"a@b.c"}, "fax": {"123"}}, &actual)
+	assert.EqualError(t, err, `group contact: keys "email", "fax" are mutually exclusive`)
+}
+
+func TestUnmarshalAnyOfGroup(t *testing.T) {
+	type testStruct struct {
+		Name   string `map:"name,anyof=filter"`
+		Email  string `map:"email,anyof=filter,xor=contact"`
+		Phone  string `map:"phone,anyof=filter,xor=contact"`
+		Limit  int    `map:"limit"`
+		Cursor string `map:"cursor"`
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(map[string][]string{"phone": {"123"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Phone: "123"}, actual)
+
+	err = structmap.Unmarshal(map[string][]string{"limit": {"10"}}, &actual)
+	assert.EqualError(t, err, `group filter: one of keys "name", "email", "phone" is required`)
+
+	err = structmap.Unmarshal(map[string][]string{"email": {"a@b.c"}, "phone": {"123"}}, &actual)
+	assert.EqualError(t, err, `group contact: keys "email", "phone" are mutually exclusive`)
+}
+
+func TestUnmarshalValidator(t *testing.T) {
+	type testStruct struct {
+		Start time.Time `map:"start,validate=future_date"`
+		Name  string    `map:"name"`
+	}
+
+	now := time.Date(2023, 8, 17, 10, 0, 0, 0, time.UTC)
+
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{})
+	u.RegisterValidator("future_date", func(value any) error {
+		if !value.(time.Time).After(now) {
+			return errors.New("date must be in the future")
+		}
+
+		return nil
+	})
+
+	var actual testStruct
+
+	err := u.Unmarshal(map[string][]string{"start": {"2023-08-18T00:00:00Z"}}, &actual)
+	require.NoError(t, err)
+
+	err = u.Unmarshal(map[string][]string{"start": {"2023-08-16T00:00:00Z"}}, &actual)
+
+	var fe *structmap.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "start", fe.Key)
+	assert.Equal(t, "future_date", fe.Validator)
+	assert.Equal(t, "2023-08-16T00:00:00Z", fe.Value)
+	assert.EqualError(t, err, "key start: validator future_date: date must be in the future")
+
+	t.Run("Unknown", func(t *testing.T) {
+		var actual testStruct
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.EqualError(t, err, "unknown validator future_date")
+	})
+}
+
+func TestUnmarshalUnsupportedTypeError(t *testing.T) {
+	type inner struct {
+		Handler func() `map:"handler"`
+	}
+
+	type testStruct struct {
+		Inner inner `map:"inner"`
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(nil, &actual)
+
+	var ute *structmap.UnsupportedTypeError
+	require.ErrorAs(t, err, &ute)
+	assert.Equal(t, reflect.TypeOf(func() {}), ute.Type)
+	assert.Equal(t, "Inner.Handler", ute.Field)
+}
+
+func TestUnmarshalBoolValues(t *testing.T) {
+	type testStruct struct {
+		Remember bool   `map:"remember"`
+		Legacy   bool   `map:"legacy"`
+		Flags    []bool `map:"flags"`
+	}
+
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+		TrueValues:  []string{"on", "yes", "y"},
+		FalseValues: []string{"off", "no", "n"},
+	})
+
+	actual := testStruct{Legacy: true}
+
+	err := u.Unmarshal(map[string][]string{
+		"remember": {"On"},
+		"legacy":   {"N"},
+		"flags":    {"yes", "false", "off", "1"},
+	}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Remember: true, Flags: []bool{true, false, false, true}}, actual)
+
+	err = u.Unmarshal(map[string][]string{"remember": {"maybe"}}, &actual)
+	assert.Error(t, err)
+
+	err = structmap.Unmarshal(map[string][]string{"remember": {"on"}}, &actual)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalUnique(t *testing.T) {
+	type testStruct struct {
+		Tags []string `map:"tag,unique"`
+		IDs  []int    `map:"id,comma,unique"`
+	}
+
+	input := map[string][]string{
+		"tag": {"a", "a", "b", "a"},
+		"id":  {"1,2,1", "3"},
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Tags: []string{"a", "b"}, IDs: []int{1, 2, 3}}, actual)
+	assert.Equal(t, []string{"a", "a", "b", "a"}, input["tag"])
+
+	t.Run("InvalidType", func(t *testing.T) {
+		type testStruct struct {
+			Tag string `map:"tag,unique"`
+		}
+
+		var actual testStruct
+
+		err := structmap.Unmarshal(nil, &actual)
+		assert.EqualError(t, err, "unique option is only valid for slice")
+	})
+}
+
+func TestUnmarshalItems(t *testing.T) {
+	type testStruct struct {
+		IDs  []int    `map:"ids,minitems=2,maxitems=3"`
+		Tags []string `map:"tags,comma,maxitems=2"`
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(map[string][]string{"ids": {"1", "2", "3"}, "tags": {"a,b"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{IDs: []int{1, 2, 3}, Tags: []string{"a", "b"}}, actual)
+
+	err = structmap.Unmarshal(map[string][]string{"ids": {"1", "2", "3", "4"}}, &actual)
+	assert.ErrorContains(t, err, "key ids")
+	assert.ErrorContains(t, err, "too many items: got 4, maximum is 3")
+
+	err = structmap.Unmarshal(map[string][]string{"ids": {"1"}}, &actual)
+	assert.ErrorContains(t, err, "too few items: got 1, minimum is 2")
+
+	err = structmap.Unmarshal(map[string][]string{"tags": {"a,b,c"}}, &actual)
+	assert.ErrorContains(t, err, "key tags")
+
+	t.Run("WithInvalidOption", func(t *testing.T) {
+		type nonSlice struct {
+			ID int `map:"id,maxitems=1"`
+		}
+
+		err := structmap.Unmarshal(nil, &nonSlice{})
+		assert.ErrorContains(t, err, "only valid for slice")
+
+		type invalidBound struct {
+			IDs []int `map:"ids,maxitems=zero"`
+		}
+
+		err = structmap.Unmarshal(nil, &invalidBound{})
+		assert.ErrorContains(t, err, "option maxitems requires a positive number")
+
+		type invalidRange struct {
+			IDs []int `map:"ids,minitems=3,maxitems=2"`
+		}
+
+		err = structmap.Unmarshal(nil, &invalidRange{})
+		assert.ErrorContains(t, err, "cannot be greater than maxitems")
+	})
+}
+
+func TestUnmarshalValueCheckFunc(t *testing.T) {
+	type testStruct struct {
+		Name  string            `map:"name"`
+		Tags  []string          `map:"tags"`
+		Page  int               `map:"page,default:1"`
+		Extra map[string]string `map:"x.,prefix"`
+	}
+
+	var checked []string
+
+	u := structmap.NewUnmarshaler(structmap.UnmarshalConfig{
+		ValueCheckFunc: func(key string, values []string) error {
+			checked = append(checked, key)
+
+			for _, val := range values {
+				if len(val) > 5 {
+					return errors.New("value is too long")
+				}
+			}
+
+			return nil
+		},
+	})
+
+	var actual testStruct
+
+	err := u.Unmarshal(map[string][]string{"name": {"alice"}, "tags": {"a", "b"}, "x.a": {"b"}}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "alice", Tags: []string{"a", "b"}, Page: 1, Extra: map[string]string{"a": "b"}}, actual)
+	assert.ElementsMatch(t, []string{"name", "tags", "x.a"}, checked)
+
+	err = u.Unmarshal(map[string][]string{"tags": {"a", "abcdef"}}, &actual)
+
+	var fieldErr *structmap.FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "tags", fieldErr.Key)
+	assert.Equal(t, "Tags", fieldErr.Field)
+	assert.ErrorContains(t, err, "value is too long")
+
+	err = u.Unmarshal(map[string][]string{"x.a": {"abcdef"}}, &actual)
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "x.a", fieldErr.Key)
+	assert.Equal(t, `Extra["a"]`, fieldErr.Field)
+}
+
+func TestUnmarshalCount(t *testing.T) {
+	type testStruct struct {
+		Attachments     []string `map:"attachment"`
+		AttachmentCount int      `map:"attachment,count"`
+		TagCount        uint8    `map:"tags,count,comma"`
+	}
+
+	input := map[string][]string{
+		"attachment": {"a.png", "b.png"},
+		"tags":       {"a,b,c"},
+	}
+
+	var actual testStruct
+
+	err := structmap.Unmarshal(input, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{
+		Attachments:     []string{"a.png", "b.png"},
+		AttachmentCount: 2,
+		TagCount:        3,
+	}, actual)
+
+	err = structmap.Unmarshal(map[string][]string{}, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{}, actual)
+
+	output := make(map[string][]string)
+
+	err = structmap.Marshal(testStruct{Attachments: []string{"a.png"}, AttachmentCount: 5}, output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"attachment": {"a.png"}}, output)
+
+	var invalid struct {
+		Count string `map:"n,count"`
+	}
+
+	err = structmap.Unmarshal(nil, &invalid)
+	assert.ErrorContains(t, err, "count option is only valid for int or uint")
+}
+
+// BenchmarkUnmarshalSparse compares the two strategies of the struct
+// unmarshaler on a wide struct with only a few keys in the input. By default
+// the input keys are iterated to find the fields, while a TraceFunc forces every
+// field key to be looked up instead.
+func BenchmarkUnmarshalSparse(b *testing.B) {
+	fields := make([]reflect.StructField, 64)
+	for i := range fields {
+		fields[i] = reflect.StructField{
+			Name: "Field" + strconv.Itoa(i),
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(`map:"field` + strconv.Itoa(i) + `"`),
+		}
+	}
+
+	typ := reflect.StructOf(fields)
+
+	input := map[string][]string{
+		"field1":  {"a"},
+		"field32": {"b"},
+	}
+
+	for _, bc := range []struct {
+		name string
+		cfg  structmap.UnmarshalConfig
+	}{
+		{"ByKeys", structmap.UnmarshalConfig{}},
+		{"ByFields", structmap.UnmarshalConfig{TraceFunc: func(string, string, []string) {}}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			u := structmap.NewUnmarshaler(bc.cfg)
+			dst := reflect.New(typ).Interface()
+
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if err := u.Unmarshal(input, dst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var _ marshaler = (*zeroCheckMarshaler)(nil)
+
+// zeroChecks holds the registered zero checks keyed by their type.
+var zeroChecks sync.Map
+
+// RegisterZeroCheck replaces the Go zero value with isZero to decide whether a
+// field of the type T is empty for the omitempty and required options, e.g.
+// to still send a decimal of zero or to omit a sentinel "unset" enum. The
+// marshalers are cached on their first use, so it should be called in an init
+// function.
+func RegisterZeroCheck[T any](isZero func(val T) bool) {
+	zeroChecks.Store(reflect.TypeOf((*T)(nil)).Elem(), func(src reflect.Value) bool {
+		// A nil interface holds no T, so it is passed as the zero T instead.
+		val, _ := src.Interface().(T)
+
+		return isZero(val)
+	})
+}
+
+func findZeroCheck(typ reflect.Type) (func(src reflect.Value) bool, bool) {
+	isZero, ok := zeroChecks.Load(typ)
+	if !ok {
+		return nil, false
+	}
+
+	return isZero.(func(src reflect.Value) bool), true
+}
+
+// zeroCheckMarshaler applies the omitempty and required options of a field
+// using its registered zero check, where its element is compiled without
+// them.
+type zeroCheckMarshaler struct {
+	keyMarshaler
+	isZero func(src reflect.Value) bool
+	elem   marshaler
+}
+
+func (m *zeroCheckMarshaler) marshal(ctx marshalContext, src reflect.Value, v map[string][]string) error {
+	if m.isZero(src) {
+		if m.required {
+			return fmt.Errorf("key %s: %w", ctx.key(m.key), errMissingValue)
+		}
+
+		if m.omitEmpty {
+			return nil
+		}
+	}
+
+	return m.elem.marshal(ctx, src, v)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Fadhli Dzil Ikram.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package structmap_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/adzil/structmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zeroState int
+
+const zeroStateUnset zeroState = -1
+
+type zeroAmount struct {
+	Cents int64
+	Valid bool
+}
+
+type zeroLabel interface {
+	Label() string
+}
+
+type zeroLabelString string
+
+func (s zeroLabelString) Label() string { return string(s) }
+
+func init() {
+	structmap.RegisterZeroCheck(func(s zeroState) bool { return s == zeroStateUnset })
+	structmap.RegisterZeroCheck(func(a zeroAmount) bool { return !a.Valid })
+	structmap.RegisterZeroCheck(func(l zeroLabel) bool { return l == nil || l.Label() == "" })
+}
+
+func TestRegisterZeroCheck(t *testing.T) {
+	type testStruct struct {
+		State  zeroState  `map:"state,omitempty"`
+		Amount zeroAmount `map:"amount,omitempty"`
+		Total  zeroAmount `map:"total,required"`
+	}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{
+		Codecs: []structmap.Codec{
+			structmap.NewCodec(func(a zeroAmount) (string, error) {
+				return strconv.FormatInt(a.Cents, 10), nil
+			}, nil),
+		},
+	})
+
+	actual := make(map[string][]string)
+
+	err := m.Marshal(testStruct{
+		State:  0,
+		Amount: zeroAmount{Valid: true},
+		Total:  zeroAmount{Cents: 10, Valid: true},
+	}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"state": {"0"}, "amount": {"0"}, "total": {"10"}}, actual)
+
+	actual = make(map[string][]string)
+
+	err = m.Marshal(testStruct{
+		State:  zeroStateUnset,
+		Amount: zeroAmount{Cents: 5},
+		Total:  zeroAmount{Valid: true},
+	}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"total": {"0"}}, actual)
+
+	err = m.Marshal(testStruct{Total: zeroAmount{Cents: 10}}, make(map[string][]string))
+	assert.ErrorContains(t, err, "key total: missing required value")
+}
+
+func TestRegisterZeroCheckInterface(t *testing.T) {
+	type testStruct struct {
+		Label zeroLabel `map:"label,omitempty"`
+	}
+
+	m := structmap.NewMarshaler(structmap.MarshalConfig{
+		Interfaces: []structmap.Interface{{
+			Type:       structmap.InterfaceOf[zeroLabel](),
+			Candidates: []structmap.InterfaceCandidate{{Name: "string", Value: zeroLabelString("")}},
+		}},
+	})
+
+	actual := make(map[string][]string)
+
+	err := m.Marshal(testStruct{}, actual)
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	err = m.Marshal(testStruct{Label: zeroLabelString("")}, actual)
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	err = m.Marshal(testStruct{Label: zeroLabelString("a")}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"label": {"a"}}, actual)
+}